@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"es/internal"
+	"es/internal/catalog"
+	"es/internal/checkout"
+	"es/internal/es"
+	"es/internal/util"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+var (
+	archiveDir   = flag.String("dir", "archive", "directory to write archived partitions to")
+	retainMonths = flag.Int("retain", 6, "number of months of events to keep in the database")
+	aheadMonths  = flag.Int("ahead", 3, "number of future monthly partitions to create")
+)
+
+func init() {
+	err := godotenv.Load()
+	if err != nil {
+		log.Fatalf("failed to load .env file: %v", err)
+	}
+
+	flag.Parse()
+}
+
+func main() {
+	ctx := context.Background()
+	pool := internal.MustDBPool(ctx)
+	defer pool.Close()
+
+	now := time.Now().UTC()
+	stream := es.NewEventStream(pool)
+
+	fmt.Println("Creating upcoming partitions...")
+	util.MustSucceed(stream.EnsurePartitions(ctx, now, *aheadMonths))
+
+	archiver := es.NewArchiver(pool, *archiveDir)
+	archiver.RegisterSnapshotter(checkout.CartType, checkout.NewPGCartRepository(pool).Snapshot)
+	archiver.RegisterSnapshotter(catalog.ProductType, catalog.NewPGProductRepository(pool).Snapshot)
+
+	cutoff := es.MonthlyPartition(now.AddDate(0, -*retainMonths, 0)).From
+	fmt.Printf("Archiving partitions before %s...\n", cutoff.Format(time.DateOnly))
+
+	archived, err := archiver.Archive(ctx, cutoff)
+	for _, p := range archived {
+		fmt.Printf("Archived %s: %d events -> %s\n", p.Name, p.Events, p.File)
+	}
+	util.MustSucceed(err)
+
+	fmt.Println("Archival completed successfully.")
+}
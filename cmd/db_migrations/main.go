@@ -3,20 +3,127 @@ package main
 import (
 	"context"
 	"es/internal"
+	"es/internal/cartsummary"
+	"es/internal/catalog"
+	"es/internal/es"
+	v1 "es/internal/inventory/v1"
+	v2 "es/internal/inventory/v2"
+	"es/internal/migrate"
+	"es/internal/sales"
 	"es/internal/util"
+	"flag"
+	"fmt"
+	"log"
 	"os"
+	"time"
+)
 
-	"fmt"
+var (
+	only      = flag.String("namespace", "", "only migrate this namespace (default: all)")
+	downSteps = flag.Int("down", 0, "revert this many migrations of -namespace instead of migrating up")
+	status    = flag.Bool("status", false, "print the applied migrations instead of migrating")
+	tenantID  = flag.String("tenant", "", "move events written before tenants existed to this tenant and rebuild the projections")
+	backfill  = flag.Bool("backfill-hashes", false, "hash the events written before events were hashed instead of migrating")
 )
 
+type namespace struct {
+	name       string
+	migrations []migrate.Migration
+	up         func(context.Context) error
+}
+
+func init() {
+	flag.Parse()
+}
+
 func main() {
-	fmt.Println("Migrating database...")
 	ctx := context.Background()
-	conn := internal.MustDBConn(ctx)
-	defer conn.Close(ctx)
+	pool := internal.MustDBPool(ctx)
+	defer pool.Close()
+
+	// The event store first, then every projection. Projections migrate
+	// through ApplyMigration, which rebuilds read models whose tables changed.
+	namespaces := []namespace{
+		{es.MigrationNamespace, es.Migrations(), nil},
+	}
+	for _, p := range []interface {
+		es.ProjectionWriter
+		Migrations() []migrate.Migration
+	}{
+		v1.NewProjection(pool),
+		v2.NewProjection(pool),
+		cartsummary.NewProjection(pool),
+		sales.NewProjection(pool),
+		catalog.NewProjection(pool),
+	} {
+		namespaces = append(namespaces, namespace{p.Name(), p.Migrations(), p.ApplyMigration})
+	}
+
+	if *downSteps > 0 && *only == "" {
+		log.Fatal("-down requires -namespace")
+	}
+
+	if *tenantID != "" && (*status || *downSteps > 0 || *only != "") {
+		log.Fatal("-tenant migrates every namespace and cannot be combined with -namespace, -down or -status")
+	}
+
+	if *backfill {
+		if *tenantID != "" || *status || *downSteps > 0 || *only != "" {
+			log.Fatal("-backfill-hashes cannot be combined with other flags")
+		}
+		hashed := util.Must(es.NewEventStream(pool).BackfillHashes(ctx))
+		fmt.Printf("Hashed %d events written before the hash chain\n", hashed)
+		return
+	}
+
+	// Projections keep the tenant of each event, so they are rebuilt once
+	// events have moved to a tenant.
+	rebuild := false
+
+	found := false
+	for _, ns := range namespaces {
+		if *only != "" && *only != ns.name {
+			continue
+		}
+		found = true
+		migrator := migrate.NewMigrator(pool, ns.name, ns.migrations)
+
+		switch {
+		case *status:
+			applied := util.Must(migrator.Status(ctx))
+			fmt.Printf("%s:\n", ns.name)
+			for _, a := range applied {
+				fmt.Printf("  %04d_%s applied at %s\n", a.Version, a.Name, a.AppliedAt.Format(time.RFC3339))
+			}
+		case *downSteps > 0:
+			reverted := util.Must(migrator.Down(ctx, *downSteps))
+			for _, m := range reverted {
+				fmt.Printf("Reverted %s %04d_%s\n", ns.name, m.Version, m.Name)
+			}
+		case ns.up != nil:
+			fmt.Printf("Migrating %s...\n", ns.name)
+			if rebuild {
+				util.Must(migrator.Down(ctx, 1))
+			}
+			util.MustSucceed(ns.up(ctx))
+		default:
+			fmt.Printf("Migrating %s...\n", ns.name)
+			applied := util.Must(migrator.Up(ctx))
+			for _, m := range applied {
+				fmt.Printf("Applied %s %04d_%s\n", ns.name, m.Version, m.Name)
+			}
+			if ns.name == es.MigrationNamespace && *tenantID != "" {
+				moved := util.Must(es.NewEventStream(pool).AssignTenant(ctx, *tenantID))
+				fmt.Printf("Moved %d aggregates to tenant %s\n", moved, *tenantID)
+				rebuild = moved > 0
+			}
+		}
+	}
 
-	sqlFile := util.Must(os.ReadFile("cmd/db_migrations/create_events_table.sql"))
-	_ = util.Must(conn.Exec(ctx, string(sqlFile)))
+	if !found {
+		fmt.Printf("unknown namespace %q\n", *only)
+		os.Exit(1)
+	}
 
 	fmt.Println("Migration executed successfully.")
 }
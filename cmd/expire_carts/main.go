@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"es/internal"
+	"es/internal/cartsummary"
+	"es/internal/checkout"
+	"es/internal/es"
+	"es/internal/expiry"
+	"es/internal/util"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+var (
+	idleFor   = flag.Duration("idle", 72*time.Hour, "expire open carts that have not changed for this long")
+	interval  = flag.Duration("interval", 0, "check for idle carts this often instead of once")
+	batchSize = flag.Int("batch", 100, "maximum number of carts to expire per check")
+)
+
+func init() {
+	err := godotenv.Load()
+	if err != nil {
+		log.Fatalf("failed to load .env file: %v", err)
+	}
+
+	flag.Parse()
+}
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	pool := internal.MustDBPool(ctx)
+	defer pool.Close()
+
+	bus := es.NewCommandBus(
+		es.Logging(),
+		es.Metrics(),
+		es.Authorization(es.RequireTenant),
+		es.Validation(),
+		es.RetryOnConflict(3, 20*time.Millisecond),
+	)
+	// Expiring carts adds no items, so there are no products to check.
+	checkout.RegisterCommandHandlers(bus, checkout.NewPGCartRepository(pool), nil, nil)
+
+	scheduler := expiry.NewScheduler(
+		cartsummary.NewPGCartSummaryRepository(pool),
+		bus,
+		*idleFor,
+		*batchSize,
+	)
+
+	if *interval > 0 {
+		fmt.Printf("Expiring carts idle for %s every %s...\n", *idleFor, *interval)
+		util.MustSucceed(scheduler.Run(ctx, *interval))
+		return
+	}
+
+	expired, err := scheduler.RunOnce(ctx)
+	fmt.Printf("Expired %d idle carts\n", expired)
+	util.MustSucceed(err)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"es/internal"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/joho/godotenv"
+)
+
+var address = flag.String("addr", ":6001", "address to serve gRPC on")
+
+func init() {
+	err := godotenv.Load()
+	if err != nil {
+		log.Fatalf("failed to load .env file: %v", err)
+	}
+
+	flag.Parse()
+}
+
+func main() {
+	ctx := context.Background()
+	pool := internal.MustDBPool(ctx)
+	defer pool.Close()
+
+	listener, err := net.Listen("tcp", *address)
+	if err != nil {
+		log.Fatalf("failed to listen on %s: %v", *address, err)
+	}
+
+	server := internal.NewGRPCServer(pool)
+
+	go func() {
+		signalCh := make(chan os.Signal, 1)
+		signal.Notify(signalCh, syscall.SIGTERM, syscall.SIGINT)
+		<-signalCh
+		fmt.Println("Received shutdown signal, exiting...")
+		server.GracefulStop()
+	}()
+
+	fmt.Printf("gRPC server listening on %s\n", *address)
+	if err := server.Serve(listener); err != nil {
+		log.Fatal(err)
+	}
+}
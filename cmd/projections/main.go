@@ -3,9 +3,12 @@ package main
 import (
 	"context"
 	"es/internal"
+	"es/internal/cartsummary"
+	"es/internal/catalog"
 	"es/internal/es"
 	v1 "es/internal/inventory/v1"
 	v2 "es/internal/inventory/v2"
+	"es/internal/sales"
 	"es/internal/util"
 	"fmt"
 	"log"
@@ -61,6 +64,42 @@ func main() {
 		util.MustSucceed(sub.Listen(ctx, stream))
 	}()
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sub := es.NewSubscription(
+			cartsummary.NewProjection(pool),
+			batchSize,
+			time.Second*2,
+		)
+
+		util.MustSucceed(sub.Listen(ctx, stream))
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sub := es.NewSubscription(
+			sales.NewProjection(pool),
+			batchSize,
+			time.Second*5,
+		)
+
+		util.MustSucceed(sub.Listen(ctx, stream))
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sub := es.NewSubscription(
+			catalog.NewProjection(pool),
+			batchSize,
+			time.Second*2,
+		)
+
+		util.MustSucceed(sub.Listen(ctx, stream))
+	}()
+
 	select {
 	case <-ctx.Done():
 		fmt.Println("Context timed out")
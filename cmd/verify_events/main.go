@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"es/internal"
+	"es/internal/es"
+	"es/internal/util"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+func init() {
+	err := godotenv.Load()
+	if err != nil {
+		log.Fatalf("failed to load .env file: %v", err)
+	}
+}
+
+func main() {
+	fmt.Println("Verifying event hash chain...")
+	ctx := context.Background()
+	pool := internal.MustDBPool(ctx)
+	defer pool.Close()
+
+	stream := es.NewEventStream(pool)
+	broken := util.Must(stream.Verify(ctx))
+
+	if broken != nil {
+		fmt.Printf(
+			"Broken link at position=%d aggregate=%s/%s version=%d expected=%s actual=%s\n",
+			broken.Position,
+			broken.AggregateType,
+			broken.AggregateID,
+			broken.VersionID,
+			broken.ExpectedHash,
+			broken.ActualHash,
+		)
+		os.Exit(1)
+	}
+
+	fmt.Println("Event hash chain is intact.")
+}
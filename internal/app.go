@@ -1,12 +1,22 @@
 package internal
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"es/internal/authentication"
+	"es/internal/cache"
+	"es/internal/cartsummary"
+	"es/internal/catalog"
 	"es/internal/checkout"
 	"es/internal/es"
+	"es/internal/graphqlapi"
+	"es/internal/idempotency"
 	v2 "es/internal/inventory/v2"
+	"es/internal/openapi"
+	"es/internal/problem"
+	"es/internal/sales"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/adaptor"
@@ -18,8 +28,12 @@ import (
 
 func NewApi(pool *pgxpool.Pool) *fiber.App {
 	eventStream := es.NewEventStream(pool)
+	eventStream.RegisterAggregate(checkout.CartType, checkout.NewReplayableCart)
+	eventStream.RegisterAggregate(catalog.ProductType, catalog.NewReplayableProduct)
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{
+		ErrorHandler: problem.ErrorHandler,
+	})
 
 	app.Get("/metrics", adaptor.HTTPHandler(promhttp.Handler()))
 
@@ -46,26 +60,154 @@ func NewApi(pool *pgxpool.Pool) *fiber.App {
 	if err != nil {
 		panic(fmt.Sprintf("failed to initialize auth middleware: %v", err))
 	}
-	repo := checkout.NewPGCartRepository(pool)
-	usecase := checkout.NewCheckoutUseCase(repo)
+	redisClient, err := cache.NewRedisClient(cfg.Redis)
+	if err != nil {
+		panic(fmt.Sprintf("failed to initialize redis client: %v", err))
+	}
+	idempotent := idempotency.Middleware(
+		idempotency.NewRedisStore(redisClient),
+		idempotency.LoadConfig(),
+	)
+
+	// Routes are registered through the OpenAPI document, which documents
+	// them from the Operations of their handlers.
+	doc := openapi.NewDocument("Event Sourcing Shopping Cart API", "1.0.0")
+	doc.SetErrorResponse(problem.ContentType, problem.Problem{})
+
+	usecase := newCheckoutUseCase(pool)
 	h := checkout.NewRouteHandler(usecase)
 
-	api := app.Group("/cart", authMW)
-	api.Get("/:cartID", h.GetCartDetails)
-	api.Get("/:cartID/:itemID", h.AddItem)
-	api.Get("/:cartID/:itemID/delete", h.RemoveItem)
-	api.Post("/:cartID/checkout", h.Checkout)
+	api := doc.Group(app, "/cart", authMW, checkout.Deprecated("/v2/carts"))
+	api.Post("/", checkout.Operations.CreateCart, idempotent, h.CreateCart)
+	api.Get("/:cartID", checkout.Operations.GetCartDetails, h.GetCartDetails)
+	api.Get("/:cartID/:itemID", checkout.Operations.AddItem, idempotent, h.AddItem)
+	api.Get("/:cartID/:itemID/delete", checkout.Operations.RemoveItem, idempotent, h.RemoveItem)
+	api.Post("/:cartID/checkout", checkout.Operations.Checkout, idempotent, h.Checkout)
+
+	// Reads of projections wait for the X-Event-Position of earlier commands
+	// given as min_position.
+	consistency := es.LoadConsistencyConfig()
+	summaryReads := es.ReadYourWrites(consistency, cartsummary.NewProjection(pool))
+	inventoryReads := es.ReadYourWrites(consistency, v2.NewProjection(pool))
+	salesReads := es.ReadYourWrites(consistency, sales.NewProjection(pool))
+	catalogReads := es.ReadYourWrites(consistency, catalog.NewProjection(pool))
+
+	summaryHandler := cartsummary.NewRouteHandler(cartsummary.NewPGCartSummaryRepository(pool))
+	doc.Group(app, "/carts", authMW).Get("/", cartsummary.Operations.ListCarts, summaryReads, summaryHandler.ListCarts)
+
+	v2h := checkout.NewV2RouteHandler(usecase)
+	carts := doc.Group(app, "/v2/carts", authMW)
+	carts.Get("/", cartsummary.Operations.ListCarts, summaryReads, summaryHandler.ListCarts)
+	carts.Post("/", checkout.V2Operations.CreateCart, idempotent, v2h.CreateCart)
+	carts.Get("/:cartID", checkout.V2Operations.GetCart, v2h.GetCart)
+	carts.Get("/:cartID/items/:itemID", checkout.V2Operations.GetItem, v2h.GetItem)
+	carts.Post("/:cartID/items", checkout.V2Operations.AddItem, checkout.RequireIfMatch, idempotent, v2h.AddItem)
+	carts.Put("/:cartID/items", checkout.V2Operations.SetContents, checkout.RequireIfMatch, idempotent, v2h.SetContents)
+	carts.Put("/:cartID/items/:itemID", checkout.V2Operations.SetItemQuantity, checkout.RequireIfMatch, idempotent, v2h.SetItemQuantity)
+	carts.Delete("/:cartID/items/:itemID", checkout.V2Operations.RemoveItem, checkout.RequireIfMatch, idempotent, v2h.RemoveItem)
+	carts.Post("/:cartID/checkout", checkout.V2Operations.Checkout, checkout.RequireIfMatch, idempotent, v2h.Checkout)
+
+	// Only tokens granting catalog:write may change the catalog, shoppers
+	// can only read it.
+	catalogWrite := authentication.RequireScope(catalog.WriteScope)
+	catalogHandler := catalog.NewRouteHandler(newCatalogUseCase(pool), catalog.NewPGProductListing(pool))
+	products := doc.Group(app, "/catalog/products", authMW)
+	products.Get("/", catalog.Operations.ListProducts, catalogReads, catalogHandler.ListProducts)
+	products.Post("/", catalog.Operations.CreateProduct, catalogWrite, idempotent, catalogHandler.CreateProduct)
+	products.Get("/:productID", catalog.Operations.GetProduct, catalogHandler.GetProduct)
+	products.Put("/:productID/name", catalog.Operations.RenameProduct, catalogWrite, idempotent, catalogHandler.RenameProduct)
+	products.Put("/:productID/price", catalog.Operations.ChangePrice, catalogWrite, idempotent, catalogHandler.ChangePrice)
+	products.Post("/:productID/discontinue", catalog.Operations.DiscontinueProduct, catalogWrite, idempotent, catalogHandler.DiscontinueProduct)
 
 	invRepo := v2.NewPGItemCountRepository(pool)
 	invHandler := v2.NewRouteHandler(invRepo)
 
-	inventoryApi := app.Group("/inventory/v2", authMW)
-	inventoryApi.Get("/", invHandler.Get)
+	gqlHandler := graphqlapi.NewRouteHandler(graphqlapi.NewSchema(usecase, invRepo, eventStream, time.Second), 15*time.Second)
+	doc.Group(app, "/graphql", authMW).Post("/", graphqlapi.Operations.Serve, gqlHandler.Serve)
+
+	inventoryApi := doc.Group(app, "/inventory/v2", authMW)
+	inventoryApi.Get("/", v2.Operations.Get, inventoryReads, invHandler.Get)
+
+	salesHandler := sales.NewRouteHandler(sales.NewPGSalesRepository(pool))
 
-	eventsApi := app.Group("/events")
+	salesApi := doc.Group(app, "/analytics/sales", authMW)
+	salesApi.Get("/top-items", sales.Operations.TopItems, salesReads, salesHandler.TopItems)
+	salesApi.Get("/units", sales.Operations.UnitsSold, salesReads, salesHandler.UnitsSold)
+	salesApi.Get("/conversion", sales.Operations.Conversion, salesReads, salesHandler.Conversion)
+
+	eventsApi := doc.Group(app, "/events", authMW)
 
 	eHandler := es.NewRouteHandler(eventStream)
-	eventsApi.Get("/:aggType/:aggID", eHandler.AggregateEvents)
+	eventsApi.Get("/schemas", es.Operations.EventSchemas, eHandler.EventSchemas)
+	eventsApi.Get("/:aggType/:aggID", es.Operations.AggregateEvents, eHandler.AggregateEvents)
+	eventsApi.Get("/:aggType/:aggID/verify", es.Operations.VerifyAggregate, eHandler.VerifyAggregate)
+	eventsApi.Get("/:aggType/:aggID/timeline", es.Operations.AggregateTimeline, eHandler.AggregateTimeline)
+
+	if err := doc.Spec().Validate(context.Background()); err != nil {
+		panic(fmt.Sprintf("invalid OpenAPI document: %v", err))
+	}
+	app.Get("/openapi.json", doc.Serve)
+	app.Get("/docs", doc.SwaggerUI("/openapi.json"))
 
 	return app
 }
+
+// newCommandBus returns a command bus with the middleware chain shared by
+// the HTTP and gRPC APIs.
+func newCommandBus() *es.CommandBus {
+	return es.NewCommandBus(
+		es.Tracing(),
+		es.Logging(),
+		es.Metrics(),
+		es.Authorization(es.RequireTenant),
+		es.Validation(),
+		es.RetryOnConflict(3, 20*time.Millisecond),
+	)
+}
+
+// newCheckoutUseCase wires the cart use case to a command bus, checking
+// the items added to carts against the product catalog and pricing carts
+// at its prices.
+func newCheckoutUseCase(pool *pgxpool.Pool) *checkout.CheckoutUseCase {
+	cfg, err := checkout.LoadPricingConfig()
+	if err != nil {
+		panic(fmt.Sprintf("invalid pricing config: %v", err))
+	}
+	checkProducts, err := checkout.LoadProductChecks()
+	if err != nil {
+		panic(fmt.Sprintf("invalid product checks config: %v", err))
+	}
+	products := catalogProducts{catalog.NewPGProductRepository(pool)}
+	pricer := checkout.NewPricer(products, cfg)
+
+	var checked checkout.ProductCatalog
+	if checkProducts {
+		checked = products
+	}
+	repo := checkout.NewPGCartRepository(pool)
+	bus := newCommandBus()
+	checkout.RegisterCommandHandlers(bus, repo, checked, pricer)
+	return checkout.NewCheckoutUseCase(repo, bus, pricer)
+}
+
+func newCatalogUseCase(pool *pgxpool.Pool) *catalog.CatalogUseCase {
+	repo := catalog.NewPGProductRepository(pool)
+	bus := newCommandBus()
+	catalog.RegisterCommandHandlers(bus, repo)
+	return catalog.NewCatalogUseCase(repo, bus)
+}
+
+// catalogProducts looks up the products of carts in their events rather
+// than the catalog projection, so a product can be added to carts as soon
+// as it is created, and not once it is discontinued.
+type catalogProducts struct {
+	repo catalog.ProductRepository
+}
+
+func (p catalogProducts) FindProduct(ctx context.Context, tenantID string, productID int) (*checkout.Product, error) {
+	product, err := p.repo.Get(ctx, tenantID, productID)
+	if err != nil || product == nil {
+		return nil, err
+	}
+	return &checkout.Product{ID: product.ID, Price: product.Price, Discontinued: product.Discontinued}, nil
+}
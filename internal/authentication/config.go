@@ -13,6 +13,7 @@ type Config struct {
 	RedirectURL  string
 	IssuerURL    string
 	JWKSURL      string
+	TenantClaim  string
 	Redis        cache.RedisConfig
 	AuthCacheTTL time.Duration
 }
@@ -24,6 +25,12 @@ func LoadConfig() Config {
 	issuerURL := os.Getenv("ISSUER_URL")
 	jwksURL := os.Getenv("JWKS_URL")
 
+	// JWT claim holding the tenant (storefront) the caller belongs to
+	tenantClaim := os.Getenv("TENANT_CLAIM")
+	if tenantClaim == "" {
+		tenantClaim = "custom:tenant_id"
+	}
+
 	// Redis configuration with defaults
 	redisAddr := os.Getenv("REDIS_ADDR")
 	if redisAddr == "" {
@@ -60,6 +67,7 @@ func LoadConfig() Config {
 		RedirectURL:  redirectURL,
 		IssuerURL:    issuerURL,
 		JWKSURL:      jwksURL,
+		TenantClaim:  tenantClaim,
 		AuthCacheTTL: cacheTTL,
 		Redis: cache.RedisConfig{
 			Addr:     redisAddr,
@@ -6,23 +6,32 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
 	"es/internal/cache"
+	"es/internal/tenant"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v4"
 	"gopkg.in/go-jose/go-jose.v2"
 )
 
-// AuthMiddleware creates an authentication middleware with Redis-backed JWKS caching.
-// The middleware:
-//  1. Extracts JWT from Authorization header
-//  2. Validates claims (expiration, issuer, audience)
-//  3. Retrieves JWKS from Redis cache or HTTP endpoint
-//  4. Verifies token signature against cached keys
-func AuthMiddleware(cfg Config) (fiber.Handler, error) {
+var (
+	ErrMissingToken       = errors.New("missing authorization token")
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrMissingTenantClaim = errors.New("missing tenant claim")
+)
+
+// Authenticator verifies bearer tokens with Redis-backed JWKS caching. It
+// is shared by the HTTP middleware and the gRPC interceptors.
+type Authenticator struct {
+	verifyer    *verifyer
+	tenantClaim string
+}
+
+func NewAuthenticator(cfg Config) (*Authenticator, error) {
 	// Initialize Redis client for JWKS caching
 	redisClient, err := cache.NewRedisClient(cfg.Redis)
 	if err != nil {
@@ -30,26 +39,124 @@ func AuthMiddleware(cfg Config) (fiber.Handler, error) {
 	}
 
 	jwksCache := cache.NewJWKSCache(redisClient, cfg.JWKSURL, cfg.AuthCacheTTL)
-	verifyer := newVerifyer(cfg, jwksCache)
 
-	return func(c *fiber.Ctx) error {
-		tokenString := c.Get("Authorization")
-		if tokenString == "" {
-			return c.Status(http.StatusUnauthorized).SendString("missing authorization token")
-		}
+	return NewJWKSAuthenticator(cfg, jwksCache), nil
+}
+
+// NewJWKSAuthenticator returns an authenticator verifying signatures with
+// the keys of the given cache.
+func NewJWKSAuthenticator(cfg Config, jwksCache *cache.JWKSCache) *Authenticator {
+	return &Authenticator{
+		verifyer:    newVerifyer(cfg, jwksCache),
+		tenantClaim: cfg.TenantClaim,
+	}
+}
+
+// Authenticate verifies the token of an Authorization header value and
+// returns its claims and the tenant named in the configured tenant claim.
+//  1. Extracts JWT from the header value
+//  2. Validates claims (expiration, issuer, audience)
+//  3. Retrieves JWKS from Redis cache or HTTP endpoint
+//  4. Verifies token signature against cached keys
+//  5. Reads the tenant claim
+func (a *Authenticator) Authenticate(ctx context.Context, authorization string) (jwt.MapClaims, string, error) {
+	if authorization == "" {
+		return nil, "", ErrMissingToken
+	}
+
+	tokenString := strings.TrimPrefix(authorization, "Bearer ")
+
+	claims, err := a.verifyer.verifyToken(ctx, tokenString)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	tenantID, ok := claims[a.tenantClaim].(string)
+	if !ok || tenantID == "" {
+		return nil, "", ErrMissingTenantClaim
+	}
+
+	return claims, tenantID, nil
+}
 
-		tokenString = strings.TrimPrefix(tokenString, "Bearer ")
+// AuthMiddleware creates an authentication middleware that verifies the
+// token of the Authorization header and scopes the request to its tenant.
+func AuthMiddleware(cfg Config) (fiber.Handler, error) {
+	authenticator, err := NewAuthenticator(cfg)
+	if err != nil {
+		return nil, err
+	}
 
-		claims, err := verifyer.verifyToken(c.Context(), tokenString)
+	return authenticator.Middleware(), nil
+}
+
+// Middleware verifies the token of the Authorization header and scopes the
+// request to its tenant. Requests without a valid token are answered with
+// 401, tokens naming no tenant with 403.
+func (a *Authenticator) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, tenantID, err := a.Authenticate(c.Context(), c.Get("Authorization"))
+		if errors.Is(err, ErrMissingTenantClaim) {
+			return fiber.NewError(http.StatusForbidden, err.Error())
+		}
 		if err != nil {
-			return c.Status(http.StatusUnauthorized).SendString(fmt.Sprintf("Invalid token: %v", err))
+			return fiber.NewError(http.StatusUnauthorized, err.Error())
 		}
 
-		// Add claims to request context
+		// Add claims and tenant to request context
 		c.Locals("user", claims)
+		tenant.Set(c, tenantID)
 
 		return c.Next()
-	}, nil
+	}
+}
+
+// RequireScope lets requests through only if their verified token grants
+// the scope, as a space separated "scope" claim or a "scp" list. Others are
+// answered with 403. It must run after the authentication middleware.
+func RequireScope(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, _ := c.Locals("user").(jwt.MapClaims)
+		if !hasScope(claims, scope) {
+			return fiber.NewError(http.StatusForbidden, fmt.Sprintf("token lacks the %s scope", scope))
+		}
+		return c.Next()
+	}
+}
+
+func hasScope(claims jwt.MapClaims, scope string) bool {
+	if granted, ok := claims["scope"].(string); ok && slices.Contains(strings.Fields(granted), scope) {
+		return true
+	}
+	granted, _ := claims["scp"].([]any)
+	return slices.Contains(granted, any(scope))
+}
+
+type claimsKey struct{}
+
+// WithClaims returns a context carrying the claims of a verified token, for
+// callers outside of fiber handlers.
+func WithClaims(ctx context.Context, claims jwt.MapClaims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// SubjectFromContext returns the subject claim of the claims carried by the
+// context, or an empty string if there is none.
+func SubjectFromContext(ctx context.Context) string {
+	claims, _ := ctx.Value(claimsKey{}).(jwt.MapClaims)
+	sub, _ := claims["sub"].(string)
+	return sub
+}
+
+// Subject returns the subject claim of the verified token, identifying the
+// user making the request, or an empty string if there is none.
+func Subject(c *fiber.Ctx) string {
+	claims, ok := c.Locals("user").(jwt.MapClaims)
+	if !ok {
+		return ""
+	}
+	sub, _ := claims["sub"].(string)
+	return sub
 }
 
 type verifyer struct {
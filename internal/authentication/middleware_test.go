@@ -0,0 +1,150 @@
+package authentication_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"es/internal/authentication"
+	"es/internal/cache"
+	"es/internal/tenant"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/go-jose/go-jose.v2"
+)
+
+// emptyRedis misses every lookup, so keys are fetched from the JWKS URL.
+type emptyRedis struct{}
+
+func (emptyRedis) Get(context.Context, string) *redis.StringCmd {
+	return redis.NewStringResult("", redis.Nil)
+}
+
+func (emptyRedis) Set(context.Context, string, any, time.Duration) *redis.StatusCmd {
+	return redis.NewStatusResult("OK", nil)
+}
+
+var testConfig = authentication.Config{
+	ClientID:    "client-1",
+	IssuerURL:   "https://issuer.example.com",
+	TenantClaim: "custom:tenant_id",
+}
+
+// newTestAuthenticator serves a key over JWKS and returns an authenticator
+// using it together with a function signing tokens with the key.
+func newTestAuthenticator(t *testing.T) (*authentication.Authenticator, func(claims jwt.MapClaims) string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &key.PublicKey, KeyID: "key-1", Algorithm: "RS256", Use: "sig"},
+		}})
+	}))
+	t.Cleanup(server.Close)
+
+	authenticator := authentication.NewJWKSAuthenticator(testConfig, cache.NewJWKSCache(emptyRedis{}, server.URL, time.Hour))
+	sign := func(claims jwt.MapClaims) string {
+		base := jwt.MapClaims{
+			"exp":       float64(time.Now().Add(time.Hour).Unix()),
+			"iss":       testConfig.IssuerURL,
+			"client_id": testConfig.ClientID,
+			"sub":       "alice",
+		}
+		for k, v := range claims {
+			base[k] = v
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, base)
+		token.Header["kid"] = "key-1"
+		signed, err := token.SignedString(key)
+		require.NoError(t, err)
+		return signed
+	}
+	return authenticator, sign
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	authenticator, sign := newTestAuthenticator(t)
+
+	app := fiber.New()
+	app.Get("/", authenticator.Middleware(), func(c *fiber.Ctx) error {
+		tenantID, err := tenant.FromRequest(c)
+		if err != nil {
+			return err
+		}
+		return c.SendString(tenantID + "/" + authentication.Subject(c))
+	})
+
+	get := func(t *testing.T, authorization string) (int, string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		body := make([]byte, 64)
+		n, _ := resp.Body.Read(body)
+		return resp.StatusCode, string(body[:n])
+	}
+
+	t.Run("scopes the request to the tenant of the token", func(t *testing.T) {
+		status, body := get(t, "Bearer "+sign(jwt.MapClaims{"custom:tenant_id": "store-a"}))
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "store-a/alice", body)
+	})
+
+	t.Run("tokens without a tenant are forbidden", func(t *testing.T) {
+		status, _ := get(t, "Bearer "+sign(jwt.MapClaims{}))
+		assert.Equal(t, http.StatusForbidden, status)
+
+		status, _ = get(t, "Bearer "+sign(jwt.MapClaims{"custom:tenant_id": ""}))
+		assert.Equal(t, http.StatusForbidden, status, "An empty tenant should not match the events of the empty tenant")
+	})
+
+	t.Run("requests without a valid token are unauthorized", func(t *testing.T) {
+		status, _ := get(t, "")
+		assert.Equal(t, http.StatusUnauthorized, status)
+
+		status, _ = get(t, "Bearer "+sign(jwt.MapClaims{"custom:tenant_id": "store-a", "iss": "https://other.example.com"}))
+		assert.Equal(t, http.StatusUnauthorized, status)
+	})
+}
+
+func TestRequireScope(t *testing.T) {
+	authenticator, sign := newTestAuthenticator(t)
+
+	app := fiber.New()
+	app.Put("/", authenticator.Middleware(), authentication.RequireScope("catalog:write"), func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusNoContent)
+	})
+
+	put := func(t *testing.T, claims jwt.MapClaims) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPut, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+sign(claims))
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	t.Run("tokens granting the scope", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, put(t, jwt.MapClaims{"custom:tenant_id": "store-a", "scope": "openid catalog:write"}))
+		assert.Equal(t, http.StatusNoContent, put(t, jwt.MapClaims{"custom:tenant_id": "store-a", "scp": []any{"catalog:write"}}))
+	})
+
+	t.Run("tokens without the scope are forbidden", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, put(t, jwt.MapClaims{"custom:tenant_id": "store-a"}))
+		assert.Equal(t, http.StatusForbidden, put(t, jwt.MapClaims{"custom:tenant_id": "store-a", "scope": "openid catalog:read"}))
+	})
+}
@@ -0,0 +1,112 @@
+// Package cartsummary keeps a summary of every cart, with its contents and
+// timestamps, for listing carts without replaying their events.
+package cartsummary
+
+import (
+	"es/internal/checkout"
+	"es/internal/es"
+	"es/internal/es/sqlprojection"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	StatusOpen       = "open"
+	StatusCheckedOut = "checked_out"
+	StatusExpired    = "expired"
+)
+
+// Projection summarises carts in tables of the cart_summary schema.
+type Projection struct {
+	*sqlprojection.Projection
+}
+
+func NewProjection(pool *pgxpool.Pool) *Projection {
+	return &Projection{
+		Projection: sqlprojection.New(pool, "cart_summary").
+			Table(sqlprojection.Table{
+				Name: "carts",
+				Columns: []sqlprojection.Column{
+					{Name: "tenant_id", Type: "VARCHAR(64) NOT NULL"},
+					{Name: "cart_id", Type: "VARCHAR(64) NOT NULL"},
+					{Name: "owner", Type: "TEXT NOT NULL DEFAULT ''"},
+					{Name: "status", Type: "VARCHAR(16) NOT NULL DEFAULT 'open'"},
+					{Name: "item_count", Type: "INTEGER NOT NULL DEFAULT 0"},
+					{Name: "created_at", Type: "TIMESTAMPTZ"},
+					{Name: "updated_at", Type: "TIMESTAMPTZ"},
+					{Name: "checked_out_at", Type: "TIMESTAMPTZ"},
+				},
+				Key:     []string{"tenant_id", "cart_id"},
+				Indexes: []string{"tenant_id, updated_at", "tenant_id, owner", "status, updated_at"},
+			}).
+			Table(sqlprojection.Table{
+				Name: "cart_items",
+				Columns: []sqlprojection.Column{
+					{Name: "tenant_id", Type: "VARCHAR(64) NOT NULL"},
+					{Name: "cart_id", Type: "VARCHAR(64) NOT NULL"},
+					{Name: "item_id", Type: "INTEGER NOT NULL"},
+					{Name: "quantity", Type: "INTEGER NOT NULL"},
+				},
+				Key: []string{"tenant_id", "cart_id", "item_id"},
+			}).
+			On(checkout.CartCreated, cartCreated).
+			On(checkout.ItemAddedToCart, itemQuantityChanged).
+			On(checkout.ItemRemovedFromCart, itemQuantityChanged).
+			On(checkout.ItemQuantityChanged, itemQuantityChanged).
+			On(checkout.CartCheckedOut, cartCheckedOut).
+			On(checkout.CartExpired, cartExpired),
+	}
+}
+
+type createdPayload struct {
+	Owner string `json:"owner"`
+}
+
+func cartCreated(event es.Event) ([]sqlprojection.Op, error) {
+	data, err := es.DecodeData[createdPayload](event)
+	if err != nil {
+		return nil, err
+	}
+
+	return []sqlprojection.Op{
+		sqlprojection.Upsert("carts", sqlprojection.Row{
+			"tenant_id":  event.TenantID,
+			"cart_id":    event.AggregateID,
+			"owner":      data.Owner,
+			"status":     StatusOpen,
+			"created_at": event.At.UTC(),
+			"updated_at": event.At.UTC(),
+		}),
+	}, nil
+}
+
+// itemQuantityChanged counts the units of the cart and marks it updated.
+var itemQuantityChanged = checkout.QuantityDeltaHandler(func(event es.Event, delta int) []sqlprojection.Op {
+	cart := sqlprojection.Row{"tenant_id": event.TenantID, "cart_id": event.AggregateID}
+	return []sqlprojection.Op{
+		sqlprojection.Increment("carts", cart, sqlprojection.Row{"item_count": delta}),
+		sqlprojection.Update("carts", cart, sqlprojection.Row{"updated_at": event.At.UTC()}),
+	}
+})
+
+func cartCheckedOut(event es.Event) ([]sqlprojection.Op, error) {
+	return []sqlprojection.Op{
+		sqlprojection.Update("carts",
+			sqlprojection.Row{"tenant_id": event.TenantID, "cart_id": event.AggregateID},
+			sqlprojection.Row{
+				"status":         StatusCheckedOut,
+				"updated_at":     event.At.UTC(),
+				"checked_out_at": event.At.UTC(),
+			},
+		),
+	}, nil
+}
+
+func cartExpired(event es.Event) ([]sqlprojection.Op, error) {
+	return []sqlprojection.Op{
+		sqlprojection.Update("carts",
+			sqlprojection.Row{"tenant_id": event.TenantID, "cart_id": event.AggregateID},
+			sqlprojection.Row{"status": StatusExpired, "updated_at": event.At.UTC()},
+		),
+	}, nil
+}
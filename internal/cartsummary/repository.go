@@ -0,0 +1,194 @@
+package cartsummary
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Item struct {
+	ItemID   int `json:"item_id"`
+	Quantity int `json:"quantity"`
+}
+
+type CartSummary struct {
+	ID           string     `json:"cart_id"`
+	Owner        string     `json:"owner"`
+	Status       string     `json:"status"`
+	ItemCount    int        `json:"item_count"`
+	Items        []Item     `json:"items"`
+	CreatedAt    *time.Time `json:"created_at"`
+	UpdatedAt    *time.Time `json:"updated_at"`
+	CheckedOutAt *time.Time `json:"checked_out_at,omitempty"`
+}
+
+// Filter selects a page of a tenant's carts, most recently updated first.
+// Zero values of Status, Owner and UpdatedSince match every cart.
+type Filter struct {
+	Status       string
+	Owner        string
+	UpdatedSince time.Time
+	Limit        int
+	Offset       int
+}
+
+// Page is a page of carts. NextOffset is set when there are more carts.
+type Page struct {
+	Carts      []CartSummary `json:"carts"`
+	NextOffset *int          `json:"next_offset,omitempty"`
+}
+
+// CartSummaryRepository lists carts from the cart_summary read model.
+type CartSummaryRepository interface {
+	ListCarts(ctx context.Context, tenantID string, filter Filter) (Page, error)
+}
+
+type PGCartSummaryRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPGCartSummaryRepository(pool *pgxpool.Pool) *PGCartSummaryRepository {
+	return &PGCartSummaryRepository{
+		pool: pool,
+	}
+}
+
+// ListCarts returns the carts of the tenant matching the filter, with the
+// items they currently hold.
+func (r *PGCartSummaryRepository) ListCarts(ctx context.Context, tenantID string, filter Filter) (Page, error) {
+	var updatedSince *time.Time
+	if !filter.UpdatedSince.IsZero() {
+		updatedSince = &filter.UpdatedSince
+	}
+
+	// One row more than the page tells whether there is a next page.
+	rows, err := r.pool.Query(ctx, `
+		SELECT cart_id, owner, status, item_count, created_at, updated_at, checked_out_at
+		FROM cart_summary.carts
+		WHERE tenant_id = $1
+		AND ($2 = '' OR status = $2)
+		AND ($3 = '' OR owner = $3)
+		AND ($4::TIMESTAMPTZ IS NULL OR updated_at >= $4)
+		ORDER BY updated_at DESC NULLS LAST, cart_id
+		LIMIT $5 OFFSET $6`,
+		tenantID, filter.Status, filter.Owner, updatedSince, filter.Limit+1, filter.Offset,
+	)
+	if err != nil {
+		return Page{}, fmt.Errorf("query carts: %w", err)
+	}
+	defer rows.Close()
+
+	page := Page{Carts: make([]CartSummary, 0, filter.Limit)}
+	for rows.Next() {
+		var cart CartSummary
+		if err := rows.Scan(
+			&cart.ID, &cart.Owner, &cart.Status, &cart.ItemCount,
+			&cart.CreatedAt, &cart.UpdatedAt, &cart.CheckedOutAt,
+		); err != nil {
+			return Page{}, fmt.Errorf("scan cart: %w", err)
+		}
+		cart.CreatedAt = inUTC(cart.CreatedAt)
+		cart.UpdatedAt = inUTC(cart.UpdatedAt)
+		cart.CheckedOutAt = inUTC(cart.CheckedOutAt)
+		cart.Items = []Item{}
+		page.Carts = append(page.Carts, cart)
+	}
+	if err := rows.Err(); err != nil {
+		return Page{}, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	if len(page.Carts) > filter.Limit {
+		page.Carts = page.Carts[:filter.Limit]
+		next := filter.Offset + filter.Limit
+		page.NextOffset = &next
+	}
+
+	if err := r.loadItems(ctx, tenantID, page.Carts); err != nil {
+		return Page{}, err
+	}
+	return page, nil
+}
+
+// CartRef identifies a cart of a tenant.
+type CartRef struct {
+	TenantID string
+	CartID   string
+}
+
+// IdleCarts returns open carts of every tenant that have not changed since
+// idleSince, least recently updated first.
+func (r *PGCartSummaryRepository) IdleCarts(ctx context.Context, idleSince time.Time, limit int) ([]CartRef, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT tenant_id, cart_id
+		FROM cart_summary.carts
+		WHERE status = $1 AND updated_at < $2
+		ORDER BY updated_at
+		LIMIT $3`,
+		StatusOpen, idleSince, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query idle carts: %w", err)
+	}
+	defer rows.Close()
+
+	carts := make([]CartRef, 0)
+	for rows.Next() {
+		var cart CartRef
+		if err := rows.Scan(&cart.TenantID, &cart.CartID); err != nil {
+			return nil, fmt.Errorf("scan cart: %w", err)
+		}
+		carts = append(carts, cart)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return carts, nil
+}
+
+// inUTC reports a time of the read model in UTC, as pgx reads TIMESTAMPTZ
+// columns in time.Local.
+func inUTC(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+func (r *PGCartSummaryRepository) loadItems(ctx context.Context, tenantID string, carts []CartSummary) error {
+	if len(carts) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*CartSummary, len(carts))
+	ids := make([]string, len(carts))
+	for i := range carts {
+		byID[carts[i].ID] = &carts[i]
+		ids[i] = carts[i].ID
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT cart_id, item_id, quantity
+		FROM cart_summary.cart_items
+		WHERE tenant_id = $1 AND cart_id = ANY($2) AND quantity > 0
+		ORDER BY cart_id, item_id`,
+		tenantID, ids,
+	)
+	if err != nil {
+		return fmt.Errorf("query cart items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cartID string
+		var item Item
+		if err := rows.Scan(&cartID, &item.ItemID, &item.Quantity); err != nil {
+			return fmt.Errorf("scan cart item: %w", err)
+		}
+		cart := byID[cartID]
+		cart.Items = append(cart.Items, item)
+	}
+	return rows.Err()
+}
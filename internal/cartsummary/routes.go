@@ -0,0 +1,91 @@
+package cartsummary
+
+import (
+	"es/internal/es"
+	"es/internal/openapi"
+	"es/internal/tenant"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+type RouteHandler struct {
+	repo CartSummaryRepository
+}
+
+func NewRouteHandler(repo CartSummaryRepository) *RouteHandler {
+	return &RouteHandler{
+		repo: repo,
+	}
+}
+
+// Operations documents the handlers of RouteHandler.
+var Operations = struct {
+	ListCarts openapi.Operation
+}{
+	ListCarts: openapi.Operation{
+		Summary: "List carts",
+		Tags:    []string{"carts"},
+		Query: []openapi.Parameter{
+			{Name: "status", Enum: []any{StatusOpen, StatusCheckedOut, StatusExpired}},
+			{Name: "owner", Description: "Subject of the user owning the carts"},
+			{Name: "updated_since", Description: "RFC 3339 timestamp", Type: time.Time{}},
+			{Name: "limit", Description: "At most 100, 20 by default", Type: 0},
+			{Name: "offset", Type: 0},
+			es.MinPositionParam,
+		},
+		Responses: map[int]any{http.StatusOK: Page{}},
+	},
+}
+
+// ListCarts returns a page of the tenant's carts, filtered by the status,
+// owner and updated_since query parameters and paged with limit and offset.
+func (h *RouteHandler) ListCarts(c *fiber.Ctx) error {
+	tenantID, err := tenant.FromRequest(c)
+
+	if err != nil {
+		return fiber.NewError(http.StatusForbidden, err.Error())
+	}
+
+	filter := Filter{
+		Status: c.Query("status"),
+		Owner:  c.Query("owner"),
+		Limit:  c.QueryInt("limit", DefaultLimit),
+		Offset: c.QueryInt("offset", 0),
+	}
+
+	switch filter.Status {
+	case "", StatusOpen, StatusCheckedOut, StatusExpired:
+	default:
+		return fiber.NewError(http.StatusBadRequest, "status must be open, checked_out or expired")
+	}
+
+	if v := c.Query("updated_since"); v != "" {
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return fiber.NewError(http.StatusBadRequest, "updated_since must be an RFC 3339 timestamp")
+		}
+		filter.UpdatedSince = t
+	}
+
+	if filter.Limit <= 0 || filter.Limit > MaxLimit {
+		return fiber.NewError(http.StatusBadRequest, "limit must be between 1 and 100")
+	}
+	if filter.Offset < 0 {
+		return fiber.NewError(http.StatusBadRequest, "offset must not be negative")
+	}
+
+	page, err := h.repo.ListCarts(c.Context(), tenantID, filter)
+
+	if err != nil {
+		return err
+	}
+
+	return c.Status(http.StatusOK).JSON(page)
+}
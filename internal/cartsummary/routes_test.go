@@ -0,0 +1,82 @@
+package cartsummary_test
+
+import (
+	"context"
+	"encoding/json"
+	"es/internal/cartsummary"
+	"es/internal/openapi"
+	"es/internal/openapi/openapitest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRepository struct {
+	tenantID string
+	filter   cartsummary.Filter
+}
+
+func (r *fakeRepository) ListCarts(_ context.Context, tenantID string, filter cartsummary.Filter) (cartsummary.Page, error) {
+	r.tenantID = tenantID
+	r.filter = filter
+	return cartsummary.Page{Carts: []cartsummary.CartSummary{{ID: "cart-1001", Items: []cartsummary.Item{}}}}, nil
+}
+
+// newTestApp serves the cart listing, checking requests and responses
+// against its OpenAPI document and failing the test on the given
+// mismatches.
+func newTestApp(t *testing.T, repo cartsummary.CartSummaryRepository, failOn ...error) *fiber.App {
+	app, doc := openapitest.NewApp(t, "Carts", failOn...)
+	doc.Group(app, "/carts").Get("/", cartsummary.Operations.ListCarts, cartsummary.NewRouteHandler(repo).ListCarts)
+	return app
+}
+
+func TestListCarts(t *testing.T) {
+	t.Run("defaults to the first page", func(t *testing.T) {
+		repo := &fakeRepository{}
+		res, err := newTestApp(t, repo).Test(httptest.NewRequest(http.MethodGet, "/carts", nil))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		var page cartsummary.Page
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&page))
+		assert.Len(t, page.Carts, 1)
+		assert.Equal(t, "store-a", repo.tenantID)
+		assert.Equal(t, cartsummary.Filter{Limit: cartsummary.DefaultLimit}, repo.filter)
+	})
+
+	t.Run("passes filters to the repository", func(t *testing.T) {
+		repo := &fakeRepository{}
+		res, err := newTestApp(t, repo).Test(httptest.NewRequest(http.MethodGet,
+			"/carts?status=checked_out&owner=alice&updated_since=2026-01-01T00:00:00Z&limit=5&offset=10", nil))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		assert.Equal(t, cartsummary.Filter{
+			Status:       cartsummary.StatusCheckedOut,
+			Owner:        "alice",
+			UpdatedSince: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			Limit:        5,
+			Offset:       10,
+		}, repo.filter)
+	})
+
+	t.Run("rejects invalid parameters", func(t *testing.T) {
+		for _, query := range []string{
+			"status=abandoned",
+			"updated_since=yesterday",
+			"limit=0",
+			"limit=101",
+			"offset=-1",
+		} {
+			res, err := newTestApp(t, &fakeRepository{}, openapi.ErrInvalidResponse).Test(httptest.NewRequest(http.MethodGet, "/carts?"+query, nil))
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, query)
+		}
+	})
+}
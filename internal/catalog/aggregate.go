@@ -0,0 +1,184 @@
+// Package catalog is the product catalog: the products a tenant sells, with
+// their names and prices. Products are event sourced like carts, and the
+// catalog projection lists them.
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"es/internal/es"
+	"es/internal/util"
+	"strconv"
+	"time"
+)
+
+// ProductAggregate is a product of the catalog. Its ID is the item ID carts
+// refer to it by. Prices are in cents.
+type ProductAggregate struct {
+	es.EventSourcedAggregate
+	now            util.Timestamp
+	TenantID       string    `json:"-"`
+	ID             int       `json:"product_id"`
+	Name           string    `json:"name"`
+	Price          int64     `json:"price"`
+	Discontinued   bool      `json:"discontinued"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	currentVersion int
+}
+
+type ProductOption func(*ProductAggregate)
+
+func UseTimestamp(tp util.Timestamp) ProductOption {
+	return func(p *ProductAggregate) {
+		p.now = tp
+	}
+}
+
+// ForTenant scopes the product and the events it raises to the given tenant.
+func ForTenant(tenantID string) ProductOption {
+	return func(p *ProductAggregate) {
+		p.TenantID = tenantID
+	}
+}
+
+// NewReplayableProduct creates an empty product for the event stream to
+// replay, see es.EventStream.RegisterAggregate.
+func NewReplayableProduct(tenantID string, aggregateID string) es.Aggregate {
+	productID, _ := strconv.Atoi(aggregateID)
+	return NewProductAggregate(productID, ForTenant(tenantID))
+}
+
+func NewProductAggregate(productID int, options ...ProductOption) *ProductAggregate {
+	p := &ProductAggregate{
+		now: time.Now,
+		ID:  productID,
+	}
+
+	for _, opt := range options {
+		opt(p)
+	}
+
+	return p
+}
+
+// AggregateID is the ID of the product in the event stream.
+func AggregateID(productID int) string {
+	return strconv.Itoa(productID)
+}
+
+// Version returns the version of the last event applied to the product.
+func (p *ProductAggregate) Version() int {
+	return p.currentVersion
+}
+
+// Create adds the product to the catalog.
+func (p *ProductAggregate) Create(name string, price int64) error {
+	if p.currentVersion > 0 {
+		return ErrProductExists
+	}
+	return p.Apply(p.newProductCreatedEvent(name, price))
+}
+
+// Rename changes the name of the product. Giving it the name it has does
+// nothing.
+func (p *ProductAggregate) Rename(name string) error {
+	if p.Discontinued {
+		return ErrProductDiscontinued
+	}
+	if name == p.Name {
+		return nil
+	}
+	return p.Apply(p.newProductRenamedEvent(name))
+}
+
+// ChangePrice sets the price of the product. Setting the price it has does
+// nothing.
+func (p *ProductAggregate) ChangePrice(price int64) error {
+	if p.Discontinued {
+		return ErrProductDiscontinued
+	}
+	if price == p.Price {
+		return nil
+	}
+	return p.Apply(p.newProductPriceChangedEvent(price))
+}
+
+// Discontinue takes the product off sale, so carts no longer accept it.
+// Discontinuing a discontinued product does nothing.
+func (p *ProductAggregate) Discontinue() error {
+	if p.Discontinued {
+		return nil
+	}
+	return p.Apply(p.newProductDiscontinuedEvent())
+}
+
+func (p *ProductAggregate) Apply(events ...es.Event) error {
+	if len(events) == 0 {
+		return errors.New("must apply at least 1 event")
+	}
+
+	for _, event := range events {
+		switch event.Type {
+		case ProductCreated:
+			data, err := es.DecodeData[productCreatedPayload](event)
+			if err != nil {
+				return err
+			}
+			p.TenantID = event.TenantID
+			p.Name = data.Name
+			p.Price = data.Price
+		case ProductRenamed:
+			data, err := es.DecodeData[productRenamedPayload](event)
+			if err != nil {
+				return err
+			}
+			p.Name = data.Name
+		case ProductPriceChanged:
+			data, err := es.DecodeData[productPriceChangedPayload](event)
+			if err != nil {
+				return err
+			}
+			p.Price = data.Price
+		case ProductDiscontinued:
+			p.Discontinued = true
+		default:
+			return errors.New("not implemented")
+		}
+
+		p.UpdatedAt = event.At
+	}
+
+	p.currentVersion = events[len(events)-1].VersionID
+	p.EventSourcedAggregate.Apply(events...)
+	return nil
+}
+
+// Snapshot captures the product state at its current version.
+func (p *ProductAggregate) Snapshot() (es.Snapshot, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return es.Snapshot{}, err
+	}
+
+	return es.Snapshot{
+		TenantID:      p.TenantID,
+		AggregateType: ProductType,
+		AggregateID:   AggregateID(p.ID),
+		VersionID:     p.currentVersion,
+		At:            p.now(),
+		Data:          data,
+	}, nil
+}
+
+// Restore resets the product to the state captured in the snapshot.
+func (p *ProductAggregate) Restore(snapshot es.Snapshot) error {
+	if snapshot.AggregateType != ProductType {
+		return errors.New("snapshot is not of a product")
+	}
+	if err := json.Unmarshal(snapshot.Data, p); err != nil {
+		return err
+	}
+	p.TenantID = snapshot.TenantID
+	p.currentVersion = snapshot.VersionID
+	return nil
+}
@@ -0,0 +1,122 @@
+package catalog_test
+
+import (
+	"es/internal/catalog"
+	"es/internal/es"
+	"es/internal/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestProduct(t *testing.T, productID int) *catalog.ProductAggregate {
+	t.Helper()
+
+	product := catalog.NewProductAggregate(productID,
+		catalog.ForTenant("store-a"),
+		catalog.UseTimestamp(util.SequencedTime(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))),
+	)
+	require.NoError(t, product.Create("Coffee beans", 1299))
+	return product
+}
+
+func TestProductAggregate(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		product := newTestProduct(t, 42)
+
+		assert.Equal(t, "Coffee beans", product.Name)
+		assert.Equal(t, int64(1299), product.Price)
+		assert.Equal(t, 1, product.Version())
+
+		events := product.UncommittedEvents()
+		require.Len(t, events, 1)
+		assert.Equal(t, catalog.ProductCreated, events[0].Type)
+		assert.Equal(t, catalog.ProductType, events[0].AggregateType)
+		assert.Equal(t, "42", events[0].AggregateID)
+		assert.Equal(t, "store-a", events[0].TenantID)
+	})
+
+	t.Run("cannot create twice", func(t *testing.T) {
+		product := newTestProduct(t, 42)
+		assert.ErrorIs(t, product.Create("Tea", 499), catalog.ErrProductExists)
+	})
+
+	t.Run("rename and change price", func(t *testing.T) {
+		product := newTestProduct(t, 42)
+		product.Commit()
+
+		assert.NoError(t, product.Rename("Espresso beans"))
+		assert.NoError(t, product.ChangePrice(1499))
+		assert.NoError(t, product.ChangePrice(1499))
+
+		assert.Equal(t, "Espresso beans", product.Name)
+		assert.Equal(t, int64(1499), product.Price)
+		assert.Len(t, product.UncommittedEvents(), 2, "Setting the same price should raise no event")
+
+		data, err := es.DecodeData[map[string]int64](product.UncommittedEvents()[1])
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int64{"price": 1499, "previous_price": 1299}, data)
+	})
+
+	t.Run("discontinued products cannot change", func(t *testing.T) {
+		product := newTestProduct(t, 42)
+
+		assert.NoError(t, product.Discontinue())
+		assert.NoError(t, product.Discontinue())
+		assert.True(t, product.Discontinued)
+		assert.Equal(t, 2, product.Version())
+
+		assert.ErrorIs(t, product.Rename("Tea"), catalog.ErrProductDiscontinued)
+		assert.ErrorIs(t, product.ChangePrice(1), catalog.ErrProductDiscontinued)
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		product := newTestProduct(t, 42)
+		assert.NoError(t, product.ChangePrice(999))
+		assert.NoError(t, product.Discontinue())
+
+		replayed := catalog.NewReplayableProduct("store-a", "42").(*catalog.ProductAggregate)
+		assert.NoError(t, replayed.Apply(product.UncommittedEvents()...))
+		assert.Equal(t, 42, replayed.ID)
+		assert.Equal(t, int64(999), replayed.Price)
+		assert.True(t, replayed.Discontinued)
+		assert.Equal(t, 3, replayed.Version())
+	})
+}
+
+func TestProductAggregateSnapshot(t *testing.T) {
+	t.Run("restore from snapshot", func(t *testing.T) {
+		product := newTestProduct(t, 42)
+		assert.NoError(t, product.ChangePrice(999))
+
+		snapshot, err := product.Snapshot()
+		assert.NoError(t, err)
+		assert.Equal(t, "42", snapshot.AggregateID)
+		assert.Equal(t, 2, snapshot.VersionID)
+
+		restored := catalog.NewProductAggregate(42)
+		assert.NoError(t, restored.Restore(snapshot))
+		assert.Equal(t, "store-a", restored.TenantID)
+		assert.Equal(t, "Coffee beans", restored.Name)
+		assert.Equal(t, int64(999), restored.Price)
+
+		// New events continue from the snapshot version
+		assert.NoError(t, restored.Discontinue())
+		assert.Equal(t, 3, restored.UncommittedEvents()[0].VersionID)
+	})
+
+	t.Run("reject snapshot of another aggregate type", func(t *testing.T) {
+		product := catalog.NewProductAggregate(42)
+		err := product.Restore(es.Snapshot{AggregateType: "cart", Data: []byte(`{}`)})
+		assert.EqualError(t, err, "snapshot is not of a product")
+	})
+}
+
+func TestCommands(t *testing.T) {
+	assert.NoError(t, catalog.CreateProduct{ProductID: 42, Name: "Coffee beans"}.Validate())
+	assert.ErrorIs(t, catalog.CreateProduct{Name: "Coffee beans"}.Validate(), catalog.ErrInvalidProduct)
+	assert.EqualError(t, catalog.RenameProduct{ProductID: 42}.Validate(), "invalid product: name must not be empty")
+	assert.EqualError(t, catalog.ChangePrice{ProductID: 42, Price: -1}.Validate(), "invalid price: must not be negative")
+}
@@ -0,0 +1,178 @@
+package catalog
+
+import (
+	"context"
+	"es/internal/es"
+	"fmt"
+	"unicode/utf8"
+)
+
+// MaxNameLength is the most characters a product name may have.
+const MaxNameLength = 200
+
+// CreateProduct adds a product to the catalog under an ID chosen by the
+// client, which is the item ID carts refer to it by.
+type CreateProduct struct {
+	TenantID  string
+	ProductID int
+	Name      string
+	Price     int64
+}
+
+func (CreateProduct) CommandName() string { return "catalog.CreateProduct" }
+func (c CreateProduct) Tenant() string    { return c.TenantID }
+
+func (c CreateProduct) Validate() error {
+	if err := validateProductID(c.ProductID); err != nil {
+		return err
+	}
+	if err := validateName(c.Name); err != nil {
+		return err
+	}
+	return validatePrice(c.Price)
+}
+
+// RenameProduct changes the name of a product.
+type RenameProduct struct {
+	TenantID  string
+	ProductID int
+	Name      string
+}
+
+func (RenameProduct) CommandName() string { return "catalog.RenameProduct" }
+func (c RenameProduct) Tenant() string    { return c.TenantID }
+
+func (c RenameProduct) Validate() error {
+	if err := validateProductID(c.ProductID); err != nil {
+		return err
+	}
+	return validateName(c.Name)
+}
+
+// ChangePrice sets the price of a product. Carts holding the product are
+// not changed.
+type ChangePrice struct {
+	TenantID  string
+	ProductID int
+	Price     int64
+}
+
+func (ChangePrice) CommandName() string { return "catalog.ChangePrice" }
+func (c ChangePrice) Tenant() string    { return c.TenantID }
+
+func (c ChangePrice) Validate() error {
+	if err := validateProductID(c.ProductID); err != nil {
+		return err
+	}
+	return validatePrice(c.Price)
+}
+
+// DiscontinueProduct takes a product off sale.
+type DiscontinueProduct struct {
+	TenantID  string
+	ProductID int
+}
+
+func (DiscontinueProduct) CommandName() string { return "catalog.DiscontinueProduct" }
+func (c DiscontinueProduct) Tenant() string    { return c.TenantID }
+
+func (c DiscontinueProduct) Validate() error {
+	return validateProductID(c.ProductID)
+}
+
+func validateProductID(productID int) error {
+	if productID <= 0 {
+		return fmt.Errorf("%w: product ID must be positive", ErrInvalidProduct)
+	}
+	return nil
+}
+
+func validateName(name string) error {
+	if name == "" {
+		return fmt.Errorf("%w: name must not be empty", ErrInvalidProduct)
+	}
+	if utf8.RuneCountInString(name) > MaxNameLength {
+		return fmt.Errorf("%w: name must be at most %d characters", ErrInvalidProduct, MaxNameLength)
+	}
+	return nil
+}
+
+func validatePrice(price int64) error {
+	if price < 0 {
+		return fmt.Errorf("%w: must not be negative", ErrInvalidPrice)
+	}
+	return nil
+}
+
+// RegisterCommandHandlers registers the product command handlers with the
+// bus.
+func RegisterCommandHandlers(bus *es.CommandBus, repository ProductRepository) {
+	es.RegisterHandler(bus, func(ctx context.Context, cmd CreateProduct) (*ProductAggregate, error) {
+		existing, err := repository.Get(ctx, cmd.TenantID, cmd.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return nil, fmt.Errorf("%w: product %d", ErrProductExists, cmd.ProductID)
+		}
+
+		product := NewProductAggregate(cmd.ProductID, ForTenant(cmd.TenantID))
+		if err := product.Create(cmd.Name, cmd.Price); err != nil {
+			return nil, err
+		}
+		// A product created since the lookup makes the save conflict, and the
+		// retried command finds it.
+		if err := repository.Save(ctx, product); err != nil {
+			return nil, err
+		}
+		return product, nil
+	})
+
+	es.RegisterHandler(bus, func(ctx context.Context, cmd RenameProduct) (*ProductAggregate, error) {
+		return changeProduct(ctx, repository, cmd.TenantID, cmd.ProductID, func(product *ProductAggregate) error {
+			return product.Rename(cmd.Name)
+		})
+	})
+
+	es.RegisterHandler(bus, func(ctx context.Context, cmd ChangePrice) (*ProductAggregate, error) {
+		return changeProduct(ctx, repository, cmd.TenantID, cmd.ProductID, func(product *ProductAggregate) error {
+			return product.ChangePrice(cmd.Price)
+		})
+	})
+
+	es.RegisterHandler(bus, func(ctx context.Context, cmd DiscontinueProduct) (*ProductAggregate, error) {
+		return changeProduct(ctx, repository, cmd.TenantID, cmd.ProductID, func(product *ProductAggregate) error {
+			return product.Discontinue()
+		})
+	})
+}
+
+// changeProduct loads a product, applies the change and saves the new
+// events.
+func changeProduct(
+	ctx context.Context,
+	repository ProductRepository,
+	tenantID string,
+	productID int,
+	change func(*ProductAggregate) error,
+) (*ProductAggregate, error) {
+	product, err := repository.Get(ctx, tenantID, productID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if product == nil {
+		return nil, ErrProductNotFound
+	}
+
+	if err := change(product); err != nil {
+		return nil, err
+	}
+
+	if err := repository.Save(ctx, product); err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
@@ -0,0 +1,17 @@
+package catalog
+
+import (
+	"es/internal/es"
+)
+
+const (
+	ProductType es.AggregateType = "product"
+
+	ProductCreated      es.EventType = "product.created"
+	ProductRenamed      es.EventType = "product.renamed"
+	ProductPriceChanged es.EventType = "product.price_changed"
+	ProductDiscontinued es.EventType = "product.discontinued"
+)
+
+// WriteScope is the token scope the routes changing the catalog require.
+const WriteScope = "catalog:write"
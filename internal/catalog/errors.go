@@ -0,0 +1,11 @@
+package catalog
+
+import "es/internal/es"
+
+var (
+	ErrProductNotFound     = es.NewError(es.KindNotFound, "product not found")
+	ErrProductExists       = es.NewError(es.KindConflict, "product already exists")
+	ErrProductDiscontinued = es.NewError(es.KindGone, "product is discontinued")
+	ErrInvalidProduct      = es.NewError(es.KindInvalid, "invalid product")
+	ErrInvalidPrice        = es.NewError(es.KindInvalid, "invalid price")
+)
@@ -0,0 +1,48 @@
+package catalog
+
+import (
+	"es/internal/es"
+)
+
+type productCreatedPayload struct {
+	Name  string `json:"name"`
+	Price int64  `json:"price"`
+}
+
+type productRenamedPayload struct {
+	Name         string `json:"name"`
+	PreviousName string `json:"previous_name"`
+}
+
+type productPriceChangedPayload struct {
+	Price         int64 `json:"price"`
+	PreviousPrice int64 `json:"previous_price"`
+}
+
+func (p *ProductAggregate) newEvent(eventType es.EventType, data any) es.Event {
+	return es.Event{
+		TenantID:      p.TenantID,
+		Type:          eventType,
+		AggregateType: ProductType,
+		AggregateID:   AggregateID(p.ID),
+		At:            p.now(),
+		VersionID:     p.currentVersion + 1,
+		Data:          data,
+	}
+}
+
+func (p *ProductAggregate) newProductCreatedEvent(name string, price int64) es.Event {
+	return p.newEvent(ProductCreated, productCreatedPayload{Name: name, Price: price})
+}
+
+func (p *ProductAggregate) newProductRenamedEvent(name string) es.Event {
+	return p.newEvent(ProductRenamed, productRenamedPayload{Name: name, PreviousName: p.Name})
+}
+
+func (p *ProductAggregate) newProductPriceChangedEvent(price int64) es.Event {
+	return p.newEvent(ProductPriceChanged, productPriceChangedPayload{Price: price, PreviousPrice: p.Price})
+}
+
+func (p *ProductAggregate) newProductDiscontinuedEvent() es.Event {
+	return p.newEvent(ProductDiscontinued, map[string]any{})
+}
@@ -0,0 +1,85 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Product is a product as listed by the catalog projection.
+type Product struct {
+	ID           int        `json:"product_id"`
+	Name         string     `json:"name"`
+	Price        int64      `json:"price"`
+	Discontinued bool       `json:"discontinued"`
+	UpdatedAt    *time.Time `json:"updated_at"`
+}
+
+// Filter selects a page of a tenant's products, ordered by name.
+// Discontinued products are left out unless IncludeDiscontinued is set.
+type Filter struct {
+	IncludeDiscontinued bool
+	Limit               int
+	Offset              int
+}
+
+// ProductPage is a page of products. NextOffset is set when there are more
+// products.
+type ProductPage struct {
+	Products   []Product `json:"products"`
+	NextOffset *int      `json:"next_offset,omitempty"`
+}
+
+// ProductListing lists products from the catalog read model.
+type ProductListing interface {
+	ListProducts(ctx context.Context, tenantID string, filter Filter) (ProductPage, error)
+}
+
+type PGProductListing struct {
+	pool *pgxpool.Pool
+}
+
+func NewPGProductListing(pool *pgxpool.Pool) *PGProductListing {
+	return &PGProductListing{
+		pool: pool,
+	}
+}
+
+// ListProducts returns the products of the tenant matching the filter.
+func (r *PGProductListing) ListProducts(ctx context.Context, tenantID string, filter Filter) (ProductPage, error) {
+	// One row more than the page tells whether there is a next page.
+	rows, err := r.pool.Query(ctx, `
+		SELECT product_id, name, price, discontinued, updated_at
+		FROM catalog.products
+		WHERE tenant_id = $1
+		AND ($2 OR NOT discontinued)
+		ORDER BY name, product_id
+		LIMIT $3 OFFSET $4`,
+		tenantID, filter.IncludeDiscontinued, filter.Limit+1, filter.Offset,
+	)
+	if err != nil {
+		return ProductPage{}, fmt.Errorf("query products: %w", err)
+	}
+	defer rows.Close()
+
+	page := ProductPage{Products: make([]Product, 0, filter.Limit)}
+	for rows.Next() {
+		var product Product
+		if err := rows.Scan(&product.ID, &product.Name, &product.Price, &product.Discontinued, &product.UpdatedAt); err != nil {
+			return ProductPage{}, fmt.Errorf("scan product: %w", err)
+		}
+		page.Products = append(page.Products, product)
+	}
+	if err := rows.Err(); err != nil {
+		return ProductPage{}, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	if len(page.Products) > filter.Limit {
+		page.Products = page.Products[:filter.Limit]
+		next := filter.Offset + filter.Limit
+		page.NextOffset = &next
+	}
+	return page, nil
+}
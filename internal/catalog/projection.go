@@ -0,0 +1,88 @@
+package catalog
+
+import (
+	"es/internal/es"
+	"es/internal/es/sqlprojection"
+	"fmt"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Projection keeps the current name, price and status of every product in
+// the catalog schema, for listing the catalog without replaying products.
+type Projection struct {
+	*sqlprojection.Projection
+}
+
+func NewProjection(pool *pgxpool.Pool) *Projection {
+	return &Projection{
+		Projection: sqlprojection.New(pool, "catalog").
+			Table(sqlprojection.Table{
+				Name: "products",
+				Columns: []sqlprojection.Column{
+					{Name: "tenant_id", Type: "VARCHAR(64) NOT NULL"},
+					{Name: "product_id", Type: "INTEGER NOT NULL"},
+					{Name: "name", Type: "TEXT NOT NULL DEFAULT ''"},
+					{Name: "price", Type: "BIGINT NOT NULL DEFAULT 0"},
+					{Name: "discontinued", Type: "BOOLEAN NOT NULL DEFAULT FALSE"},
+					{Name: "updated_at", Type: "TIMESTAMP"},
+				},
+				Key:     []string{"tenant_id", "product_id"},
+				Indexes: []string{"tenant_id, name"},
+			}).
+			On(ProductCreated, productCreated).
+			On(ProductRenamed, productChanged(func(event es.Event) (sqlprojection.Row, error) {
+				data, err := es.DecodeData[productRenamedPayload](event)
+				return sqlprojection.Row{"name": data.Name}, err
+			})).
+			On(ProductPriceChanged, productChanged(func(event es.Event) (sqlprojection.Row, error) {
+				data, err := es.DecodeData[productPriceChangedPayload](event)
+				return sqlprojection.Row{"price": data.Price}, err
+			})).
+			On(ProductDiscontinued, productChanged(func(es.Event) (sqlprojection.Row, error) {
+				return sqlprojection.Row{"discontinued": true}, nil
+			})),
+	}
+}
+
+func productKey(event es.Event) (sqlprojection.Row, error) {
+	productID, err := strconv.Atoi(event.AggregateID)
+	if err != nil {
+		return nil, fmt.Errorf("product ID %q: %w", event.AggregateID, err)
+	}
+	return sqlprojection.Row{"tenant_id": event.TenantID, "product_id": productID}, nil
+}
+
+func productCreated(event es.Event) ([]sqlprojection.Op, error) {
+	key, err := productKey(event)
+	if err != nil {
+		return nil, err
+	}
+	data, err := es.DecodeData[productCreatedPayload](event)
+	if err != nil {
+		return nil, err
+	}
+
+	key["name"] = data.Name
+	key["price"] = data.Price
+	key["updated_at"] = event.At.UTC()
+	return []sqlprojection.Op{sqlprojection.Upsert("products", key)}, nil
+}
+
+// productChanged updates the columns of the product the event changes.
+func productChanged(columns func(es.Event) (sqlprojection.Row, error)) sqlprojection.Handler {
+	return func(event es.Event) ([]sqlprojection.Op, error) {
+		key, err := productKey(event)
+		if err != nil {
+			return nil, err
+		}
+		set, err := columns(event)
+		if err != nil {
+			return nil, err
+		}
+
+		set["updated_at"] = event.At.UTC()
+		return []sqlprojection.Op{sqlprojection.Update("products", key, set)}, nil
+	}
+}
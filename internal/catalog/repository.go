@@ -0,0 +1,98 @@
+package catalog
+
+import (
+	"context"
+	"es/internal/es"
+	"fmt"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ProductRepository interface {
+	Get(ctx context.Context, tenantID string, productID int) (*ProductAggregate, error)
+	Save(context.Context, *ProductAggregate) error
+}
+
+type PGProductRepository struct {
+	stream *es.EventStream
+}
+
+func NewPGProductRepository(pool *pgxpool.Pool) *PGProductRepository {
+	return &PGProductRepository{
+		stream: es.NewEventStream(pool),
+	}
+}
+
+// Get rehydrates the product from its latest snapshot and the events after
+// it, returning nil if it has neither.
+func (r *PGProductRepository) Get(ctx context.Context, tenantID string, productID int) (*ProductAggregate, error) {
+	product := NewProductAggregate(productID, ForTenant(tenantID))
+
+	// Start from the latest snapshot if there is one, since the events it
+	// covers may have been archived.
+	snapshot, err := r.stream.GetSnapshot(ctx, tenantID, ProductType, AggregateID(productID))
+	if err != nil {
+		return nil, err
+	}
+
+	fromVersion := 0
+	if snapshot != nil {
+		if err := product.Restore(*snapshot); err != nil {
+			return nil, err
+		}
+		fromVersion = snapshot.VersionID
+	}
+
+	events, err := r.stream.GetAggregateEventsAfter(ctx, tenantID, ProductType, AggregateID(productID), fromVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(events) == 0 && snapshot == nil {
+		return nil, nil
+	}
+
+	if len(events) > 0 {
+		if err := product.Apply(events...); err != nil {
+			return nil, err
+		}
+	}
+	product.Commit()
+	return product, nil
+}
+
+// Save appends the new events of the product. Creating a product whose ID
+// is taken fails with es.ErrConcurrencyConflict.
+func (r *PGProductRepository) Save(ctx context.Context, product *ProductAggregate) error {
+	if err := r.stream.Append(ctx, product.UncommittedEvents()...); err != nil {
+		return err
+	}
+
+	product.Commit()
+	return nil
+}
+
+// Snapshot stores a snapshot of the product at its latest version. Its
+// signature is the es.SnapshotFunc the archiver takes.
+func (r *PGProductRepository) Snapshot(ctx context.Context, tenantID string, aggregateID string) error {
+	productID, err := strconv.Atoi(aggregateID)
+	if err != nil {
+		return fmt.Errorf("invalid product ID %q: %w", aggregateID, err)
+	}
+
+	product, err := r.Get(ctx, tenantID, productID)
+	if err != nil {
+		return err
+	}
+	if product == nil {
+		return fmt.Errorf("product %d not found", productID)
+	}
+
+	snapshot, err := product.Snapshot()
+	if err != nil {
+		return err
+	}
+
+	return r.stream.SaveSnapshot(ctx, snapshot)
+}
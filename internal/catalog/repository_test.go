@@ -0,0 +1,52 @@
+//go:build integration
+
+package catalog_test
+
+import (
+	"context"
+	"es/internal/catalog"
+	"es/internal/es"
+	"es/internal/migrate"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductRepository(t *testing.T) {
+	ctx := context.Background()
+	pool := setupTestPool(t)
+	_, err := migrate.NewMigrator(pool, es.MigrationNamespace, es.Migrations()).Up(ctx)
+	require.NoError(t, err)
+	repository := catalog.NewPGProductRepository(pool)
+
+	product := catalog.NewProductAggregate(42, catalog.ForTenant("store-a"))
+	require.NoError(t, product.Create("Coffee beans", 1299))
+	require.NoError(t, product.ChangePrice(999))
+	require.NoError(t, repository.Save(ctx, product))
+
+	require.NoError(t, repository.Snapshot(ctx, "store-a", "42"))
+	require.NoError(t, product.Rename("Espresso beans"))
+	require.NoError(t, repository.Save(ctx, product))
+
+	// Archive the events the snapshot stands in for.
+	_, err = pool.Exec(ctx, `DELETE FROM events WHERE aggregate_id = '42' AND version_id <= 2`)
+	require.NoError(t, err)
+
+	t.Run("starts from the snapshot", func(t *testing.T) {
+		product, err := repository.Get(ctx, "store-a", 42)
+		require.NoError(t, err)
+		require.NotNil(t, product)
+		assert.Equal(t, "Espresso beans", product.Name)
+		assert.Equal(t, int64(999), product.Price)
+		assert.Equal(t, 3, product.Version())
+	})
+
+	t.Run("unknown products", func(t *testing.T) {
+		product, err := repository.Get(ctx, "store-a", 7)
+		require.NoError(t, err)
+		assert.Nil(t, product)
+
+		assert.Error(t, repository.Snapshot(ctx, "store-a", "7"))
+	})
+}
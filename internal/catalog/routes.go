@@ -0,0 +1,304 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"es/internal/es"
+	"es/internal/openapi"
+	"es/internal/tenant"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// RouteHandler serves the /catalog/products API. Reads of a single product
+// and changes go to the product's events, while listings come from the
+// catalog projection.
+type RouteHandler struct {
+	usecase *CatalogUseCase
+	listing ProductListing
+}
+
+func NewRouteHandler(usecase *CatalogUseCase, listing ProductListing) *RouteHandler {
+	return &RouteHandler{
+		usecase: usecase,
+		listing: listing,
+	}
+}
+
+var productIDParam = openapi.Parameter{Name: "productID", Type: 0}
+
+// Operations documents the handlers of RouteHandler.
+var Operations = struct {
+	ListProducts, CreateProduct, GetProduct, RenameProduct, ChangePrice, DiscontinueProduct openapi.Operation
+}{
+	ListProducts: openapi.Operation{
+		Summary: "List products",
+		Tags:    []string{"catalog"},
+		Query: []openapi.Parameter{
+			{Name: "include_discontinued", Type: false},
+			{Name: "limit", Description: "At most 100, 20 by default", Type: 0},
+			{Name: "offset", Type: 0},
+			es.MinPositionParam,
+		},
+		Responses: map[int]any{http.StatusOK: ProductPage{}},
+	},
+	CreateProduct: openapi.Operation{
+		Summary:     "Add a product to the catalog",
+		Description: "The product ID is the item ID carts refer to the product by. The Location header names the new product.",
+		Tags:        []string{"catalog"},
+		Request:     CreateProductRequest{},
+		Responses:   map[int]any{http.StatusCreated: ProductAggregate{}},
+	},
+	GetProduct: openapi.Operation{
+		Summary:    "Get a product",
+		Tags:       []string{"catalog"},
+		PathParams: []openapi.Parameter{productIDParam},
+		Responses:  map[int]any{http.StatusOK: ProductAggregate{}},
+	},
+	RenameProduct: openapi.Operation{
+		Summary:    "Rename a product",
+		Tags:       []string{"catalog"},
+		PathParams: []openapi.Parameter{productIDParam},
+		Request:    RenameRequest{},
+		Responses:  map[int]any{http.StatusOK: ProductAggregate{}},
+	},
+	ChangePrice: openapi.Operation{
+		Summary:     "Change the price of a product",
+		Description: "Prices are in cents.",
+		Tags:        []string{"catalog"},
+		PathParams:  []openapi.Parameter{productIDParam},
+		Request:     PriceRequest{},
+		Responses:   map[int]any{http.StatusOK: ProductAggregate{}},
+	},
+	DiscontinueProduct: openapi.Operation{
+		Summary:     "Discontinue a product",
+		Description: "Carts no longer accept the product, but keep the units they hold.",
+		Tags:        []string{"catalog"},
+		PathParams:  []openapi.Parameter{productIDParam},
+		Responses:   map[int]any{http.StatusOK: ProductAggregate{}},
+	},
+}
+
+// CreateProductRequest is the body of POST /catalog/products. Price is in
+// cents.
+type CreateProductRequest struct {
+	ProductID int    `json:"product_id" openapi:"required,minimum=1"`
+	Name      string `json:"name" openapi:"required"`
+	Price     *int64 `json:"price" openapi:"required,minimum=0"`
+}
+
+// RenameRequest is the body of PUT /catalog/products/:productID/name.
+type RenameRequest struct {
+	Name string `json:"name" openapi:"required"`
+}
+
+// PriceRequest is the body of PUT /catalog/products/:productID/price.
+type PriceRequest struct {
+	Price *int64 `json:"price" openapi:"required,minimum=0"`
+}
+
+// ListProducts returns a page of the tenant's products, paged with limit
+// and offset.
+func (h *RouteHandler) ListProducts(c *fiber.Ctx) error {
+	tenantID, err := tenant.FromRequest(c)
+
+	if err != nil {
+		return fiber.NewError(http.StatusForbidden, err.Error())
+	}
+
+	filter := Filter{
+		IncludeDiscontinued: c.QueryBool("include_discontinued", false),
+		Limit:               c.QueryInt("limit", DefaultLimit),
+		Offset:              c.QueryInt("offset", 0),
+	}
+
+	if filter.Limit <= 0 || filter.Limit > MaxLimit {
+		return fiber.NewError(http.StatusBadRequest, "limit must be between 1 and 100")
+	}
+	if filter.Offset < 0 {
+		return fiber.NewError(http.StatusBadRequest, "offset must not be negative")
+	}
+
+	page, err := h.listing.ListProducts(c.Context(), tenantID, filter)
+
+	if err != nil {
+		return err
+	}
+
+	return c.Status(http.StatusOK).JSON(page)
+}
+
+// CreateProduct adds the product of the JSON body to the catalog and
+// returns it with a Location header naming it.
+func (h *RouteHandler) CreateProduct(c *fiber.Ctx) error {
+	tenantID, err := tenant.FromRequest(c)
+
+	if err != nil {
+		return fiber.NewError(http.StatusForbidden, err.Error())
+	}
+
+	var req CreateProductRequest
+	if err := decodeBody(c, &req); err != nil {
+		return err
+	}
+	if req.Price == nil {
+		return fmt.Errorf("%w: price is required", ErrInvalidPrice)
+	}
+
+	product, err := h.usecase.CreateProduct(c.Context(), tenantID, req.ProductID, req.Name, *req.Price)
+
+	if err != nil {
+		return err
+	}
+
+	setProductHeaders(c, product)
+	c.Location(strings.TrimSuffix(c.Path(), "/") + "/" + strconv.Itoa(product.ID))
+	return c.Status(http.StatusCreated).JSON(product)
+}
+
+func (h *RouteHandler) GetProduct(c *fiber.Ctx) error {
+	tenantID, err := tenant.FromRequest(c)
+
+	if err != nil {
+		return fiber.NewError(http.StatusForbidden, err.Error())
+	}
+
+	productID, err := productIDFromPath(c)
+
+	if err != nil {
+		return err
+	}
+
+	product, err := h.usecase.GetProduct(c.Context(), tenantID, productID)
+
+	if err != nil {
+		return err
+	}
+
+	setProductHeaders(c, product)
+	return c.Status(http.StatusOK).JSON(product)
+}
+
+// RenameProduct gives a product the name in the JSON body.
+func (h *RouteHandler) RenameProduct(c *fiber.Ctx) error {
+	tenantID, err := tenant.FromRequest(c)
+
+	if err != nil {
+		return fiber.NewError(http.StatusForbidden, err.Error())
+	}
+
+	productID, err := productIDFromPath(c)
+
+	if err != nil {
+		return err
+	}
+
+	var req RenameRequest
+	if err := decodeBody(c, &req); err != nil {
+		return err
+	}
+
+	product, err := h.usecase.RenameProduct(c.Context(), tenantID, productID, req.Name)
+
+	if err != nil {
+		return err
+	}
+
+	setProductHeaders(c, product)
+	return c.Status(http.StatusOK).JSON(product)
+}
+
+// ChangePrice sets the price of a product to the one in the JSON body.
+func (h *RouteHandler) ChangePrice(c *fiber.Ctx) error {
+	tenantID, err := tenant.FromRequest(c)
+
+	if err != nil {
+		return fiber.NewError(http.StatusForbidden, err.Error())
+	}
+
+	productID, err := productIDFromPath(c)
+
+	if err != nil {
+		return err
+	}
+
+	var req PriceRequest
+	if err := decodeBody(c, &req); err != nil {
+		return err
+	}
+	if req.Price == nil {
+		return fmt.Errorf("%w: price is required", ErrInvalidPrice)
+	}
+
+	product, err := h.usecase.ChangePrice(c.Context(), tenantID, productID, *req.Price)
+
+	if err != nil {
+		return err
+	}
+
+	setProductHeaders(c, product)
+	return c.Status(http.StatusOK).JSON(product)
+}
+
+func (h *RouteHandler) DiscontinueProduct(c *fiber.Ctx) error {
+	tenantID, err := tenant.FromRequest(c)
+
+	if err != nil {
+		return fiber.NewError(http.StatusForbidden, err.Error())
+	}
+
+	productID, err := productIDFromPath(c)
+
+	if err != nil {
+		return err
+	}
+
+	product, err := h.usecase.DiscontinueProduct(c.Context(), tenantID, productID)
+
+	if err != nil {
+		return err
+	}
+
+	setProductHeaders(c, product)
+	return c.Status(http.StatusOK).JSON(product)
+}
+
+// setProductHeaders sets the position of the product's latest event, which
+// reads of the catalog projection can wait for.
+func setProductHeaders(c *fiber.Ctx, product *ProductAggregate) {
+	if position := product.Position(); position > 0 {
+		c.Set(es.HeaderEventPosition, strconv.FormatInt(position, 10))
+	}
+}
+
+func productIDFromPath(c *fiber.Ctx) (int, error) {
+	productID, err := c.ParamsInt("productID")
+	if err != nil || productID <= 0 {
+		return 0, fiber.NewError(http.StatusBadRequest, "product ID must be a positive integer")
+	}
+	return productID, nil
+}
+
+// decodeBody decodes the JSON body of the request into v, rejecting other
+// media types and unknown fields.
+func decodeBody(c *fiber.Ctx, v any) error {
+	if !strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEApplicationJSON) {
+		return fiber.NewError(http.StatusUnsupportedMediaType, "request body must be application/json")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(c.Body()))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fiber.NewError(http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package catalog_test
+
+import (
+	"context"
+	"encoding/json"
+	"es/internal/catalog"
+	"es/internal/es"
+	"es/internal/openapi"
+	"es/internal/openapi/openapitest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryProductRepository struct {
+	products map[int]*catalog.ProductAggregate
+	position int64
+}
+
+func (r *memoryProductRepository) Get(_ context.Context, _ string, productID int) (*catalog.ProductAggregate, error) {
+	return r.products[productID], nil
+}
+
+// Save assigns the events their positions, as EventStream.Append does.
+func (r *memoryProductRepository) Save(_ context.Context, product *catalog.ProductAggregate) error {
+	events := product.UncommittedEvents()
+	for i := range events {
+		r.position++
+		events[i].Position = r.position
+	}
+	product.Commit()
+	r.products[product.ID] = product
+	return nil
+}
+
+type fakeListing struct {
+	filter catalog.Filter
+}
+
+func (l *fakeListing) ListProducts(_ context.Context, _ string, filter catalog.Filter) (catalog.ProductPage, error) {
+	l.filter = filter
+	return catalog.ProductPage{Products: []catalog.Product{{ID: 42, Name: "Coffee beans", Price: 1299}}}, nil
+}
+
+// newTestApp serves the catalog routes, checking requests and responses
+// against their OpenAPI document and failing the test on the given
+// mismatches.
+func newTestApp(t *testing.T, listing catalog.ProductListing, failOn ...error) *fiber.App {
+	repository := &memoryProductRepository{products: map[int]*catalog.ProductAggregate{}}
+	bus := es.NewCommandBus(es.Validation())
+	catalog.RegisterCommandHandlers(bus, repository)
+	h := catalog.NewRouteHandler(catalog.NewCatalogUseCase(repository, bus), listing)
+
+	app, doc := openapitest.NewApp(t, "Catalog", failOn...)
+	products := doc.Group(app, "/catalog/products")
+	products.Get("/", catalog.Operations.ListProducts, h.ListProducts)
+	products.Post("/", catalog.Operations.CreateProduct, h.CreateProduct)
+	products.Get("/:productID", catalog.Operations.GetProduct, h.GetProduct)
+	products.Put("/:productID/name", catalog.Operations.RenameProduct, h.RenameProduct)
+	products.Put("/:productID/price", catalog.Operations.ChangePrice, h.ChangePrice)
+	products.Post("/:productID/discontinue", catalog.Operations.DiscontinueProduct, h.DiscontinueProduct)
+	return app
+}
+
+// send makes a request with the JSON body, unless it is empty, and decodes
+// the product of successful responses.
+func send(t *testing.T, app *fiber.App, method string, path string, body string) (*http.Response, catalog.ProductAggregate) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+
+	var product catalog.ProductAggregate
+	if resp.StatusCode < 300 {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&product))
+	}
+	return resp, product
+}
+
+func TestRoutes(t *testing.T) {
+	t.Run("manage a product", func(t *testing.T) {
+		app := newTestApp(t, &fakeListing{})
+
+		resp, product := send(t, app, http.MethodPost, "/catalog/products", `{"product_id": 42, "name": "Coffee beans", "price": 1299}`)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "/catalog/products/42", resp.Header.Get("Location"))
+		assert.Equal(t, "1", resp.Header.Get("X-Event-Position"))
+		assert.Equal(t, "Coffee beans", product.Name)
+
+		resp, product = send(t, app, http.MethodPut, "/catalog/products/42/name", `{"name": "Espresso beans"}`)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "Espresso beans", product.Name)
+
+		resp, product = send(t, app, http.MethodPut, "/catalog/products/42/price", `{"price": 1499}`)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, int64(1499), product.Price)
+
+		resp, product = send(t, app, http.MethodPost, "/catalog/products/42/discontinue", "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.True(t, product.Discontinued)
+
+		resp, product = send(t, app, http.MethodGet, "/catalog/products/42", "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "4", resp.Header.Get("X-Event-Position"))
+		assert.Equal(t, catalog.ProductAggregate{ID: 42, Name: "Espresso beans", Price: 1499, Discontinued: true, UpdatedAt: product.UpdatedAt}, product)
+
+		resp, _ = send(t, app, http.MethodPut, "/catalog/products/42/price", `{"price": 999}`)
+		assert.Equal(t, http.StatusGone, resp.StatusCode)
+	})
+
+	t.Run("reject invalid products", func(t *testing.T) {
+		app := newTestApp(t, &fakeListing{}, openapi.ErrInvalidResponse)
+		_, _ = send(t, app, http.MethodPost, "/catalog/products", `{"product_id": 42, "name": "Coffee beans", "price": 1299}`)
+
+		for body, status := range map[string]int{
+			`{"product_id": 42, "name": "Tea", "price": 499}`: http.StatusConflict,
+			`{"product_id": 7, "name": "", "price": 499}`:     http.StatusUnprocessableEntity,
+			`{"product_id": 7, "name": "Tea"}`:                http.StatusUnprocessableEntity,
+			`{"product_id": 7, "name": "Tea", "price": -1}`:   http.StatusUnprocessableEntity,
+			`{"product_id": 7, "name": "Tea", "colour": "g"}`: http.StatusBadRequest,
+		} {
+			resp, _ := send(t, app, http.MethodPost, "/catalog/products", body)
+			assert.Equal(t, status, resp.StatusCode, body)
+		}
+
+		resp, _ := send(t, app, http.MethodGet, "/catalog/products/7", "")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("list products", func(t *testing.T) {
+		listing := &fakeListing{}
+		resp, err := newTestApp(t, listing).Test(httptest.NewRequest(http.MethodGet, "/catalog/products?include_discontinued=true&limit=5", nil))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, catalog.Filter{IncludeDiscontinued: true, Limit: 5}, listing.filter)
+
+		var page catalog.ProductPage
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+		assert.Len(t, page.Products, 1)
+	})
+}
@@ -0,0 +1,45 @@
+package catalog
+
+import "es/internal/es"
+
+const productCreatedSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"properties": {
+		"name": {"type": "string", "minLength": 1},
+		"price": {"type": "integer", "minimum": 0}
+	},
+	"required": ["name", "price"]
+}`
+
+const productRenamedSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"properties": {
+		"name": {"type": "string", "minLength": 1},
+		"previous_name": {"type": "string"}
+	},
+	"required": ["name"]
+}`
+
+const productPriceChangedSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"properties": {
+		"price": {"type": "integer", "minimum": 0},
+		"previous_price": {"type": "integer", "minimum": 0}
+	},
+	"required": ["price"]
+}`
+
+const emptySchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object"
+}`
+
+func init() {
+	es.MustRegisterSchema(ProductCreated, productCreatedSchema)
+	es.MustRegisterSchema(ProductRenamed, productRenamedSchema)
+	es.MustRegisterSchema(ProductPriceChanged, productPriceChangedSchema)
+	es.MustRegisterSchema(ProductDiscontinued, emptySchema)
+}
@@ -0,0 +1,52 @@
+package catalog
+
+import (
+	"context"
+	"es/internal/es"
+)
+
+// CatalogUseCase reads products from the repository and changes them by
+// dispatching commands on the bus.
+type CatalogUseCase struct {
+	repository ProductRepository
+	bus        *es.CommandBus
+}
+
+func NewCatalogUseCase(repository ProductRepository, bus *es.CommandBus) *CatalogUseCase {
+	return &CatalogUseCase{
+		repository: repository,
+		bus:        bus,
+	}
+}
+
+// GetProduct returns the product as of its latest event, so it reflects
+// changes the catalog projection may not have caught up with yet.
+func (u *CatalogUseCase) GetProduct(ctx context.Context, tenantID string, productID int) (*ProductAggregate, error) {
+	product, err := u.repository.Get(ctx, tenantID, productID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if product == nil {
+		return nil, ErrProductNotFound
+	}
+
+	return product, nil
+}
+
+func (u *CatalogUseCase) CreateProduct(ctx context.Context, tenantID string, productID int, name string, price int64) (*ProductAggregate, error) {
+	return es.Dispatch[*ProductAggregate](ctx, u.bus, CreateProduct{TenantID: tenantID, ProductID: productID, Name: name, Price: price})
+}
+
+func (u *CatalogUseCase) RenameProduct(ctx context.Context, tenantID string, productID int, name string) (*ProductAggregate, error) {
+	return es.Dispatch[*ProductAggregate](ctx, u.bus, RenameProduct{TenantID: tenantID, ProductID: productID, Name: name})
+}
+
+func (u *CatalogUseCase) ChangePrice(ctx context.Context, tenantID string, productID int, price int64) (*ProductAggregate, error) {
+	return es.Dispatch[*ProductAggregate](ctx, u.bus, ChangePrice{TenantID: tenantID, ProductID: productID, Price: price})
+}
+
+func (u *CatalogUseCase) DiscontinueProduct(ctx context.Context, tenantID string, productID int) (*ProductAggregate, error) {
+	return es.Dispatch[*ProductAggregate](ctx, u.bus, DiscontinueProduct{TenantID: tenantID, ProductID: productID})
+}
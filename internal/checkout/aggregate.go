@@ -5,17 +5,24 @@ import (
 	"errors"
 	"es/internal/es"
 	"es/internal/util"
-	"slices"
+	"fmt"
 	"time"
 )
 
 type CartAggregate struct {
 	es.EventSourcedAggregate
 	now            util.Timestamp
-	ID             int   `json:"cart_id"`
-	Contents       []int `json:"contents"`
-	CheckedOut     bool  `json:"checked_out"`
+	TenantID       string    `json:"-"`
+	ID             string    `json:"cart_id"`
+	Owner          string    `json:"owner,omitempty"`
+	Region         string    `json:"region,omitempty"`
+	Contents       Lines     `json:"contents"`
+	Totals         *Totals   `json:"totals,omitempty"`
+	CheckedOut     bool      `json:"checked_out"`
+	Expired        bool      `json:"expired"`
+	UpdatedAt      time.Time `json:"updated_at"`
 	currentVersion int
+	products       ProductLookup
 }
 
 type CartOption func(*CartAggregate)
@@ -26,11 +33,38 @@ func UseTimestamp(tp util.Timestamp) CartOption {
 	}
 }
 
-func NewCartAggregate(cartID int, options ...CartOption) *CartAggregate {
+// ForTenant scopes the cart and the events it raises to the given tenant.
+func ForTenant(tenantID string) CartOption {
+	return func(c *CartAggregate) {
+		c.TenantID = tenantID
+	}
+}
+
+// OwnedBy records the user the cart is created for.
+func OwnedBy(owner string) CartOption {
+	return func(c *CartAggregate) {
+		c.Owner = owner
+	}
+}
+
+// InRegion records the tax region the cart is priced in.
+func InRegion(region string) CartOption {
+	return func(c *CartAggregate) {
+		c.Region = region
+	}
+}
+
+// NewReplayableCart creates an empty cart for the event stream to replay,
+// see es.EventStream.RegisterAggregate.
+func NewReplayableCart(tenantID string, cartID string) es.Aggregate {
+	return NewCartAggregate(cartID, ForTenant(tenantID))
+}
+
+func NewCartAggregate(cartID string, options ...CartOption) *CartAggregate {
 	c := &CartAggregate{
 		now:        time.Now,
 		ID:         cartID,
-		Contents:   []int{},
+		Contents:   Lines{},
 		CheckedOut: false,
 	}
 
@@ -41,32 +75,102 @@ func NewCartAggregate(cartID int, options ...CartOption) *CartAggregate {
 	return c
 }
 
+// Version returns the version of the last event applied to the cart.
+func (c *CartAggregate) Version() int {
+	return c.currentVersion
+}
+
 func (c *CartAggregate) Init() error {
 	return c.Apply(c.newCartCreatedEvent(c.ID))
 }
 
 func (c *CartAggregate) Add(itemID int) error {
+	if c.Expired {
+		return ErrCartExpired
+	}
 	if c.CheckedOut {
-		return errors.New("cannot add items to a checked out cart")
+		return fmt.Errorf("cannot add items: %w", ErrCartCheckedOut)
+	}
+	if err := checkLineQuantity(c.Contents.Quantity(itemID) + 1); err != nil {
+		return err
+	}
+	if err := c.checkProduct(itemID); err != nil {
+		return err
 	}
 	return c.Apply(c.newItemAddedToCartEvent(itemID))
 }
 
+// checkLineQuantity rejects raising a line of a cart above MaxQuantity
+// units, however many commands it takes.
+func checkLineQuantity(quantity int) error {
+	if quantity > MaxQuantity {
+		return fmt.Errorf("%w: a cart holds at most %d units of an item", ErrInvalidQuantity, MaxQuantity)
+	}
+	return nil
+}
+
 func (c *CartAggregate) Remove(itemID int) error {
+	if c.Expired {
+		return ErrCartExpired
+	}
 	if c.CheckedOut {
-		return errors.New("cannot remove items from a checked out cart")
+		return fmt.Errorf("cannot remove items: %w", ErrCartCheckedOut)
 	}
-	if slices.Contains(c.Contents, itemID) {
+	if c.Contents.Quantity(itemID) > 0 {
 		return c.Apply(c.newItemRemovedFromCartEvent(itemID))
 	}
 	return nil
 }
 
-func (c *CartAggregate) Checkout() error {
+// SetQuantity sets the units of an item in the cart in a single event,
+// removing the item at zero. Setting the quantity it has does nothing.
+// Only more units are checked against the catalog, so discontinued products
+// can still be taken out.
+func (c *CartAggregate) SetQuantity(itemID int, quantity int) error {
+	if c.Expired {
+		return ErrCartExpired
+	}
 	if c.CheckedOut {
-		return errors.New("cart is already checked out")
+		return fmt.Errorf("cannot change items: %w", ErrCartCheckedOut)
+	}
+	previous := c.Contents.Quantity(itemID)
+	if quantity == previous {
+		return nil
+	}
+	if quantity > previous {
+		if err := checkLineQuantity(quantity); err != nil {
+			return err
+		}
+		if err := c.checkProduct(itemID); err != nil {
+			return err
+		}
 	}
-	return c.Apply(c.newCartCheckedOutEvent())
+	return c.Apply(c.newItemQuantityChangedEvent(itemID, quantity, previous))
+}
+
+// Checkout closes the cart, freezing the totals it was priced at into the
+// event so later price changes leave them alone. Totals may be nil when
+// carts are not priced.
+func (c *CartAggregate) Checkout(totals *Totals) error {
+	if c.Expired {
+		return ErrCartExpired
+	}
+	if c.CheckedOut {
+		return ErrCartCheckedOut
+	}
+	return c.Apply(c.newCartCheckedOutEvent(totals))
+}
+
+// Expire closes a cart that was abandoned before checkout, so it accepts
+// no further changes. Expiring an expired cart does nothing.
+func (c *CartAggregate) Expire() error {
+	if c.CheckedOut {
+		return fmt.Errorf("cannot expire: %w", ErrCartCheckedOut)
+	}
+	if c.Expired {
+		return nil
+	}
+	return c.Apply(c.newCartExpiredEvent())
 }
 
 func (c *CartAggregate) Apply(events ...es.Event) error {
@@ -77,30 +181,46 @@ func (c *CartAggregate) Apply(events ...es.Event) error {
 	for _, event := range events {
 		switch event.Type {
 		case CartCreated:
+			data, err := es.DecodeData[cartCreatedPayload](event)
+			if err != nil {
+				return err
+			}
 			c.ID = event.AggregateID
+			c.TenantID = event.TenantID
+			c.Owner = data.Owner
+			c.Region = data.Region
 		case ItemAddedToCart:
 			itemID, err := toItemID(event.Data)
 			if err != nil {
 				return err
 			}
-			c.Contents = append(c.Contents, itemID)
+			c.Contents = c.Contents.set(itemID, c.Contents.Quantity(itemID)+1)
 		case ItemRemovedFromCart:
 			itemID, err := toItemID(event.Data)
 			if err != nil {
 				return err
 			}
-			for i, id := range c.Contents {
-				if id == itemID {
-					c.Contents = append(c.Contents[:i], c.Contents[i+1:]...)
-					break
-				}
+			c.Contents = c.Contents.set(itemID, max(c.Contents.Quantity(itemID)-1, 0))
+		case ItemQuantityChanged:
+			data, err := es.DecodeData[itemQuantityChangedPayload](event)
+			if err != nil {
+				return err
 			}
+			c.Contents = c.Contents.set(data.ItemID, data.Quantity)
 		case CartCheckedOut:
+			data, err := es.DecodeData[cartCheckedOutPayload](event)
+			if err != nil {
+				return err
+			}
 			c.CheckedOut = true
+			c.Totals = data.Totals
+		case CartExpired:
+			c.Expired = true
 		default:
 			return errors.New("not implemented")
 		}
 
+		c.UpdatedAt = event.At
 	}
 
 	c.currentVersion = events[len(events)-1].VersionID
@@ -108,6 +228,36 @@ func (c *CartAggregate) Apply(events ...es.Event) error {
 	return nil
 }
 
+// Snapshot captures the cart state at its current version.
+func (c *CartAggregate) Snapshot() (es.Snapshot, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return es.Snapshot{}, err
+	}
+
+	return es.Snapshot{
+		TenantID:      c.TenantID,
+		AggregateType: CartType,
+		AggregateID:   c.ID,
+		VersionID:     c.currentVersion,
+		At:            c.now(),
+		Data:          data,
+	}, nil
+}
+
+// Restore resets the cart to the state captured in the snapshot.
+func (c *CartAggregate) Restore(snapshot es.Snapshot) error {
+	if snapshot.AggregateType != CartType {
+		return errors.New("snapshot is not of a cart")
+	}
+	if err := json.Unmarshal(snapshot.Data, c); err != nil {
+		return err
+	}
+	c.TenantID = snapshot.TenantID
+	c.currentVersion = snapshot.VersionID
+	return nil
+}
+
 func toItemID(data any) (int, error) {
 	dataBytes, err := json.Marshal(data)
 	if err != nil {
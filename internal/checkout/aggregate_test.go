@@ -9,102 +9,183 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"es/internal/es"
+	"es/internal/es/estest"
+	"es/internal/es/sqlprojection"
 )
 
 func TestCartAggregateCommands(t *testing.T) {
 	t.Run("add single item", func(t *testing.T) {
-		cart := newTestCartAggregate(t, 1001)
+		cart := newTestCartAggregate(t, "cart-1001")
 		assert.NoError(t, cart.Add(42))
-		assert.Equal(t, []int{42}, cart.Contents)
+		assert.Equal(t, checkout.Lines{{ItemID: 42, Quantity: 1}}, cart.Contents)
 	})
 
 	t.Run("add single item multiple times", func(t *testing.T) {
-		cart := newTestCartAggregate(t, 1001)
+		cart := newTestCartAggregate(t, "cart-1001")
 		assert.NoError(t, cart.Add(42))
 		assert.NoError(t, cart.Add(42))
-		assert.Equal(t, []int{42, 42}, cart.Contents)
+		assert.Equal(t, checkout.Lines{{ItemID: 42, Quantity: 2}}, cart.Contents)
 	})
 
 	t.Run("add and remove single item", func(t *testing.T) {
-		cart := newTestCartAggregate(t, 1001)
+		cart := newTestCartAggregate(t, "cart-1001")
 		assert.NoError(t, cart.Add(42))
 		assert.NoError(t, cart.Remove(42))
-		assert.Equal(t, []int{}, cart.Contents)
+		assert.Equal(t, checkout.Lines{}, cart.Contents)
 	})
 
 	t.Run("checkout", func(t *testing.T) {
-		cart := newTestCartAggregate(t, 1001)
+		cart := newTestCartAggregate(t, "cart-1001")
 
 		assert.Equal(t, false, cart.CheckedOut)
 
-		assert.NoError(t, cart.Checkout())
+		assert.NoError(t, cart.Checkout(nil))
 
 		assert.Equal(t, true, cart.CheckedOut)
 	})
 
 	t.Run("cannot add item to checked out cart", func(t *testing.T) {
-		cart := newTestCartAggregate(t, 1001)
+		cart := newTestCartAggregate(t, "cart-1001")
 		assert.Equal(t, false, cart.CheckedOut)
-		assert.NoError(t, cart.Checkout())
+		assert.NoError(t, cart.Checkout(nil))
 
 		err := cart.Add(42)
 		assert.Error(t, err)
-		assert.EqualError(t, err, "cannot add items to a checked out cart")
-		assert.Equal(t, []int{}, cart.Contents)
+		assert.ErrorIs(t, err, checkout.ErrCartCheckedOut)
+		assert.EqualError(t, err, "cannot add items: cart is already checked out")
+		assert.Equal(t, checkout.Lines{}, cart.Contents)
 		assert.Equal(t, true, cart.CheckedOut)
 	})
 
 	t.Run("cannot remove item from checked out cart", func(t *testing.T) {
-		cart := newTestCartAggregate(t, 1001)
+		cart := newTestCartAggregate(t, "cart-1001")
 		assert.NoError(t, cart.Add(42))
 
-		assert.NoError(t, cart.Checkout())
+		assert.NoError(t, cart.Checkout(nil))
 
 		err := cart.Remove(42)
 		assert.Error(t, err)
-		assert.EqualError(t, err, "cannot remove items from a checked out cart")
-		assert.Equal(t, []int{42}, cart.Contents)
+		assert.ErrorIs(t, err, checkout.ErrCartCheckedOut)
+		assert.EqualError(t, err, "cannot remove items: cart is already checked out")
+		assert.Equal(t, checkout.Lines{{ItemID: 42, Quantity: 1}}, cart.Contents)
 	})
 
 	t.Run("remove non-existent item", func(t *testing.T) {
-		cart := newTestCartAggregate(t, 1001)
+		cart := newTestCartAggregate(t, "cart-1001")
 		assert.NoError(t, cart.Remove(99))
-		assert.Equal(t, []int{}, cart.Contents)
+		assert.Equal(t, checkout.Lines{}, cart.Contents)
 	})
 
 	t.Run("multiple unique items", func(t *testing.T) {
-		cart := newTestCartAggregate(t, 1001)
+		cart := newTestCartAggregate(t, "cart-1001")
 		assert.NoError(t, cart.Add(42))
 		assert.NoError(t, cart.Add(43))
 		assert.NoError(t, cart.Add(44))
 
-		assert.Equal(t, []int{42, 43, 44}, cart.Contents)
+		assert.Equal(t, checkout.Lines{{ItemID: 42, Quantity: 1}, {ItemID: 43, Quantity: 1}, {ItemID: 44, Quantity: 1}}, cart.Contents)
 	})
 
 	t.Run("remove item from multiple items", func(t *testing.T) {
-		cart := newTestCartAggregate(t, 1001)
+		cart := newTestCartAggregate(t, "cart-1001")
 		assert.NoError(t, cart.Add(42))
 		assert.NoError(t, cart.Add(43))
 		assert.NoError(t, cart.Add(44))
 
 		assert.NoError(t, cart.Remove(43))
 
-		assert.Equal(t, []int{42, 44}, cart.Contents)
+		assert.Equal(t, checkout.Lines{{ItemID: 42, Quantity: 1}, {ItemID: 44, Quantity: 1}}, cart.Contents)
+	})
+
+	t.Run("set quantity in a single event", func(t *testing.T) {
+		cart := newTestCartAggregate(t, "cart-1001")
+		assert.NoError(t, cart.Add(42))
+		cart.Commit()
+
+		assert.NoError(t, cart.SetQuantity(42, 10))
+		assert.NoError(t, cart.SetQuantity(7, 2))
+		assert.Equal(t, checkout.Lines{{ItemID: 42, Quantity: 10}, {ItemID: 7, Quantity: 2}}, cart.Contents)
+
+		events := cart.UncommittedEvents()
+		assert.Len(t, events, 2)
+		assert.Equal(t, checkout.ItemQuantityChanged, events[0].Type)
+		data, err := es.DecodeData[map[string]int](events[0])
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int{"item_id": 42, "quantity": 10, "previous_quantity": 1}, data)
+	})
+
+	t.Run("set quantity to zero removes the item", func(t *testing.T) {
+		cart := newTestCartAggregate(t, "cart-1001")
+		assert.NoError(t, cart.SetQuantity(42, 3))
+		assert.NoError(t, cart.SetQuantity(42, 0))
+		assert.Equal(t, checkout.Lines{}, cart.Contents)
+		assert.Equal(t, 0, cart.Contents.Quantity(42))
+	})
+
+	t.Run("setting the same quantity changes nothing", func(t *testing.T) {
+		cart := newTestCartAggregate(t, "cart-1001")
+		assert.NoError(t, cart.SetQuantity(42, 3))
+		cart.Commit()
+
+		assert.NoError(t, cart.SetQuantity(42, 3))
+		assert.Equal(t, []es.Event{}, cart.UncommittedEvents())
+	})
+
+	t.Run("lines hold at most MaxQuantity units", func(t *testing.T) {
+		cart := newTestCartAggregate(t, "cart-1001")
+		assert.NoError(t, cart.SetQuantity(42, checkout.MaxQuantity))
+		assert.ErrorIs(t, cart.Add(42), checkout.ErrInvalidQuantity)
+		assert.ErrorIs(t, cart.SetQuantity(42, checkout.MaxQuantity+1), checkout.ErrInvalidQuantity)
+		assert.Equal(t, checkout.MaxQuantity, cart.Contents.Quantity(42))
+		assert.NoError(t, cart.SetQuantity(42, 1))
+	})
+
+	t.Run("cannot set quantity in checked out cart", func(t *testing.T) {
+		cart := newTestCartAggregate(t, "cart-1001")
+		assert.NoError(t, cart.Checkout(nil))
+
+		err := cart.SetQuantity(42, 3)
+		assert.ErrorIs(t, err, checkout.ErrCartCheckedOut)
+		assert.EqualError(t, err, "cannot change items: cart is already checked out")
+	})
+
+	t.Run("checks added items against the catalog", func(t *testing.T) {
+		catalog := map[int]*checkout.Product{42: {ID: 42}, 13: {ID: 13, Discontinued: true}}
+		cart := newTestCartAggregate(t, "cart-1001")
+		checkout.UseProducts(func(productID int) (*checkout.Product, error) {
+			return catalog[productID], nil
+		})(cart)
+
+		assert.NoError(t, cart.Add(42))
+		assert.ErrorIs(t, cart.Add(99), checkout.ErrUnknownProduct)
+		assert.ErrorIs(t, cart.SetQuantity(13, 2), checkout.ErrProductDiscontinued)
+		assert.Equal(t, checkout.Lines{{ItemID: 42, Quantity: 1}}, cart.Contents)
+	})
+
+	t.Run("takes discontinued products out", func(t *testing.T) {
+		cart := newTestCartAggregate(t, "cart-1001")
+		assert.NoError(t, cart.SetQuantity(13, 3))
+		checkout.UseProducts(func(productID int) (*checkout.Product, error) {
+			return &checkout.Product{ID: productID, Discontinued: true}, nil
+		})(cart)
+
+		assert.NoError(t, cart.SetQuantity(13, 1))
+		assert.NoError(t, cart.Remove(13))
+		assert.Equal(t, checkout.Lines{}, cart.Contents)
 	})
 
 	t.Run("cannot checkout multiple times", func(t *testing.T) {
-		cart := newTestCartAggregate(t, 1001)
-		assert.NoError(t, cart.Checkout())
+		cart := newTestCartAggregate(t, "cart-1001")
+		assert.NoError(t, cart.Checkout(nil))
 
-		err := cart.Checkout()
+		err := cart.Checkout(nil)
 		assert.Error(t, err)
-		assert.EqualError(t, err, "cart is already checked out")
+		assert.ErrorIs(t, err, checkout.ErrCartCheckedOut)
 	})
 }
 
 func TestCartAggregateEvents(t *testing.T) {
 	t.Run("cart aggregate initial events", func(t *testing.T) {
-		cart := newTestCartAggregate(t, 1001)
+		cart := newTestCartAggregate(t, "cart-1001")
 		assert.NoError(t, cart.Add(42))
 
 		assert.Equal(t, []es.Event{
@@ -113,7 +194,7 @@ func TestCartAggregateEvents(t *testing.T) {
 				At:            atTimeDelta(0),
 				VersionID:     1,
 				AggregateType: checkout.CartType,
-				AggregateID:   1001,
+				AggregateID:   "cart-1001",
 				Data:          map[string]any{},
 			},
 			{
@@ -121,14 +202,45 @@ func TestCartAggregateEvents(t *testing.T) {
 				At:            atTimeDelta(1),
 				VersionID:     2,
 				AggregateType: checkout.CartType,
-				AggregateID:   1001,
+				AggregateID:   "cart-1001",
 				Data:          map[string]int{"item_id": 42},
 			},
 		}, cart.UncommittedEvents())
 	})
 
+	t.Run("events are scoped to the cart tenant", func(t *testing.T) {
+		cart := checkout.NewCartAggregate("cart-1001", checkout.ForTenant("store-a"))
+		assert.NoError(t, cart.Init())
+		assert.NoError(t, cart.Add(42))
+
+		for _, event := range cart.UncommittedEvents() {
+			assert.Equal(t, "store-a", event.TenantID)
+			assert.NoError(t, event.Validate())
+		}
+	})
+
+	t.Run("cart created event records the owner", func(t *testing.T) {
+		cart := checkout.NewCartAggregate("cart-1001", checkout.ForTenant("store-a"), checkout.OwnedBy("alice"))
+		assert.NoError(t, cart.Init())
+
+		created := cart.UncommittedEvents()[0]
+		assert.Equal(t, map[string]any{"owner": "alice"}, created.Data)
+		assert.NoError(t, es.DefaultSchemas.Validate(created))
+
+		replayed := checkout.NewReplayableCart("store-a", "cart-1001").(*checkout.CartAggregate)
+		assert.NoError(t, replayed.Apply(created))
+		assert.Equal(t, "alice", replayed.Owner)
+	})
+
+	t.Run("events without a tenant are invalid", func(t *testing.T) {
+		cart := newTestCartAggregate(t, "cart-1001")
+
+		err := cart.UncommittedEvents()[0].Validate()
+		assert.EqualError(t, err, "tenant ID must not be empty")
+	})
+
 	t.Run("apply no events", func(t *testing.T) {
-		cart := newTestCartAggregate(t, 1001)
+		cart := newTestCartAggregate(t, "cart-1001")
 
 		err := cart.Apply()
 		assert.Error(t, err)
@@ -136,7 +248,7 @@ func TestCartAggregateEvents(t *testing.T) {
 	})
 
 	t.Run("apply unknown event type returns error", func(t *testing.T) {
-		cart := newTestCartAggregate(t, 1001)
+		cart := newTestCartAggregate(t, "cart-1001")
 
 		// Create an event with an unimplemented type to trigger the default case
 		unknownEvent := es.Event{
@@ -150,7 +262,7 @@ func TestCartAggregateEvents(t *testing.T) {
 	})
 
 	t.Run("apply event with invalid item ID data", func(t *testing.T) {
-		cart := newTestCartAggregate(t, 1001)
+		cart := newTestCartAggregate(t, "cart-1001")
 
 		// Create an event with invalid item ID data
 		invalidItemEvent := es.Event{
@@ -163,7 +275,7 @@ func TestCartAggregateEvents(t *testing.T) {
 	})
 
 	t.Run("apply multiple different events", func(t *testing.T) {
-		cart := newTestCartAggregate(t, 1001)
+		cart := newTestCartAggregate(t, "cart-1001")
 
 		// Prepare multiple events to apply in a single call
 		events := []es.Event{
@@ -174,12 +286,12 @@ func TestCartAggregateEvents(t *testing.T) {
 
 		err := cart.Apply(events...)
 		assert.NoError(t, err)
-		assert.Equal(t, []int{42, 43}, cart.Contents)
+		assert.Equal(t, checkout.Lines{{ItemID: 42, Quantity: 1}, {ItemID: 43, Quantity: 1}}, cart.Contents)
 		assert.True(t, cart.CheckedOut)
 	})
 
 	t.Run("remove item from non-consecutive position", func(t *testing.T) {
-		cart := newTestCartAggregate(t, 1001)
+		cart := newTestCartAggregate(t, "cart-1001")
 
 		// Add multiple items and remove a non-first, non-last item
 		events := []es.Event{
@@ -191,11 +303,11 @@ func TestCartAggregateEvents(t *testing.T) {
 
 		err := cart.Apply(events...)
 		assert.NoError(t, err)
-		assert.Equal(t, []int{10, 30}, cart.Contents)
+		assert.Equal(t, checkout.Lines{{ItemID: 10, Quantity: 1}, {ItemID: 30, Quantity: 1}}, cart.Contents)
 	})
 
 	t.Run("apply event with unmarshalable data", func(t *testing.T) {
-		cart := newTestCartAggregate(t, 1001)
+		cart := newTestCartAggregate(t, "cart-1001")
 
 		// Create an event with data that cannot be JSON marshaled
 		invalidEvent := es.Event{
@@ -208,7 +320,7 @@ func TestCartAggregateEvents(t *testing.T) {
 	})
 
 	t.Run("commit events", func(t *testing.T) {
-		cart := newTestCartAggregate(t, 1001)
+		cart := newTestCartAggregate(t, "cart-1001")
 
 		events := []es.Event{
 			{Type: checkout.ItemAddedToCart, Data: map[string]int{"item_id": 10}},
@@ -225,7 +337,7 @@ func TestCartAggregateEvents(t *testing.T) {
 					At:            atTimeDelta(0),
 					VersionID:     1,
 					AggregateType: checkout.CartType,
-					AggregateID:   1001,
+					AggregateID:   "cart-1001",
 					Data:          map[string]any{},
 				},
 			}, events...), cart.UncommittedEvents())
@@ -239,7 +351,44 @@ func TestCartAggregateEvents(t *testing.T) {
 	})
 }
 
-func newTestCartAggregate(t *testing.T, cartID int) *checkout.CartAggregate {
+func TestCartAggregateSnapshot(t *testing.T) {
+	t.Run("restore from snapshot", func(t *testing.T) {
+		cart := newTestCartAggregate(t, "cart-1001")
+		assert.NoError(t, cart.Add(42))
+		assert.NoError(t, cart.Add(43))
+
+		snapshot, err := cart.Snapshot()
+		assert.NoError(t, err)
+		assert.Equal(t, 3, snapshot.VersionID)
+
+		restored := checkout.NewCartAggregate("cart-1001")
+		assert.NoError(t, restored.Restore(snapshot))
+		assert.Equal(t, checkout.Lines{{ItemID: 42, Quantity: 1}, {ItemID: 43, Quantity: 1}}, restored.Contents)
+
+		// New events continue from the snapshot version
+		assert.NoError(t, restored.Checkout(nil))
+		assert.Equal(t, 4, restored.UncommittedEvents()[0].VersionID)
+	})
+
+	t.Run("restore snapshot with contents as item IDs", func(t *testing.T) {
+		cart := checkout.NewCartAggregate("cart-1001")
+		assert.NoError(t, cart.Restore(es.Snapshot{
+			AggregateType: checkout.CartType,
+			VersionID:     4,
+			Data:          []byte(`{"id": "cart-1001", "contents": [42, 7, 42]}`),
+		}))
+		assert.Equal(t, checkout.Lines{{ItemID: 42, Quantity: 2}, {ItemID: 7, Quantity: 1}}, cart.Contents)
+		assert.Equal(t, []int{42, 42, 7}, cart.Contents.Items())
+	})
+
+	t.Run("reject snapshot of another aggregate type", func(t *testing.T) {
+		cart := checkout.NewCartAggregate("cart-1001")
+		err := cart.Restore(es.Snapshot{AggregateType: "order", Data: []byte(`{}`)})
+		assert.EqualError(t, err, "snapshot is not of a cart")
+	})
+}
+
+func newTestCartAggregate(t *testing.T, cartID string) *checkout.CartAggregate {
 	t.Helper()
 
 	cart := checkout.NewCartAggregate(cartID, checkout.UseTimestamp(
@@ -253,3 +402,154 @@ func newTestCartAggregate(t *testing.T, cartID int) *checkout.CartAggregate {
 func atTimeDelta(ns int) time.Time {
 	return time.Date(2026, 1, 1, 0, 0, 0, ns, time.UTC)
 }
+
+func TestCartEventSchemas(t *testing.T) {
+	t.Run("events raised by the cart match their schemas", func(t *testing.T) {
+		cart := newTestCartAggregate(t, "cart-1001")
+		assert.NoError(t, cart.Add(42))
+		assert.NoError(t, cart.Remove(42))
+		assert.NoError(t, cart.Checkout(nil))
+
+		for _, event := range cart.UncommittedEvents() {
+			assert.NoError(t, es.DefaultSchemas.Validate(event), event.Type)
+		}
+	})
+
+	t.Run("checkout with totals matches its schema", func(t *testing.T) {
+		cart := newTestCartAggregate(t, "cart-1001")
+		assert.NoError(t, cart.Add(42))
+		assert.NoError(t, cart.Checkout(&checkout.Totals{
+			Region:   "DE",
+			Lines:    []checkout.PricedLine{{ItemID: 42, Quantity: 1, UnitPrice: 1000, Total: 1000}},
+			Subtotal: 1000,
+			Taxes:    []checkout.Tax{{Name: "VAT", Rate: 1900, Amount: 190}},
+			Tax:      190,
+			Total:    1190,
+		}))
+
+		events := cart.UncommittedEvents()
+		assert.NoError(t, es.DefaultSchemas.Validate(events[len(events)-1]))
+	})
+
+	t.Run("item events require an item ID", func(t *testing.T) {
+		err := es.DefaultSchemas.Validate(es.Event{
+			Type: checkout.ItemAddedToCart,
+			Data: map[string]any{},
+		})
+		assert.ErrorIs(t, err, es.ErrInvalidEventData)
+	})
+}
+
+func TestCartScenarios(t *testing.T) {
+	newCart := func(now util.Timestamp) *checkout.CartAggregate {
+		return checkout.NewCartAggregate("cart-1001", checkout.UseTimestamp(now), checkout.ForTenant("store-a"))
+	}
+	created := es.Event{Type: checkout.CartCreated, AggregateID: "cart-1001", Data: map[string]any{}}
+	added := func(itemID int) es.Event {
+		return es.Event{Type: checkout.ItemAddedToCart, Data: map[string]int{"item_id": itemID}}
+	}
+
+	t.Run("add to an existing cart", func(t *testing.T) {
+		estest.NewAggregateFixture(t, newCart).
+			Given(created, added(42)).
+			When(func(c *checkout.CartAggregate) error { return c.Add(43) }).
+			Then(es.Event{Type: checkout.ItemAddedToCart, VersionID: 3, Data: map[string]int{"item_id": 43}})
+	})
+
+	t.Run("removing an item not in the cart raises nothing", func(t *testing.T) {
+		estest.NewAggregateFixture(t, newCart).
+			Given(created, added(42)).
+			When(func(c *checkout.CartAggregate) error { return c.Remove(43) }).
+			Then()
+	})
+
+	t.Run("checked out cart rejects changes", func(t *testing.T) {
+		estest.NewAggregateFixture(t, newCart).
+			Given(created, added(42), es.Event{Type: checkout.CartCheckedOut, Data: map[string]any{}}).
+			When(func(c *checkout.CartAggregate) error { return c.Add(43) }).
+			ThenError(checkout.ErrCartCheckedOut)
+	})
+
+	expired := es.Event{Type: checkout.CartExpired, Data: map[string]any{}}
+
+	t.Run("expire an abandoned cart", func(t *testing.T) {
+		estest.NewAggregateFixture(t, newCart).
+			Given(created, added(42)).
+			When(func(c *checkout.CartAggregate) error { return c.Expire() }).
+			Then(es.Event{Type: checkout.CartExpired, VersionID: 3, Data: map[string]any{}})
+	})
+
+	t.Run("expiring twice raises nothing", func(t *testing.T) {
+		estest.NewAggregateFixture(t, newCart).
+			Given(created, expired).
+			When(func(c *checkout.CartAggregate) error { return c.Expire() }).
+			Then()
+	})
+
+	t.Run("expired cart rejects changes", func(t *testing.T) {
+		for name, change := range map[string]func(*checkout.CartAggregate) error{
+			"add":      func(c *checkout.CartAggregate) error { return c.Add(43) },
+			"remove":   func(c *checkout.CartAggregate) error { return c.Remove(42) },
+			"checkout": func(c *checkout.CartAggregate) error { return c.Checkout(nil) },
+		} {
+			t.Run(name, func(t *testing.T) {
+				estest.NewAggregateFixture(t, newCart).
+					Given(created, added(42), expired).
+					When(change).
+					ThenError(checkout.ErrCartExpired)
+			})
+		}
+	})
+
+	t.Run("checked out cart cannot expire", func(t *testing.T) {
+		estest.NewAggregateFixture(t, newCart).
+			Given(created, es.Event{Type: checkout.CartCheckedOut, Data: map[string]any{}}).
+			When(func(c *checkout.CartAggregate) error { return c.Expire() }).
+			ThenError(checkout.ErrCartCheckedOut)
+	})
+}
+
+func TestQuantityDelta(t *testing.T) {
+	cart := newTestCartAggregate(t, "cart-1001")
+	assert.NoError(t, cart.Add(42))
+	assert.NoError(t, cart.SetQuantity(42, 5))
+	assert.NoError(t, cart.SetQuantity(42, 2))
+	assert.NoError(t, cart.Remove(42))
+
+	type change struct{ itemID, delta int }
+	var changes []change
+	for _, event := range storedEvents(t, cart.UncommittedEvents()[1:]) {
+		itemID, delta, err := checkout.QuantityDelta(event)
+		assert.NoError(t, err)
+		changes = append(changes, change{itemID, delta})
+	}
+	assert.Equal(t, []change{{42, 1}, {42, 4}, {42, -3}, {42, -1}}, changes)
+
+	_, _, err := checkout.QuantityDelta(cart.UncommittedEvents()[0])
+	assert.EqualError(t, err, "cart.created does not change item quantities")
+}
+
+func TestQuantityDeltaHandler(t *testing.T) {
+	cart := newTestCartAggregate(t, "cart-1001")
+	assert.NoError(t, cart.SetQuantity(42, 5))
+	event := storedEvents(t, cart.UncommittedEvents()[1:])[0]
+	item := sqlprojection.Increment("cart_items",
+		sqlprojection.Row{"tenant_id": event.TenantID, "cart_id": "cart-1001", "item_id": 42},
+		sqlprojection.Row{"quantity": 5},
+	)
+
+	ops, err := checkout.QuantityDeltaHandler(nil)(event)
+	assert.NoError(t, err)
+	assert.Equal(t, []sqlprojection.Op{item}, ops)
+
+	touched := sqlprojection.Upsert("carts", sqlprojection.Row{"cart_id": "cart-1001"})
+	ops, err = checkout.QuantityDeltaHandler(func(event es.Event, delta int) []sqlprojection.Op {
+		assert.Equal(t, 5, delta)
+		return []sqlprojection.Op{touched}
+	})(event)
+	assert.NoError(t, err)
+	assert.Equal(t, []sqlprojection.Op{touched, item}, ops, "The cart row should change before its items")
+
+	_, err = checkout.QuantityDeltaHandler(nil)(cart.UncommittedEvents()[0])
+	assert.Error(t, err)
+}
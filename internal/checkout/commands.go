@@ -0,0 +1,312 @@
+package checkout
+
+import (
+	"context"
+	"errors"
+	"es/internal/es"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreateCart starts a new cart with an ID minted by the server. The cart is
+// priced in Region, or in the default region if it is empty.
+type CreateCart struct {
+	TenantID string
+	Owner    string
+	Region   string
+}
+
+func (CreateCart) CommandName() string { return "checkout.CreateCart" }
+func (c CreateCart) Tenant() string    { return c.TenantID }
+
+const (
+	// MaxQuantity is the most units of an item a cart holds and so a
+	// single command adds or sets.
+	MaxQuantity = 100
+	// MaxLines is the most items SetContents can fill a cart with.
+	MaxLines = 100
+)
+
+// AddItem adds Quantity units of an item to a cart, or one if Quantity is
+// zero. The units are added in a single event.
+//
+// Like the other commands changing a cart on behalf of a client, it fails
+// with ErrCartChanged if ExpectedVersion is set and the cart is no longer
+// at that version.
+type AddItem struct {
+	TenantID        string
+	CartID          string
+	ItemID          int
+	Quantity        int
+	ExpectedVersion int
+}
+
+func (AddItem) CommandName() string { return "checkout.AddItem" }
+func (c AddItem) Tenant() string    { return c.TenantID }
+
+func (c AddItem) Validate() error {
+	if err := validateCartItem(c.CartID, c.ItemID); err != nil {
+		return err
+	}
+	if c.Quantity < 0 || c.Quantity > MaxQuantity {
+		return fmt.Errorf("%w: must be between 1 and %d, or 0 for one unit", ErrInvalidQuantity, MaxQuantity)
+	}
+	return nil
+}
+
+// RemoveItem removes Quantity units of an item from a cart, one if it is
+// zero, or every unit if All is set. Removing more units than the cart
+// holds removes the item.
+type RemoveItem struct {
+	TenantID        string
+	CartID          string
+	ItemID          int
+	Quantity        int
+	All             bool
+	ExpectedVersion int
+}
+
+func (RemoveItem) CommandName() string { return "checkout.RemoveItem" }
+func (c RemoveItem) Tenant() string    { return c.TenantID }
+
+func (c RemoveItem) Validate() error {
+	if err := validateCartItem(c.CartID, c.ItemID); err != nil {
+		return err
+	}
+	if c.Quantity < 0 || c.Quantity > MaxQuantity {
+		return fmt.Errorf("%w: must be between 1 and %d, or 0 for one unit", ErrInvalidQuantity, MaxQuantity)
+	}
+	return nil
+}
+
+// SetItemQuantity sets the units of an item in a cart, removing the item
+// at zero.
+type SetItemQuantity struct {
+	TenantID        string
+	CartID          string
+	ItemID          int
+	Quantity        int
+	ExpectedVersion int
+}
+
+func (SetItemQuantity) CommandName() string { return "checkout.SetItemQuantity" }
+func (c SetItemQuantity) Tenant() string    { return c.TenantID }
+
+func (c SetItemQuantity) Validate() error {
+	if err := validateCartItem(c.CartID, c.ItemID); err != nil {
+		return err
+	}
+	if c.Quantity < 0 || c.Quantity > MaxQuantity {
+		return fmt.Errorf("%w: must be between 0 and %d", ErrInvalidQuantity, MaxQuantity)
+	}
+	return nil
+}
+
+// SetContents replaces the contents of a cart with the given lines. The
+// items whose quantity changes are changed in a single save, so the cart
+// never holds part of the new contents.
+type SetContents struct {
+	TenantID        string
+	CartID          string
+	Lines           Lines
+	ExpectedVersion int
+}
+
+func (SetContents) CommandName() string { return "checkout.SetContents" }
+func (c SetContents) Tenant() string    { return c.TenantID }
+
+func (c SetContents) Validate() error {
+	if err := validateCart(c.CartID); err != nil {
+		return err
+	}
+	return c.Lines.validate()
+}
+
+// Checkout checks out a cart.
+type Checkout struct {
+	TenantID        string
+	CartID          string
+	ExpectedVersion int
+}
+
+func (Checkout) CommandName() string { return "checkout.Checkout" }
+func (c Checkout) Tenant() string    { return c.TenantID }
+func (c Checkout) Validate() error   { return validateCart(c.CartID) }
+
+// ExpireCart expires a cart that has not changed since IdleSince. Carts
+// changed since then, checked out or already expired are left alone.
+type ExpireCart struct {
+	TenantID  string
+	CartID    string
+	IdleSince time.Time
+}
+
+func (ExpireCart) CommandName() string { return "checkout.ExpireCart" }
+func (c ExpireCart) Tenant() string    { return c.TenantID }
+func (c ExpireCart) Validate() error   { return validateCart(c.CartID) }
+
+func validateCart(cartID string) error {
+	if cartID == "" {
+		return errors.New("cart ID must not be empty")
+	}
+	if len(cartID) > es.MaxAggregateIDLength {
+		return errors.New("cart ID is too long")
+	}
+	return nil
+}
+
+func validateCartItem(cartID string, itemID int) error {
+	if err := validateCart(cartID); err != nil {
+		return err
+	}
+	if itemID <= 0 {
+		return fmt.Errorf("%w: item ID must be positive", ErrInvalidItem)
+	}
+	return nil
+}
+
+// RegisterCommandHandlers registers the cart command handlers with the bus.
+// Items added to carts must be products on sale in products unless it is
+// nil, and carts are checked out with their totals unless pricer is nil.
+func RegisterCommandHandlers(bus *es.CommandBus, repository CartRepository, products ProductCatalog, pricer *Pricer) {
+	es.RegisterHandler(bus, func(ctx context.Context, cmd CreateCart) (*CartAggregate, error) {
+		region := cmd.Region
+		if pricer != nil {
+			var err error
+			if region, err = pricer.Region(region); err != nil {
+				return nil, err
+			}
+		}
+		cartID, err := NewCartID()
+		if err != nil {
+			return nil, err
+		}
+		return repository.New(ctx, cmd.TenantID, cartID, OwnedBy(cmd.Owner), InRegion(region))
+	})
+
+	es.RegisterHandler(bus, func(ctx context.Context, cmd AddItem) (*CartAggregate, error) {
+		return changeCart(ctx, repository, products, cmd.TenantID, cmd.CartID, cmd.ExpectedVersion, func(cart *CartAggregate) error {
+			if cmd.Quantity <= 1 {
+				return cart.Add(cmd.ItemID)
+			}
+			return cart.SetQuantity(cmd.ItemID, cart.Contents.Quantity(cmd.ItemID)+cmd.Quantity)
+		})
+	})
+
+	es.RegisterHandler(bus, func(ctx context.Context, cmd RemoveItem) (*CartAggregate, error) {
+		return changeCart(ctx, repository, products, cmd.TenantID, cmd.CartID, cmd.ExpectedVersion, func(cart *CartAggregate) error {
+			if cmd.All {
+				return cart.SetQuantity(cmd.ItemID, 0)
+			}
+			if cmd.Quantity <= 1 {
+				return cart.Remove(cmd.ItemID)
+			}
+			return cart.SetQuantity(cmd.ItemID, max(cart.Contents.Quantity(cmd.ItemID)-cmd.Quantity, 0))
+		})
+	})
+
+	es.RegisterHandler(bus, func(ctx context.Context, cmd SetItemQuantity) (*CartAggregate, error) {
+		return changeCart(ctx, repository, products, cmd.TenantID, cmd.CartID, cmd.ExpectedVersion, func(cart *CartAggregate) error {
+			return cart.SetQuantity(cmd.ItemID, cmd.Quantity)
+		})
+	})
+
+	es.RegisterHandler(bus, func(ctx context.Context, cmd SetContents) (*CartAggregate, error) {
+		return changeCart(ctx, repository, products, cmd.TenantID, cmd.CartID, cmd.ExpectedVersion, func(cart *CartAggregate) error {
+			for _, line := range slices.Clone(cart.Contents) {
+				if cmd.Lines.Quantity(line.ItemID) == 0 {
+					if err := cart.SetQuantity(line.ItemID, 0); err != nil {
+						return err
+					}
+				}
+			}
+			for _, line := range cmd.Lines {
+				if err := cart.SetQuantity(line.ItemID, line.Quantity); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+
+	es.RegisterHandler(bus, func(ctx context.Context, cmd Checkout) (*CartAggregate, error) {
+		return changeCart(ctx, repository, products, cmd.TenantID, cmd.CartID, cmd.ExpectedVersion, func(cart *CartAggregate) error {
+			if pricer == nil {
+				return cart.Checkout(nil)
+			}
+			// Carts that cannot be priced are checked out without totals,
+			// like carts checked out before pricing.
+			totals, err := pricer.Price(ctx, cmd.TenantID, cart.Region, cart.Contents)
+			if unpriceable(err) {
+				return cart.Checkout(nil)
+			}
+			if err != nil {
+				return err
+			}
+			return cart.Checkout(totals)
+		})
+	})
+
+	es.RegisterHandler(bus, func(ctx context.Context, cmd ExpireCart) (*CartAggregate, error) {
+		return changeCart(ctx, repository, products, cmd.TenantID, cmd.CartID, 0, func(cart *CartAggregate) error {
+			// The cart may have been used since it was found idle.
+			if cart.CheckedOut || cart.UpdatedAt.After(cmd.IdleSince) {
+				return nil
+			}
+			return cart.Expire()
+		})
+	})
+}
+
+// changeCart loads a cart, applies the change and saves the new events. A
+// non-zero expectedVersion must match the version of the loaded cart, so
+// clients never change a cart they have not seen.
+func changeCart(
+	ctx context.Context,
+	repository CartRepository,
+	products ProductCatalog,
+	tenantID string,
+	cartID string,
+	expectedVersion int,
+	change func(*CartAggregate) error,
+) (*CartAggregate, error) {
+	cart, err := repository.Get(ctx, tenantID, cartID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if cart == nil {
+		return nil, ErrCartNotFound
+	}
+
+	if expectedVersion != 0 && cart.Version() != expectedVersion {
+		return nil, fmt.Errorf("%w: expected version %d, cart is at version %d", ErrCartChanged, expectedVersion, cart.Version())
+	}
+
+	if products != nil {
+		UseProducts(lookupIn(ctx, products, tenantID))(cart)
+	}
+
+	if err := change(cart); err != nil {
+		return nil, err
+	}
+
+	if err := repository.Save(ctx, cart); err != nil {
+		return nil, err
+	}
+
+	return cart, nil
+}
+
+// NewCartID mints an opaque, time ordered cart ID (UUIDv7).
+func NewCartID() (string, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return "", fmt.Errorf("generate cart ID: %w", err)
+	}
+	return id.String(), nil
+}
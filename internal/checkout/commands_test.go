@@ -0,0 +1,242 @@
+package checkout_test
+
+import (
+	"context"
+	"es/internal/checkout"
+	"es/internal/es"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommands(t *testing.T) {
+	t.Run("cart IDs are UUIDv7", func(t *testing.T) {
+		first, err := checkout.NewCartID()
+		require.NoError(t, err)
+		second, err := checkout.NewCartID()
+		require.NoError(t, err)
+
+		id, err := uuid.Parse(first)
+		require.NoError(t, err)
+		assert.Equal(t, uuid.Version(7), id.Version())
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("validate cart and item IDs", func(t *testing.T) {
+		assert.NoError(t, checkout.AddItem{CartID: "cart-1001", ItemID: 42}.Validate())
+		assert.EqualError(t, checkout.AddItem{ItemID: 42}.Validate(), "cart ID must not be empty")
+		assert.ErrorIs(t, checkout.RemoveItem{CartID: "cart-1001"}.Validate(), checkout.ErrInvalidItem)
+		assert.EqualError(t, checkout.Checkout{CartID: strings.Repeat("x", 65)}.Validate(), "cart ID is too long")
+		assert.EqualError(t, checkout.AddItem{CartID: "cart-1001", ItemID: 42, Quantity: 101}.Validate(), "invalid quantity: must be between 1 and 100, or 0 for one unit")
+		assert.ErrorIs(t, checkout.AddItem{CartID: "cart-1001", ItemID: 42, Quantity: -1}.Validate(), checkout.ErrInvalidQuantity)
+		assert.ErrorIs(t, checkout.RemoveItem{CartID: "cart-1001", ItemID: 42, Quantity: 101}.Validate(), checkout.ErrInvalidQuantity)
+		assert.NoError(t, checkout.SetItemQuantity{CartID: "cart-1001", ItemID: 42}.Validate())
+	})
+
+	t.Run("validate cart contents", func(t *testing.T) {
+		assert.NoError(t, checkout.SetContents{CartID: "cart-1001"}.Validate())
+		assert.EqualError(t, checkout.SetContents{CartID: "cart-1001", Lines: checkout.Lines{{ItemID: 42, Quantity: 1}, {ItemID: 42, Quantity: 2}}}.Validate(),
+			"invalid item: item 42 is listed more than once")
+		assert.ErrorIs(t, checkout.SetContents{CartID: "cart-1001", Lines: checkout.Lines{{ItemID: 42}}}.Validate(), checkout.ErrInvalidQuantity)
+		assert.ErrorIs(t, checkout.SetContents{CartID: "cart-1001", Lines: make(checkout.Lines, checkout.MaxLines+1)}.Validate(), checkout.ErrInvalidItem)
+	})
+}
+
+type memoryCartRepository struct {
+	carts    map[string]*checkout.CartAggregate
+	position int64
+}
+
+func (r *memoryCartRepository) New(_ context.Context, tenantID string, cartID string, options ...checkout.CartOption) (*checkout.CartAggregate, error) {
+	cart := checkout.NewCartAggregate(cartID, append([]checkout.CartOption{checkout.ForTenant(tenantID)}, options...)...)
+	if err := cart.Init(); err != nil {
+		return nil, err
+	}
+	r.carts[cartID] = cart
+	return cart, r.Save(context.Background(), cart)
+}
+
+// Get only finds carts of the given tenant, like PGCartRepository.
+func (r *memoryCartRepository) Get(_ context.Context, tenantID string, cartID string) (*checkout.CartAggregate, error) {
+	cart := r.carts[cartID]
+	if cart == nil || cart.TenantID != tenantID {
+		return nil, nil
+	}
+	return cart, nil
+}
+
+func (r *memoryCartRepository) GetAsOf(_ context.Context, _ string, _ string, _ es.EventRange) (*checkout.CartAggregate, error) {
+	return nil, nil
+}
+
+// Save assigns the events their positions, as EventStream.Append does.
+func (r *memoryCartRepository) Save(_ context.Context, cart *checkout.CartAggregate) error {
+	events := cart.UncommittedEvents()
+	for i := range events {
+		r.position++
+		events[i].Position = r.position
+	}
+	cart.Commit()
+	return nil
+}
+
+func TestSetContents(t *testing.T) {
+	cart := checkout.NewCartAggregate("cart-1001", checkout.ForTenant("store-a"))
+	require.NoError(t, cart.Init())
+	require.NoError(t, cart.Add(42))
+	require.NoError(t, cart.Add(7))
+	cart.Commit()
+
+	repository := &memoryCartRepository{carts: map[string]*checkout.CartAggregate{"cart-1001": cart}}
+	bus := es.NewCommandBus()
+	checkout.RegisterCommandHandlers(bus, repository, nil, nil)
+
+	t.Run("replaces the contents in a single save", func(t *testing.T) {
+		cart, err := es.Dispatch[*checkout.CartAggregate](context.Background(), bus, checkout.SetContents{
+			TenantID: "store-a",
+			CartID:   "cart-1001",
+			Lines:    checkout.Lines{{ItemID: 42, Quantity: 5}, {ItemID: 9, Quantity: 1}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, checkout.Lines{{ItemID: 42, Quantity: 5}, {ItemID: 9, Quantity: 1}}, cart.Contents)
+		assert.Equal(t, 6, cart.Version(), "Removing 7, setting 42 and adding 9 should take three events")
+		assert.Equal(t, int64(3), repository.position, "All events should be saved at once")
+	})
+
+	t.Run("repeated adds stay within MaxQuantity", func(t *testing.T) {
+		add := checkout.AddItem{TenantID: "store-a", CartID: "cart-1001", ItemID: 42, Quantity: checkout.MaxQuantity}
+		_, err := bus.Dispatch(context.Background(), add)
+		assert.ErrorIs(t, err, checkout.ErrInvalidQuantity)
+		assert.Equal(t, 5, repository.carts["cart-1001"].Contents.Quantity(42))
+	})
+
+	t.Run("changes nothing when the cart is checked out", func(t *testing.T) {
+		_, err := bus.Dispatch(context.Background(), checkout.Checkout{TenantID: "store-a", CartID: "cart-1001"})
+		require.NoError(t, err)
+
+		_, err = bus.Dispatch(context.Background(), checkout.SetContents{TenantID: "store-a", CartID: "cart-1001"})
+		assert.ErrorIs(t, err, checkout.ErrCartCheckedOut)
+		assert.Equal(t, checkout.Lines{{ItemID: 42, Quantity: 5}, {ItemID: 9, Quantity: 1}}, repository.carts["cart-1001"].Contents)
+	})
+}
+
+func TestRemoveItems(t *testing.T) {
+	cart := checkout.NewCartAggregate("cart-1001", checkout.ForTenant("store-a"))
+	require.NoError(t, cart.Init())
+	require.NoError(t, cart.SetQuantity(42, 5))
+	require.NoError(t, cart.Add(7))
+	cart.Commit()
+
+	repository := &memoryCartRepository{carts: map[string]*checkout.CartAggregate{"cart-1001": cart}}
+	bus := es.NewCommandBus()
+	checkout.RegisterCommandHandlers(bus, repository, nil, nil)
+	remove := checkout.RemoveItem{TenantID: "store-a", CartID: "cart-1001", ItemID: 42, Quantity: 3}
+
+	cart, err := es.Dispatch[*checkout.CartAggregate](context.Background(), bus, remove)
+	require.NoError(t, err)
+	assert.Equal(t, 2, cart.Contents.Quantity(42))
+	assert.Equal(t, 4, cart.Version(), "Removing several units should take a single event")
+
+	cart, err = es.Dispatch[*checkout.CartAggregate](context.Background(), bus, remove)
+	require.NoError(t, err)
+	assert.Equal(t, checkout.Lines{{ItemID: 7, Quantity: 1}}, cart.Contents, "Removing more units than the cart holds should remove the item")
+}
+
+// memoryCatalog holds the products of every tenant, any other item is
+// unknown.
+type memoryCatalog map[int]*checkout.Product
+
+func (c memoryCatalog) FindProduct(_ context.Context, _ string, productID int) (*checkout.Product, error) {
+	return c[productID], nil
+}
+
+func TestProductChecks(t *testing.T) {
+	bus := es.NewCommandBus()
+	repository := &memoryCartRepository{carts: map[string]*checkout.CartAggregate{}}
+	checkout.RegisterCommandHandlers(bus, repository, memoryCatalog{
+		42: {ID: 42},
+		13: {ID: 13, Discontinued: true},
+	}, nil)
+
+	cart, err := es.Dispatch[*checkout.CartAggregate](context.Background(), bus, checkout.CreateCart{TenantID: "store-a"})
+	require.NoError(t, err)
+
+	t.Run("adds products on sale", func(t *testing.T) {
+		_, err := bus.Dispatch(context.Background(), checkout.AddItem{TenantID: "store-a", CartID: cart.ID, ItemID: 42, Quantity: 2})
+		assert.NoError(t, err)
+	})
+
+	t.Run("rejects unknown products", func(t *testing.T) {
+		saved := repository.position
+		_, err := bus.Dispatch(context.Background(), checkout.AddItem{TenantID: "store-a", CartID: cart.ID, ItemID: 99})
+		assert.ErrorIs(t, err, checkout.ErrUnknownProduct)
+
+		_, err = bus.Dispatch(context.Background(), checkout.SetContents{
+			TenantID: "store-a", CartID: cart.ID, Lines: checkout.Lines{{ItemID: 42, Quantity: 1}, {ItemID: 99, Quantity: 1}},
+		})
+		assert.ErrorIs(t, err, checkout.ErrUnknownProduct)
+		assert.Equal(t, saved, repository.position, "No line should be saved")
+	})
+
+	t.Run("rejects discontinued products", func(t *testing.T) {
+		_, err := bus.Dispatch(context.Background(), checkout.SetItemQuantity{TenantID: "store-a", CartID: cart.ID, ItemID: 13, Quantity: 1})
+		assert.ErrorIs(t, err, checkout.ErrProductDiscontinued)
+		assert.EqualError(t, err, "product is discontinued: 13")
+	})
+
+	t.Run("checks nothing until turned on", func(t *testing.T) {
+		bus := es.NewCommandBus()
+		repository := &memoryCartRepository{carts: map[string]*checkout.CartAggregate{}}
+		checkout.RegisterCommandHandlers(bus, repository, nil, checkout.NewPricer(memoryCatalog{}, checkout.PricingConfig{}))
+
+		cart, err := es.Dispatch[*checkout.CartAggregate](context.Background(), bus, checkout.CreateCart{TenantID: "store-a"})
+		require.NoError(t, err)
+
+		_, err = bus.Dispatch(context.Background(), checkout.AddItem{TenantID: "store-a", CartID: cart.ID, ItemID: 99})
+		assert.NoError(t, err)
+	})
+}
+
+func TestExpireCart(t *testing.T) {
+	lastChange := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	newBus := func(t *testing.T) *es.CommandBus {
+		cart := checkout.NewCartAggregate("cart-1001",
+			checkout.ForTenant("store-a"),
+			checkout.UseTimestamp(func() time.Time { return lastChange }),
+		)
+		require.NoError(t, cart.Init())
+		cart.Commit()
+
+		bus := es.NewCommandBus()
+		checkout.RegisterCommandHandlers(bus, &memoryCartRepository{
+			carts: map[string]*checkout.CartAggregate{"cart-1001": cart},
+		}, nil, nil)
+		return bus
+	}
+
+	t.Run("expires carts idle since before the cutoff", func(t *testing.T) {
+		cart, err := es.Dispatch[*checkout.CartAggregate](context.Background(), newBus(t), checkout.ExpireCart{
+			TenantID: "store-a", CartID: "cart-1001", IdleSince: lastChange.Add(time.Hour),
+		})
+		require.NoError(t, err)
+		assert.True(t, cart.Expired)
+	})
+
+	t.Run("leaves carts changed since the cutoff", func(t *testing.T) {
+		cart, err := es.Dispatch[*checkout.CartAggregate](context.Background(), newBus(t), checkout.ExpireCart{
+			TenantID: "store-a", CartID: "cart-1001", IdleSince: lastChange.Add(-time.Hour),
+		})
+		require.NoError(t, err)
+		assert.False(t, cart.Expired)
+	})
+
+	t.Run("unknown cart", func(t *testing.T) {
+		_, err := newBus(t).Dispatch(context.Background(), checkout.ExpireCart{TenantID: "store-a", CartID: "cart-9999"})
+		assert.ErrorIs(t, err, checkout.ErrCartNotFound)
+	})
+}
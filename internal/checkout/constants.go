@@ -10,5 +10,7 @@ const (
 	CartCreated         es.EventType = "cart.created"
 	ItemAddedToCart     es.EventType = "cart.item_added"
 	ItemRemovedFromCart es.EventType = "cart.item_removed"
+	ItemQuantityChanged es.EventType = "cart.item_quantity_changed"
 	CartCheckedOut      es.EventType = "cart.checked_out"
+	CartExpired         es.EventType = "cart.expired"
 )
@@ -0,0 +1,19 @@
+package checkout
+
+import "es/internal/es"
+
+var (
+	ErrCartNotFound    = es.NewError(es.KindNotFound, "cart not found")
+	ErrItemNotInCart   = es.NewError(es.KindNotFound, "item is not in the cart")
+	ErrCartCheckedOut  = es.NewError(es.KindConflict, "cart is already checked out")
+	ErrInvalidItem     = es.NewError(es.KindInvalid, "invalid item")
+	ErrInvalidQuantity = es.NewError(es.KindInvalid, "invalid quantity")
+	ErrCartExpired     = es.NewError(es.KindGone, "cart has expired")
+	ErrHistoryArchived = es.NewError(es.KindGone, "cart history at that point has been archived")
+	ErrCartChanged     = es.NewError(es.KindPrecondition, "cart has changed since the expected version")
+	// ErrUnknownProduct and ErrProductDiscontinued reject items that are not
+	// products on sale in the catalog.
+	ErrUnknownProduct      = es.NewError(es.KindInvalid, "unknown product")
+	ErrProductDiscontinued = es.NewError(es.KindGone, "product is discontinued")
+	ErrUnknownRegion       = es.NewError(es.KindInvalid, "unknown tax region")
+)
@@ -0,0 +1,112 @@
+package checkout
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"es/internal/es"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ETag returns the entity tag of a cart at the given version. Every change
+// of a cart bumps its version, but the totals of open carts follow the
+// catalog prices and tax rules. The tag of a priced open cart therefore
+// also carries a hash of its totals, e.g. "4-1f2e3d4c5b6a7980", so that
+// it is a strong validator of the whole response.
+func ETag(version int, totals *Totals) string {
+	tag := strconv.Itoa(version)
+	if totals != nil {
+		data, _ := json.Marshal(totals)
+		sum := sha256.Sum256(data)
+		tag += "-" + hex.EncodeToString(sum[:8])
+	}
+	return `"` + tag + `"`
+}
+
+// cartETag returns the entity tag of the cart. Checked out carts keep their
+// totals, so their version alone tells them apart.
+func cartETag(cart *CartAggregate) string {
+	if cart.CheckedOut {
+		return ETag(cart.Version(), nil)
+	}
+	return ETag(cart.Version(), cart.Totals)
+}
+
+// setCartHeaders sets the ETag of the cart and the position of its latest
+// event, which reads of projections can wait for.
+func setCartHeaders(c *fiber.Ctx, cart *CartAggregate) {
+	c.Set(fiber.HeaderETag, cartETag(cart))
+	if position := cart.Position(); position > 0 {
+		c.Set(es.HeaderEventPosition, strconv.FormatInt(position, 10))
+	}
+}
+
+// notModified reports whether the If-None-Match header of a read matches
+// the cart. Unlike If-Match it uses the weak comparison.
+func notModified(c *fiber.Ctx, cart *CartAggregate) bool {
+	header := strings.TrimSpace(c.Get(fiber.HeaderIfNoneMatch))
+	if header == "" {
+		return false
+	}
+	if header == "*" {
+		return true
+	}
+
+	etag := cartETag(cart)
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// sendCart answers a read of the cart with its headers, or with 304 if the
+// client already has this version at these prices.
+func sendCart(c *fiber.Ctx, cart *CartAggregate) error {
+	setCartHeaders(c, cart)
+	if notModified(c, cart) {
+		return c.SendStatus(http.StatusNotModified)
+	}
+	return c.Status(http.StatusOK).JSON(cart)
+}
+
+// expectedVersion returns the version named by the If-Match header, or
+// zero if there is none or it is "*", which any existing cart matches.
+// Only the version of the tag is compared, since changes are based on the
+// cart's contents rather than its prices. Tags that name no version,
+// including weak ones, can never match and fail with ErrCartChanged.
+func expectedVersion(c *fiber.Ctx) (int, error) {
+	header := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	if strings.Contains(header, ",") {
+		return 0, fiber.NewError(http.StatusBadRequest, "If-Match must name a single version")
+	}
+
+	tag, ok := strings.CutPrefix(header, `"`)
+	if ok {
+		tag, ok = strings.CutSuffix(tag, `"`)
+	}
+	tag, _, _ = strings.Cut(tag, "-")
+	version, err := strconv.Atoi(tag)
+	if !ok || err != nil || version <= 0 {
+		return 0, fmt.Errorf("%w: If-Match %s names no version of the cart", ErrCartChanged, header)
+	}
+	return version, nil
+}
+
+// RequireIfMatch rejects requests without an If-Match header with 428, so
+// that clients cannot change a cart without saying which version they saw.
+func RequireIfMatch(c *fiber.Ctx) error {
+	if c.Get(fiber.HeaderIfMatch) == "" {
+		return fiber.NewError(http.StatusPreconditionRequired, "If-Match header is required, send the ETag of the cart")
+	}
+	return c.Next()
+}
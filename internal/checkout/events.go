@@ -2,21 +2,37 @@ package checkout
 
 import (
 	"es/internal/es"
+	"es/internal/es/sqlprojection"
+	"fmt"
 )
 
-func (c *CartAggregate) newCartCreatedEvent(cartID int) es.Event {
+type cartCreatedPayload struct {
+	Owner  string `json:"owner,omitempty"`
+	Region string `json:"region,omitempty"`
+}
+
+func (c *CartAggregate) newCartCreatedEvent(cartID string) es.Event {
+	data := map[string]any{}
+	if c.Owner != "" {
+		data["owner"] = c.Owner
+	}
+	if c.Region != "" {
+		data["region"] = c.Region
+	}
 	return es.Event{
+		TenantID:      c.TenantID,
 		AggregateType: CartType,
 		At:            c.now(),
 		Type:          CartCreated,
 		AggregateID:   cartID,
-		Data:          map[string]any{},
+		Data:          data,
 		VersionID:     1,
 	}
 }
 
 func (c *CartAggregate) newItemAddedToCartEvent(itemID int) es.Event {
 	return es.Event{
+		TenantID:      c.TenantID,
 		Type:          ItemAddedToCart,
 		AggregateType: CartType,
 		AggregateID:   c.ID,
@@ -30,6 +46,7 @@ func (c *CartAggregate) newItemAddedToCartEvent(itemID int) es.Event {
 
 func (c *CartAggregate) newItemRemovedFromCartEvent(itemID int) es.Event {
 	return es.Event{
+		TenantID:      c.TenantID,
 		Type:          ItemRemovedFromCart,
 		AggregateType: CartType,
 		AggregateID:   c.ID,
@@ -41,9 +58,73 @@ func (c *CartAggregate) newItemRemovedFromCartEvent(itemID int) es.Event {
 	}
 }
 
-func (c *CartAggregate) newCartCheckedOutEvent() es.Event {
+type itemQuantityChangedPayload struct {
+	ItemID           int `json:"item_id"`
+	Quantity         int `json:"quantity"`
+	PreviousQuantity int `json:"previous_quantity"`
+}
+
+// QuantityDelta returns the item an item added, item removed or item
+// quantity changed event is about and by how many units it changed the
+// item's quantity, for projections counting units.
+func QuantityDelta(event es.Event) (itemID int, delta int, err error) {
+	data, err := es.DecodeData[itemQuantityChangedPayload](event)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	switch event.Type {
+	case ItemAddedToCart:
+		return data.ItemID, 1, nil
+	case ItemRemovedFromCart:
+		return data.ItemID, -1, nil
+	case ItemQuantityChanged:
+		return data.ItemID, data.Quantity - data.PreviousQuantity, nil
+	}
+	return 0, 0, fmt.Errorf("%s does not change item quantities", event.Type)
+}
+
+// QuantityDeltaHandler is the projection handler of the item added, item
+// removed and item quantity changed events for projections counting units
+// in a cart_items table keyed by tenant_id, cart_id and item_id. It adds
+// each change to the item's quantity there, after the ops cart returns for
+// the cart row, if cart is not nil.
+func QuantityDeltaHandler(cart func(event es.Event, delta int) []sqlprojection.Op) sqlprojection.Handler {
+	return func(event es.Event) ([]sqlprojection.Op, error) {
+		itemID, delta, err := QuantityDelta(event)
+		if err != nil {
+			return nil, err
+		}
+
+		var ops []sqlprojection.Op
+		if cart != nil {
+			ops = cart(event, delta)
+		}
+		item := sqlprojection.Row{"tenant_id": event.TenantID, "cart_id": event.AggregateID, "item_id": itemID}
+		return append(ops, sqlprojection.Increment("cart_items", item, sqlprojection.Row{"quantity": delta})), nil
+	}
+}
+
+func (c *CartAggregate) newItemQuantityChangedEvent(itemID int, quantity int, previous int) es.Event {
 	return es.Event{
-		Type:          CartCheckedOut,
+		TenantID:      c.TenantID,
+		Type:          ItemQuantityChanged,
+		AggregateType: CartType,
+		AggregateID:   c.ID,
+		At:            c.now(),
+		VersionID:     c.currentVersion + 1,
+		Data: itemQuantityChangedPayload{
+			ItemID:           itemID,
+			Quantity:         quantity,
+			PreviousQuantity: previous,
+		},
+	}
+}
+
+func (c *CartAggregate) newCartExpiredEvent() es.Event {
+	return es.Event{
+		TenantID:      c.TenantID,
+		Type:          CartExpired,
 		AggregateType: CartType,
 		AggregateID:   c.ID,
 		At:            c.now(),
@@ -51,3 +132,19 @@ func (c *CartAggregate) newCartCheckedOutEvent() es.Event {
 		Data:          map[string]any{},
 	}
 }
+
+type cartCheckedOutPayload struct {
+	Totals *Totals `json:"totals,omitempty"`
+}
+
+func (c *CartAggregate) newCartCheckedOutEvent(totals *Totals) es.Event {
+	return es.Event{
+		TenantID:      c.TenantID,
+		Type:          CartCheckedOut,
+		AggregateType: CartType,
+		AggregateID:   c.ID,
+		At:            c.now(),
+		VersionID:     c.currentVersion + 1,
+		Data:          cartCheckedOutPayload{Totals: totals},
+	}
+}
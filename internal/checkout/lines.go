@@ -0,0 +1,101 @@
+package checkout
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// Line is an item in a cart with the units of it the cart holds.
+type Line struct {
+	ItemID   int `json:"item_id" openapi:"required,minimum=1"`
+	Quantity int `json:"quantity" openapi:"required,minimum=1"`
+}
+
+// Lines are the contents of a cart, one line per item in the order the
+// items were first added. Items whose quantity drops to zero have no line.
+type Lines []Line
+
+// Quantity returns the units of the item in the cart.
+func (l Lines) Quantity(itemID int) int {
+	for _, line := range l {
+		if line.ItemID == itemID {
+			return line.Quantity
+		}
+	}
+	return 0
+}
+
+// Items lists the item IDs with one entry per unit, which is how contents
+// were represented before carts had lines.
+func (l Lines) Items() []int {
+	items := []int{}
+	for _, line := range l {
+		for range line.Quantity {
+			items = append(items, line.ItemID)
+		}
+	}
+	return items
+}
+
+// validate checks that the lines could be the contents of a cart: at most
+// MaxLines items, each listed once with 1 to MaxQuantity units.
+func (l Lines) validate() error {
+	if len(l) > MaxLines {
+		return fmt.Errorf("%w: a cart holds at most %d items", ErrInvalidItem, MaxLines)
+	}
+	seen := make(map[int]bool, len(l))
+	for _, line := range l {
+		if line.ItemID <= 0 {
+			return fmt.Errorf("%w: item_id must be a positive integer", ErrInvalidItem)
+		}
+		if seen[line.ItemID] {
+			return fmt.Errorf("%w: item %d is listed more than once", ErrInvalidItem, line.ItemID)
+		}
+		seen[line.ItemID] = true
+		if line.Quantity < 1 || line.Quantity > MaxQuantity {
+			return fmt.Errorf("%w: must be between 1 and %d", ErrInvalidQuantity, MaxQuantity)
+		}
+	}
+	return nil
+}
+
+// set returns the lines with the quantity of the item set, appending a line
+// for a new item and dropping the line of an item set to zero.
+func (l Lines) set(itemID int, quantity int) Lines {
+	i := slices.IndexFunc(l, func(line Line) bool { return line.ItemID == itemID })
+	switch {
+	case i < 0 && quantity > 0:
+		return append(l, Line{ItemID: itemID, Quantity: quantity})
+	case i < 0:
+		return l
+	case quantity > 0:
+		l[i].Quantity = quantity
+		return l
+	default:
+		return slices.Delete(l, i, i+1)
+	}
+}
+
+// UnmarshalJSON also reads a list of item IDs with one entry per unit, the
+// form of snapshots taken before carts had lines.
+func (l *Lines) UnmarshalJSON(data []byte) error {
+	var lines []Line
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*l = Lines(lines)
+		if *l == nil {
+			*l = Lines{}
+		}
+		return nil
+	}
+
+	var items []int
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	*l = Lines{}
+	for _, itemID := range items {
+		*l = l.set(itemID, l.Quantity(itemID)+1)
+	}
+	return nil
+}
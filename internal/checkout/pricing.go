@@ -0,0 +1,148 @@
+package checkout
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// TaxRule is a tax levied on the subtotal of the carts of a region. Rate is
+// in basis points, so 1900 is 19%.
+type TaxRule struct {
+	Name string `json:"name"`
+	Rate int    `json:"rate"`
+}
+
+// PricingConfig holds the tax rules of every region carts can be priced
+// in. Carts created without a region are priced in DefaultRegion.
+type PricingConfig struct {
+	DefaultRegion string
+	Regions       map[string][]TaxRule
+}
+
+// LoadPricingConfig reads the tax rules per region as JSON from TAX_RULES,
+// e.g. {"DE": [{"name": "VAT", "rate": 1900}]}, and the region of carts
+// created without one from TAX_DEFAULT_REGION. Without them carts are
+// priced without tax.
+func LoadPricingConfig() (PricingConfig, error) {
+	cfg := PricingConfig{
+		DefaultRegion: os.Getenv("TAX_DEFAULT_REGION"),
+		Regions:       map[string][]TaxRule{},
+	}
+	if v := os.Getenv("TAX_RULES"); v != "" {
+		if err := json.Unmarshal([]byte(v), &cfg.Regions); err != nil {
+			return PricingConfig{}, fmt.Errorf("parse TAX_RULES: %w", err)
+		}
+	}
+	for region, rules := range cfg.Regions {
+		for _, rule := range rules {
+			if rule.Name == "" || rule.Rate < 0 {
+				return PricingConfig{}, fmt.Errorf("tax rule of region %s needs a name and a non-negative rate", region)
+			}
+		}
+	}
+	if _, ok := cfg.Regions[cfg.DefaultRegion]; !ok && cfg.DefaultRegion != "" {
+		return PricingConfig{}, fmt.Errorf("TAX_DEFAULT_REGION %s has no tax rules", cfg.DefaultRegion)
+	}
+	return cfg, nil
+}
+
+// PricedLine is a line of a cart with its price. Prices are in cents.
+type PricedLine struct {
+	ItemID    int   `json:"item_id"`
+	Quantity  int   `json:"quantity"`
+	UnitPrice int64 `json:"unit_price"`
+	Total     int64 `json:"total"`
+}
+
+// Tax is the amount a tax rule levies on the subtotal.
+type Tax struct {
+	Name   string `json:"name"`
+	Rate   int    `json:"rate"`
+	Amount int64  `json:"amount"`
+}
+
+// Totals are the prices of a cart's lines and what the cart comes to. Open
+// carts are priced at the current catalog prices, while checked out carts
+// keep the totals they were checked out with.
+type Totals struct {
+	Region   string       `json:"region,omitempty"`
+	Lines    []PricedLine `json:"lines"`
+	Subtotal int64        `json:"subtotal"`
+	Taxes    []Tax        `json:"taxes"`
+	Tax      int64        `json:"tax"`
+	Total    int64        `json:"total"`
+}
+
+// Pricer prices carts with the prices of the product catalog and the tax
+// rules of their region.
+type Pricer struct {
+	products ProductCatalog
+	config   PricingConfig
+}
+
+func NewPricer(products ProductCatalog, config PricingConfig) *Pricer {
+	return &Pricer{
+		products: products,
+		config:   config,
+	}
+}
+
+// Region returns the region carts created with the given one are priced
+// in, failing with ErrUnknownRegion for regions without tax rules.
+func (p *Pricer) Region(region string) (string, error) {
+	if region == "" {
+		return p.config.DefaultRegion, nil
+	}
+	if _, ok := p.config.Regions[region]; !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownRegion, region)
+	}
+	return region, nil
+}
+
+// Price prices the lines in the region. Every item must be in the catalog,
+// discontinued products are priced at their last price. Taxes are rounded
+// half up to the cent.
+func (p *Pricer) Price(ctx context.Context, tenantID string, region string, lines Lines) (*Totals, error) {
+	region, err := p.Region(region)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := &Totals{Region: region, Lines: make([]PricedLine, 0, len(lines)), Taxes: []Tax{}}
+	for _, line := range lines {
+		product, err := p.products.FindProduct(ctx, tenantID, line.ItemID)
+		if err != nil {
+			return nil, err
+		}
+		if product == nil {
+			return nil, fmt.Errorf("%w: %d", ErrUnknownProduct, line.ItemID)
+		}
+
+		priced := PricedLine{
+			ItemID:    line.ItemID,
+			Quantity:  line.Quantity,
+			UnitPrice: product.Price,
+			Total:     product.Price * int64(line.Quantity),
+		}
+		totals.Lines = append(totals.Lines, priced)
+		totals.Subtotal += priced.Total
+	}
+
+	for _, rule := range p.config.Regions[region] {
+		amount := (totals.Subtotal*int64(rule.Rate) + 5000) / 10000
+		totals.Taxes = append(totals.Taxes, Tax{Name: rule.Name, Rate: rule.Rate, Amount: amount})
+		totals.Tax += amount
+	}
+	totals.Total = totals.Subtotal + totals.Tax
+	return totals, nil
+}
+
+// unpriceable reports whether Price failed because the cart holds items that
+// are not in the catalog or its region has no tax rules anymore, rather
+// than because the catalog could not be read.
+func unpriceable(err error) bool {
+	return errors.Is(err, ErrUnknownProduct) || errors.Is(err, ErrUnknownRegion)
+}
@@ -0,0 +1,221 @@
+package checkout_test
+
+import (
+	"context"
+	"es/internal/checkout"
+	"es/internal/es"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testPricing = checkout.PricingConfig{
+	DefaultRegion: "DE",
+	Regions: map[string][]checkout.TaxRule{
+		"DE": {{Name: "VAT", Rate: 1900}},
+		"US-NY": {
+			{Name: "State sales tax", Rate: 400},
+			{Name: "City sales tax", Rate: 450},
+		},
+		"HK": {},
+	},
+}
+
+func TestPricer(t *testing.T) {
+	pricer := checkout.NewPricer(memoryCatalog{
+		42: {ID: 42, Price: 1250},
+		7:  {ID: 7, Price: 399},
+		13: {ID: 13, Price: 500, Discontinued: true},
+	}, testPricing)
+	lines := checkout.Lines{{ItemID: 42, Quantity: 3}, {ItemID: 7, Quantity: 1}}
+
+	t.Run("line totals and subtotal", func(t *testing.T) {
+		totals, err := pricer.Price(context.Background(), "store-a", "HK", lines)
+		require.NoError(t, err)
+
+		assert.Equal(t, []checkout.PricedLine{
+			{ItemID: 42, Quantity: 3, UnitPrice: 1250, Total: 3750},
+			{ItemID: 7, Quantity: 1, UnitPrice: 399, Total: 399},
+		}, totals.Lines)
+		assert.Equal(t, int64(4149), totals.Subtotal)
+		assert.Empty(t, totals.Taxes)
+		assert.Equal(t, int64(4149), totals.Total)
+	})
+
+	t.Run("taxes of the default region", func(t *testing.T) {
+		totals, err := pricer.Price(context.Background(), "store-a", "", lines)
+		require.NoError(t, err)
+
+		assert.Equal(t, "DE", totals.Region)
+		// 19% of 41.49 is 7.8831.
+		assert.Equal(t, []checkout.Tax{{Name: "VAT", Rate: 1900, Amount: 788}}, totals.Taxes)
+		assert.Equal(t, int64(788), totals.Tax)
+		assert.Equal(t, int64(4937), totals.Total)
+	})
+
+	t.Run("every tax of the region is rounded half up", func(t *testing.T) {
+		totals, err := pricer.Price(context.Background(), "store-a", "US-NY", checkout.Lines{{ItemID: 42, Quantity: 1}, {ItemID: 13, Quantity: 1}})
+		require.NoError(t, err)
+
+		// 4% of 17.50 is 0.70 and 4.5% is 0.7875.
+		assert.Equal(t, []checkout.Tax{
+			{Name: "State sales tax", Rate: 400, Amount: 70},
+			{Name: "City sales tax", Rate: 450, Amount: 79},
+		}, totals.Taxes)
+		assert.Equal(t, int64(149), totals.Tax)
+		assert.Equal(t, int64(1899), totals.Total)
+	})
+
+	t.Run("empty cart", func(t *testing.T) {
+		totals, err := pricer.Price(context.Background(), "store-a", "DE", checkout.Lines{})
+		require.NoError(t, err)
+		assert.Empty(t, totals.Lines)
+		assert.Equal(t, int64(0), totals.Total)
+	})
+
+	t.Run("unknown region", func(t *testing.T) {
+		_, err := pricer.Price(context.Background(), "store-a", "FR", lines)
+		assert.ErrorIs(t, err, checkout.ErrUnknownRegion)
+		assert.EqualError(t, err, "unknown tax region: FR")
+	})
+
+	t.Run("unknown product", func(t *testing.T) {
+		_, err := pricer.Price(context.Background(), "store-a", "DE", checkout.Lines{{ItemID: 99, Quantity: 1}})
+		assert.ErrorIs(t, err, checkout.ErrUnknownProduct)
+	})
+}
+
+func TestLoadPricingConfig(t *testing.T) {
+	t.Run("no tax by default", func(t *testing.T) {
+		cfg, err := checkout.LoadPricingConfig()
+		require.NoError(t, err)
+		assert.Empty(t, cfg.DefaultRegion)
+		assert.Empty(t, cfg.Regions)
+	})
+
+	t.Run("rules per region", func(t *testing.T) {
+		t.Setenv("TAX_RULES", `{"DE": [{"name": "VAT", "rate": 1900}], "CH": [{"name": "VAT", "rate": 810}]}`)
+		t.Setenv("TAX_DEFAULT_REGION", "DE")
+
+		cfg, err := checkout.LoadPricingConfig()
+		require.NoError(t, err)
+		assert.Equal(t, "DE", cfg.DefaultRegion)
+		assert.Equal(t, []checkout.TaxRule{{Name: "VAT", Rate: 810}}, cfg.Regions["CH"])
+	})
+
+	t.Run("default region without rules", func(t *testing.T) {
+		t.Setenv("TAX_DEFAULT_REGION", "DE")
+		_, err := checkout.LoadPricingConfig()
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid rules", func(t *testing.T) {
+		t.Setenv("TAX_RULES", `{"DE": [{"name": "VAT", "rate": -1}]}`)
+		_, err := checkout.LoadPricingConfig()
+		assert.Error(t, err)
+
+		t.Setenv("TAX_RULES", `[]`)
+		_, err = checkout.LoadPricingConfig()
+		assert.Error(t, err)
+	})
+}
+
+func TestCheckoutTotals(t *testing.T) {
+	products := memoryCatalog{42: {ID: 42, Price: 1000}}
+	pricer := checkout.NewPricer(products, testPricing)
+	bus := es.NewCommandBus()
+	repository := &memoryCartRepository{carts: map[string]*checkout.CartAggregate{}}
+	checkout.RegisterCommandHandlers(bus, repository, nil, pricer)
+	usecase := checkout.NewCheckoutUseCase(repository, bus, pricer)
+	ctx := context.Background()
+
+	cart, err := usecase.CreateCart(ctx, "store-a", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "DE", cart.Region, "Carts should be priced in the default region")
+	_, err = usecase.AddItemsToCart(ctx, "store-a", cart.ID, 42, 2, 0)
+	require.NoError(t, err)
+
+	t.Run("open carts are priced at current prices", func(t *testing.T) {
+		products[42].Price = 1500
+		cart, err := usecase.GetCartDetails(ctx, "store-a", cart.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3570), cart.Totals.Total)
+	})
+
+	t.Run("checkout freezes the totals", func(t *testing.T) {
+		_, err := usecase.Checkout(ctx, "store-a", cart.ID, 0)
+		require.NoError(t, err)
+
+		products[42].Price = 2000
+		cart, err := usecase.GetCartDetails(ctx, "store-a", cart.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3000), cart.Totals.Subtotal)
+		assert.Equal(t, int64(3570), cart.Totals.Total)
+	})
+
+	t.Run("carts that cannot be priced check out without totals", func(t *testing.T) {
+		unknownItem, err := usecase.CreateCart(ctx, "store-a", "", "")
+		require.NoError(t, err)
+		_, err = usecase.AddItemsToCart(ctx, "store-a", unknownItem.ID, 99, 1, 0)
+		require.NoError(t, err, "Items outside the catalog are taken while product checks are off")
+
+		removedRegion, err := usecase.CreateCart(ctx, "store-a", "", "US-NY")
+		require.NoError(t, err)
+		_, err = usecase.AddItemsToCart(ctx, "store-a", removedRegion.ID, 42, 1, 0)
+		require.NoError(t, err)
+
+		bus := es.NewCommandBus()
+		withoutNY := checkout.NewPricer(products, checkout.PricingConfig{DefaultRegion: "DE", Regions: map[string][]checkout.TaxRule{"DE": testPricing.Regions["DE"]}})
+		checkout.RegisterCommandHandlers(bus, repository, nil, withoutNY)
+		usecase := checkout.NewCheckoutUseCase(repository, bus, withoutNY)
+
+		for _, cartID := range []string{unknownItem.ID, removedRegion.ID} {
+			cart, err := usecase.Checkout(ctx, "store-a", cartID, 0)
+			require.NoError(t, err)
+			assert.True(t, cart.CheckedOut)
+			assert.Nil(t, cart.Totals)
+		}
+	})
+
+	t.Run("replayed carts keep the totals of their checkout", func(t *testing.T) {
+		totals, err := pricer.Price(ctx, "store-a", "DE", checkout.Lines{{ItemID: 42, Quantity: 1}})
+		require.NoError(t, err)
+		cart := newTestCartAggregate(t, "cart-1001")
+		require.NoError(t, cart.Add(42))
+		require.NoError(t, cart.Checkout(totals))
+
+		replayed := checkout.NewCartAggregate("cart-1001")
+		require.NoError(t, replayed.Apply(storedEvents(t, cart.UncommittedEvents())...))
+		assert.Equal(t, totals, replayed.Totals)
+	})
+}
+
+// storedEvents returns the events with their data decoded from JSON, as
+// the event stream reads them.
+func storedEvents(t *testing.T, events []es.Event) []es.Event {
+	t.Helper()
+	stored := make([]es.Event, len(events))
+	for i, event := range events {
+		data, err := es.DecodeData[map[string]any](event)
+		require.NoError(t, err)
+		event.Data = data
+		stored[i] = event
+	}
+	return stored
+}
+
+func TestLoadProductChecks(t *testing.T) {
+	check, err := checkout.LoadProductChecks()
+	require.NoError(t, err)
+	assert.False(t, check, "Products should not be checked by default")
+
+	t.Setenv("CHECK_CART_PRODUCTS", "true")
+	check, err = checkout.LoadProductChecks()
+	require.NoError(t, err)
+	assert.True(t, check)
+
+	t.Setenv("CHECK_CART_PRODUCTS", "sometimes")
+	_, err = checkout.LoadProductChecks()
+	assert.Error(t, err)
+}
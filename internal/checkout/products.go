@@ -0,0 +1,75 @@
+package checkout
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Product is what a cart needs to know of a product in the catalog. Price
+// is in cents.
+type Product struct {
+	ID           int
+	Price        int64
+	Discontinued bool
+}
+
+// ProductCatalog is the port carts look products up through. It returns nil
+// for products that are not in the tenant's catalog.
+type ProductCatalog interface {
+	FindProduct(ctx context.Context, tenantID string, productID int) (*Product, error)
+}
+
+// LoadProductChecks reports whether CHECK_CART_PRODUCTS=true turns on the
+// catalog checks of items added to carts. They are off by default, so carts
+// keep working while the catalog is being filled.
+func LoadProductChecks() (bool, error) {
+	v := os.Getenv("CHECK_CART_PRODUCTS")
+	if v == "" {
+		return false, nil
+	}
+	check, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("parse CHECK_CART_PRODUCTS: %w", err)
+	}
+	return check, nil
+}
+
+// ProductLookup finds a product in the catalog of the cart's tenant.
+type ProductLookup func(productID int) (*Product, error)
+
+// UseProducts makes the cart reject items that are not products on sale
+// when they are added. Carts without it accept any item, which is how
+// events are replayed.
+func UseProducts(lookup ProductLookup) CartOption {
+	return func(c *CartAggregate) {
+		c.products = lookup
+	}
+}
+
+// checkProduct fails with ErrUnknownProduct or ErrProductDiscontinued for
+// items the cart may not take more units of.
+func (c *CartAggregate) checkProduct(itemID int) error {
+	if c.products == nil {
+		return nil
+	}
+	product, err := c.products(itemID)
+	if err != nil {
+		return err
+	}
+	if product == nil {
+		return fmt.Errorf("%w: %d", ErrUnknownProduct, itemID)
+	}
+	if product.Discontinued {
+		return fmt.Errorf("%w: %d", ErrProductDiscontinued, itemID)
+	}
+	return nil
+}
+
+// lookupIn binds the catalog to the context and tenant of a command.
+func lookupIn(ctx context.Context, catalog ProductCatalog, tenantID string) ProductLookup {
+	return func(productID int) (*Product, error) {
+		return catalog.FindProduct(ctx, tenantID, productID)
+	}
+}
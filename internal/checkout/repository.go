@@ -3,118 +3,162 @@ package checkout
 import (
 	"context"
 	"es/internal/es"
+	"fmt"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type CartRepository interface {
-	New(context.Context, int) (*CartAggregate, error)
-	Get(context.Context, int) (*CartAggregate, error)
+	New(ctx context.Context, tenantID string, cartID string, options ...CartOption) (*CartAggregate, error)
+	Get(ctx context.Context, tenantID string, cartID string) (*CartAggregate, error)
+	GetAsOf(ctx context.Context, tenantID string, cartID string, until es.EventRange) (*CartAggregate, error)
 	Save(context.Context, *CartAggregate) error
 }
 
 type PGCartRepository struct {
-	pool *pgxpool.Pool
+	stream *es.EventStream
 }
 
 func NewPGCartRepository(pool *pgxpool.Pool) *PGCartRepository {
 	return &PGCartRepository{
-		pool: pool,
+		stream: es.NewEventStream(pool),
 	}
 }
 
-func (r *PGCartRepository) New(ctx context.Context, cartID int) (*CartAggregate, error) {
-	cart := NewCartAggregate(cartID)
+func (r *PGCartRepository) New(ctx context.Context, tenantID string, cartID string, options ...CartOption) (*CartAggregate, error) {
+	cart := NewCartAggregate(cartID, append([]CartOption{ForTenant(tenantID)}, options...)...)
 	if err := cart.Init(); err != nil {
 		return nil, err
 	}
 	return cart, r.Save(ctx, cart)
 }
 
-func (r *PGCartRepository) Get(ctx context.Context, cartID int) (*CartAggregate, error) {
-	conn, err := r.pool.Acquire(ctx)
+func (r *PGCartRepository) Get(ctx context.Context, tenantID string, cartID string) (*CartAggregate, error) {
+	cart := NewCartAggregate(cartID, ForTenant(tenantID))
+
+	// Start from the latest snapshot if there is one, since the events it
+	// covers may have been archived.
+	snapshot, err := r.stream.GetSnapshot(ctx, tenantID, CartType, cartID)
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Release()
-
-	sql := `
-        SELECT
-        	position,
-        	event_type,
-         	aggregate_type,
-          	aggregate_id,
-         	at,
-          	version_id,
-           	data
-        FROM events
-        WHERE aggregate_id = $1 AND aggregate_type = $2
-        ORDER BY version_id ASC`
-
-	rows, err := conn.Query(ctx, sql, cartID, CartType)
+
+	fromVersion := 0
+	if snapshot != nil {
+		if err := cart.Restore(*snapshot); err != nil {
+			return nil, err
+		}
+		fromVersion = snapshot.VersionID
+	}
+
+	events, err := r.stream.GetAggregateEventsAfter(ctx, tenantID, CartType, cartID, fromVersion)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	events := []es.Event{}
-	for rows.Next() {
-		var e es.Event
-
-		if err := rows.Scan(
-			&e.Position,
-			&e.Type,
-			&e.AggregateType,
-			&e.AggregateID,
-			&e.At,
-			&e.VersionID,
-			&e.Data,
-		); err != nil {
+
+	if len(events) == 0 && snapshot == nil {
+		return nil, nil
+	}
+
+	if len(events) > 0 {
+		if err := cart.Apply(events...); err != nil {
+			return nil, err
+		}
+	}
+	cart.Commit()
+	return cart, nil
+}
+
+// GetAsOf rehydrates the cart from its events up to the given bounds only,
+// returning nil if the cart did not exist at that point. It returns
+// ErrHistoryArchived when the events needed have been archived.
+func (r *PGCartRepository) GetAsOf(ctx context.Context, tenantID string, cartID string, until es.EventRange) (*CartAggregate, error) {
+	cart := NewCartAggregate(cartID, ForTenant(tenantID))
+	until.AfterVersion = 0
+
+	// A snapshot can stand in for the history before it when the requested
+	// version is known to be at or beyond it.
+	if until.UntilVersion > 0 && until.Until.IsZero() {
+		snapshot, err := r.stream.GetSnapshot(ctx, tenantID, CartType, cartID)
+		if err != nil {
 			return nil, err
 		}
 
-		events = append(events, e)
+		if snapshot != nil && snapshot.VersionID <= until.UntilVersion {
+			if err := cart.Restore(*snapshot); err != nil {
+				return nil, err
+			}
+			until.AfterVersion = snapshot.VersionID
+		}
 	}
 
-	if err := rows.Err(); err != nil {
+	events, err := r.stream.GetAggregateEventsInRange(ctx, tenantID, CartType, cartID, until)
+	if err != nil {
 		return nil, err
 	}
 
-	if len(events) == 0 {
-		return nil, nil
+	if until.AfterVersion == 0 {
+		if len(events) == 0 {
+			return nil, r.checkArchived(ctx, tenantID, cartID)
+		}
+		if events[0].VersionID != 1 {
+			return nil, ErrHistoryArchived
+		}
 	}
 
-	cart := NewCartAggregate(cartID)
-	if err := cart.Apply(events...); err != nil {
-		return nil, err
+	if len(events) > 0 {
+		if err := cart.Apply(events...); err != nil {
+			return nil, err
+		}
 	}
 	cart.Commit()
 	return cart, nil
 }
 
-func (r *PGCartRepository) Save(ctx context.Context, cart *CartAggregate) error {
-	conn, err := r.pool.Acquire(ctx)
+// checkArchived tells apart a cart that did not exist yet at the requested
+// point from one whose early history has been archived.
+func (r *PGCartRepository) checkArchived(ctx context.Context, tenantID string, cartID string) error {
+	created, err := r.stream.GetAggregateEventsInRange(ctx, tenantID, CartType, cartID, es.EventRange{UntilVersion: 1})
 	if err != nil {
 		return err
 	}
-	defer conn.Release()
-
-	for _, event := range cart.UncommittedEvents() {
-		if err := event.Validate(); err != nil {
-			return err
-		}
+	if len(created) > 0 {
+		return nil
+	}
 
-		sql := `
-			INSERT INTO events (aggregate_id, aggregate_type, event_type, at, version_id, data)
-			VALUES ($1, $2, $3, $4, $5, $6)`
+	snapshot, err := r.stream.GetSnapshot(ctx, tenantID, CartType, cartID)
+	if err != nil {
+		return err
+	}
+	if snapshot != nil {
+		return ErrHistoryArchived
+	}
+	return nil
+}
 
-		_, err = conn.Exec(context.Background(), sql, event.AggregateID, event.AggregateType,
-			event.Type, event.At, event.VersionID, event.Data)
-		if err != nil {
-			return err
-		}
+func (r *PGCartRepository) Save(ctx context.Context, cart *CartAggregate) error {
+	if err := r.stream.Append(ctx, cart.UncommittedEvents()...); err != nil {
+		return err
 	}
 
 	cart.Commit()
 	return nil
 }
+
+// Snapshot stores a snapshot of the cart at its latest version.
+func (r *PGCartRepository) Snapshot(ctx context.Context, tenantID string, cartID string) error {
+	cart, err := r.Get(ctx, tenantID, cartID)
+	if err != nil {
+		return err
+	}
+	if cart == nil {
+		return fmt.Errorf("cart %s not found", cartID)
+	}
+
+	snapshot, err := cart.Snapshot()
+	if err != nil {
+		return err
+	}
+
+	return r.stream.SaveSnapshot(ctx, snapshot)
+}
@@ -1,7 +1,13 @@
 package checkout
 
 import (
+	"es/internal/authentication"
+	"es/internal/es"
+	"es/internal/openapi"
+	"es/internal/tenant"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -16,77 +22,231 @@ func NewRouteHandler(usecase *CheckoutUseCase) *RouteHandler {
 	}
 }
 
+var (
+	itemIDParam       = openapi.Parameter{Name: "itemID", Type: 0}
+	ifNoneMatchHeader = openapi.Parameter{
+		Name:        "If-None-Match",
+		Description: "ETag of the cart the client has; answered with 304 if the cart has not changed",
+	}
+	ifMatchHeader = openapi.Parameter{
+		Name:        "If-Match",
+		Description: "ETag of the cart the change is based on; answered with 412 if the cart has changed since",
+	}
+)
+
+// Operations documents the handlers of RouteHandler, which are deprecated
+// in favour of V2RouteHandler.
+var Operations = struct {
+	CreateCart, GetCartDetails, AddItem, RemoveItem, Checkout openapi.Operation
+}{
+	CreateCart: openapi.Operation{
+		Summary:    "Create a cart",
+		Tags:       []string{"cart (deprecated)"},
+		Deprecated: true,
+		Responses:  map[int]any{http.StatusCreated: CartAggregate{}},
+	},
+	GetCartDetails: openapi.Operation{
+		Summary:     "Get a cart",
+		Description: "With as_of and/or version, returns the read-only state of the cart at that point. Otherwise the ETag header carries the version of the cart and, while it is open, a hash of its totals.",
+		Tags:        []string{"cart (deprecated)"},
+		Deprecated:  true,
+		Query: []openapi.Parameter{
+			{Name: "as_of", Description: "RFC 3339 timestamp", Type: time.Time{}},
+			{Name: "version", Description: "Version of the cart", Type: 0},
+		},
+		Headers:   []openapi.Parameter{ifNoneMatchHeader},
+		Responses: map[int]any{http.StatusOK: historicalCart{}, http.StatusNotModified: nil},
+	},
+	AddItem: openapi.Operation{
+		Summary:    "Add an item to a cart",
+		Tags:       []string{"cart (deprecated)"},
+		Deprecated: true,
+		PathParams: []openapi.Parameter{itemIDParam},
+		Headers:    []openapi.Parameter{ifMatchHeader},
+		Responses:  map[int]any{http.StatusOK: CartAggregate{}},
+	},
+	RemoveItem: openapi.Operation{
+		Summary:    "Remove an item from a cart",
+		Tags:       []string{"cart (deprecated)"},
+		Deprecated: true,
+		PathParams: []openapi.Parameter{itemIDParam},
+		Headers:    []openapi.Parameter{ifMatchHeader},
+		Responses:  map[int]any{http.StatusOK: CartAggregate{}},
+	},
+	Checkout: openapi.Operation{
+		Summary:    "Check out a cart",
+		Tags:       []string{"cart (deprecated)"},
+		Deprecated: true,
+		Headers:    []openapi.Parameter{ifMatchHeader},
+		Responses:  map[int]any{http.StatusOK: CartAggregate{}},
+	},
+}
+
+// CreateCart starts a new cart and returns it with its server minted ID.
+func (h *RouteHandler) CreateCart(c *fiber.Ctx) error {
+	tenantID, err := tenant.FromRequest(c)
+
+	if err != nil {
+		return fiber.NewError(http.StatusForbidden, err.Error())
+	}
+
+	cart, err := h.usecase.CreateCart(c.Context(), tenantID, authentication.Subject(c), "")
+
+	if err != nil {
+		return err
+	}
+
+	setCartHeaders(c, cart)
+	return c.Status(http.StatusCreated).JSON(cart)
+}
+
 func (h *RouteHandler) GetCartDetails(c *fiber.Ctx) error {
-	cartID, err := c.ParamsInt("cartID")
+	tenantID, err := tenant.FromRequest(c)
 
 	if err != nil {
-		return c.Status(http.StatusBadRequest).SendString(err.Error())
+		return fiber.NewError(http.StatusForbidden, err.Error())
+	}
+
+	cartID := c.Params("cartID")
+
+	if c.Query("as_of") != "" || c.Query("version") != "" {
+		return h.getCartHistory(c, tenantID, cartID)
 	}
 
-	cart, err := h.usecase.GetCartDetails(c.Context(), cartID)
+	cart, err := h.usecase.GetCartDetails(c.Context(), tenantID, cartID)
 	if err != nil {
 		return err
 	}
 
-	return c.Status(http.StatusOK).JSON(cart)
+	return sendCart(c, cart)
+}
+
+// historicalCart is the read-only state of a cart at a past point.
+type historicalCart struct {
+	*CartAggregate
+	Version  int        `json:"version"`
+	AsOf     *time.Time `json:"as_of,omitempty"`
+	ReadOnly bool       `json:"read_only"`
+}
+
+func (h *RouteHandler) getCartHistory(c *fiber.Ctx, tenantID string, cartID string) error {
+	var until es.EventRange
+	var asOf *time.Time
+
+	if v := c.Query("as_of"); v != "" {
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return fiber.NewError(http.StatusBadRequest, "as_of must be an RFC 3339 timestamp")
+		}
+		until.Until = t
+		asOf = &t
+	}
+
+	if v := c.Query("version"); v != "" {
+		version, err := strconv.Atoi(v)
+		if err != nil || version <= 0 {
+			return fiber.NewError(http.StatusBadRequest, "version must be a positive integer")
+		}
+		until.UntilVersion = version
+	}
+
+	cart, err := h.usecase.GetCartHistory(c.Context(), tenantID, cartID, until)
+
+	if err != nil {
+		return err
+	}
+
+	return c.Status(http.StatusOK).JSON(historicalCart{
+		CartAggregate: cart,
+		Version:       cart.Version(),
+		AsOf:          asOf,
+		ReadOnly:      true,
+	})
 }
 
 func (h *RouteHandler) AddItem(c *fiber.Ctx) error {
-	cartID, err := c.ParamsInt("cartID")
+	tenantID, err := tenant.FromRequest(c)
 
 	if err != nil {
-		return c.Status(http.StatusBadRequest).SendString(err.Error())
+		return fiber.NewError(http.StatusForbidden, err.Error())
 	}
 
+	cartID := c.Params("cartID")
+
 	itemID, err := c.ParamsInt("itemID")
 
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "item ID must be an integer")
+	}
+
+	version, err := expectedVersion(c)
+
 	if err != nil {
 		return err
 	}
 
-	cart, err := h.usecase.AddItemToCart(c.Context(), cartID, itemID)
+	cart, err := h.usecase.AddItemToCart(c.Context(), tenantID, cartID, itemID, version)
 
 	if err != nil {
 		return err
 	}
 
+	setCartHeaders(c, cart)
 	return c.Status(http.StatusOK).JSON(cart)
 }
 
 func (h *RouteHandler) RemoveItem(c *fiber.Ctx) error {
-	cartID, err := c.ParamsInt("cartID")
+	tenantID, err := tenant.FromRequest(c)
 
 	if err != nil {
-		return c.Status(http.StatusBadRequest).SendString(err.Error())
+		return fiber.NewError(http.StatusForbidden, err.Error())
 	}
 
+	cartID := c.Params("cartID")
+
 	itemID, err := c.ParamsInt("itemID")
 
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "item ID must be an integer")
+	}
+
+	version, err := expectedVersion(c)
+
 	if err != nil {
 		return err
 	}
 
-	cart, err := h.usecase.RemoveItemFromCart(c.Context(), cartID, itemID)
+	cart, err := h.usecase.RemoveItemFromCart(c.Context(), tenantID, cartID, itemID, version)
 
 	if err != nil {
 		return err
 	}
 
+	setCartHeaders(c, cart)
 	return c.Status(http.StatusOK).JSON(cart)
 }
 
 func (h *RouteHandler) Checkout(c *fiber.Ctx) error {
-	cartID, err := c.ParamsInt("cartID")
+	tenantID, err := tenant.FromRequest(c)
 
 	if err != nil {
-		return c.Status(http.StatusBadRequest).SendString(err.Error())
+		return fiber.NewError(http.StatusForbidden, err.Error())
+	}
+
+	cartID := c.Params("cartID")
+
+	version, err := expectedVersion(c)
+
+	if err != nil {
+		return err
 	}
 
-	cart, err := h.usecase.Checkout(c.Context(), cartID)
+	cart, err := h.usecase.Checkout(c.Context(), tenantID, cartID, version)
 
 	if err != nil {
 		return err
 	}
 
+	setCartHeaders(c, cart)
 	return c.Status(http.StatusOK).JSON(cart)
 }
@@ -0,0 +1,100 @@
+package checkout_test
+
+import (
+	"context"
+	"encoding/json"
+	"es/internal/checkout"
+	"es/internal/es"
+	"es/internal/openapi"
+	"es/internal/openapi/openapitest"
+	"es/internal/problem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// historyRepository answers GetAsOf with the given cart or error and
+// records the bounds it was asked for.
+type historyRepository struct {
+	*memoryCartRepository
+	cart  *checkout.CartAggregate
+	err   error
+	until es.EventRange
+}
+
+func (r *historyRepository) GetAsOf(_ context.Context, _ string, _ string, until es.EventRange) (*checkout.CartAggregate, error) {
+	r.until = until
+	return r.cart, r.err
+}
+
+// newHistoryApp serves GET /cart/:cartID from the repository, checking its
+// responses against the OpenAPI document.
+func newHistoryApp(t *testing.T, repository *historyRepository) *fiber.App {
+	bus := es.NewCommandBus()
+	h := checkout.NewRouteHandler(checkout.NewCheckoutUseCase(repository, bus, nil))
+
+	app, doc := openapitest.NewApp(t, "Carts", openapi.ErrInvalidResponse)
+	doc.Group(app, "/cart").Get("/:cartID", checkout.Operations.GetCartDetails, h.GetCartDetails)
+	return app
+}
+
+func TestGetCartHistory(t *testing.T) {
+	get := func(t *testing.T, app *fiber.App, query string) *http.Response {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/cart/cart-1001?"+query, nil))
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("returns the cart at the requested point", func(t *testing.T) {
+		cart := newTestCartAggregate(t, "cart-1001")
+		require.NoError(t, cart.Add(42))
+		cart.Commit()
+		repository := &historyRepository{memoryCartRepository: &memoryCartRepository{}, cart: cart}
+
+		resp := get(t, newHistoryApp(t, repository), "as_of=2026-01-01T12:00:00Z&version=2")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var body struct {
+			Version  int       `json:"version"`
+			AsOf     time.Time `json:"as_of"`
+			ReadOnly bool      `json:"read_only"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, 2, body.Version)
+		assert.True(t, body.ReadOnly)
+		assert.Equal(t, time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC), body.AsOf.UTC())
+
+		assert.Equal(t, 2, repository.until.UntilVersion)
+		assert.True(t, repository.until.Until.Equal(body.AsOf))
+	})
+
+	t.Run("rejects malformed bounds", func(t *testing.T) {
+		repository := &historyRepository{memoryCartRepository: &memoryCartRepository{}}
+		app := newHistoryApp(t, repository)
+
+		for _, query := range []string{"as_of=yesterday", "as_of=2026-01-01", "version=two", "version=0", "version=-1"} {
+			assert.Equal(t, http.StatusBadRequest, get(t, app, query).StatusCode, query)
+		}
+	})
+
+	t.Run("unknown carts are not found", func(t *testing.T) {
+		repository := &historyRepository{memoryCartRepository: &memoryCartRepository{}}
+
+		resp := get(t, newHistoryApp(t, repository), "version=1")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("archived history is gone", func(t *testing.T) {
+		repository := &historyRepository{memoryCartRepository: &memoryCartRepository{}, err: checkout.ErrHistoryArchived}
+
+		resp := get(t, newHistoryApp(t, repository), "version=1")
+		assert.Equal(t, http.StatusGone, resp.StatusCode)
+		assert.Equal(t, problem.ContentType, resp.Header.Get(fiber.HeaderContentType))
+	})
+}
@@ -0,0 +1,428 @@
+package checkout
+
+import (
+	"bytes"
+	"encoding/json"
+	"es/internal/authentication"
+	"es/internal/openapi"
+	"es/internal/tenant"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// V2RouteHandler serves the /v2/carts API, which takes JSON bodies and only
+// changes carts on POST and DELETE. Changes to a cart are conditional on the
+// If-Match header, which the routes require with RequireIfMatch.
+type V2RouteHandler struct {
+	usecase *CheckoutUseCase
+}
+
+func NewV2RouteHandler(usecase *CheckoutUseCase) *V2RouteHandler {
+	return &V2RouteHandler{
+		usecase: usecase,
+	}
+}
+
+// requiredIfMatchHeader is the If-Match header of the v2 changes to a cart,
+// which require it.
+var requiredIfMatchHeader = openapi.Parameter{
+	Name:        ifMatchHeader.Name,
+	Description: ifMatchHeader.Description,
+	Required:    true,
+}
+
+// V2Operations documents the handlers of V2RouteHandler.
+var V2Operations = struct {
+	CreateCart, GetCart, AddItem, GetItem, SetItemQuantity, SetContents, RemoveItem, Checkout openapi.Operation
+}{
+	CreateCart: openapi.Operation{
+		Summary:         "Create a cart",
+		Description:     "The body is optional. The Location header names the new cart and the ETag header carries its version.",
+		Tags:            []string{"carts"},
+		Request:         CreateCartRequest{},
+		OptionalRequest: true,
+		Responses:       map[int]any{http.StatusCreated: CartAggregate{}},
+	},
+	GetCart: openapi.Operation{
+		Summary:     "Get a cart",
+		Description: "The ETag header carries the version of the cart and, while it is open, a hash of its totals.",
+		Tags:        []string{"carts"},
+		Headers:     []openapi.Parameter{ifNoneMatchHeader},
+		Responses:   map[int]any{http.StatusOK: CartAggregate{}, http.StatusNotModified: nil},
+	},
+	AddItem: openapi.Operation{
+		Summary:     "Add units of an item to a cart",
+		Description: "Answers 201 with a Location header naming the item in the cart if the item was not in it yet, and 200 if it was.",
+		Tags:        []string{"carts"},
+		Headers:     []openapi.Parameter{requiredIfMatchHeader},
+		Request:     AddItemRequest{},
+		Responses:   map[int]any{http.StatusCreated: CartAggregate{}, http.StatusOK: CartAggregate{}},
+	},
+	GetItem: openapi.Operation{
+		Summary:     "Get the units of an item in a cart",
+		Description: "The ETag header carries the version of the cart and, while it is open, a hash of its totals.",
+		Tags:        []string{"carts"},
+		PathParams:  []openapi.Parameter{itemIDParam},
+		Headers:     []openapi.Parameter{ifNoneMatchHeader},
+		Responses:   map[int]any{http.StatusOK: Line{}, http.StatusNotModified: nil},
+	},
+	SetItemQuantity: openapi.Operation{
+		Summary:     "Set the units of an item in a cart",
+		Description: "A quantity of 0 removes the item.",
+		Tags:        []string{"carts"},
+		PathParams:  []openapi.Parameter{itemIDParam},
+		Headers:     []openapi.Parameter{requiredIfMatchHeader},
+		Request:     SetQuantityRequest{},
+		Responses:   map[int]any{http.StatusOK: CartAggregate{}},
+	},
+	SetContents: openapi.Operation{
+		Summary:     "Replace the contents of a cart",
+		Description: "Every item changes in a single save; items not listed are removed.",
+		Tags:        []string{"carts"},
+		Headers:     []openapi.Parameter{requiredIfMatchHeader},
+		Request:     SetContentsRequest{},
+		Responses:   map[int]any{http.StatusOK: CartAggregate{}},
+	},
+	RemoveItem: openapi.Operation{
+		Summary:    "Remove every unit of an item from a cart",
+		Tags:       []string{"carts"},
+		PathParams: []openapi.Parameter{itemIDParam},
+		Headers:    []openapi.Parameter{requiredIfMatchHeader},
+		Responses:  map[int]any{http.StatusOK: CartAggregate{}},
+	},
+	Checkout: openapi.Operation{
+		Summary:   "Check out a cart",
+		Tags:      []string{"carts"},
+		Headers:   []openapi.Parameter{requiredIfMatchHeader},
+		Responses: map[int]any{http.StatusOK: CartAggregate{}},
+	},
+}
+
+// CreateCartRequest is the optional body of POST /v2/carts. Carts without
+// a region are priced in the default region.
+type CreateCartRequest struct {
+	Region string `json:"region,omitempty"`
+}
+
+// AddItemRequest is the body of POST /v2/carts/:cartID/items. Quantity
+// defaults to 1.
+type AddItemRequest struct {
+	ItemID   int  `json:"item_id" openapi:"required,minimum=1"`
+	Quantity *int `json:"quantity,omitempty" openapi:"minimum=1,maximum=100"`
+}
+
+// SetQuantityRequest is the body of PUT /v2/carts/:cartID/items/:itemID.
+type SetQuantityRequest struct {
+	Quantity *int `json:"quantity" openapi:"required,minimum=0,maximum=100"`
+}
+
+// SetContentsRequest is the body of PUT /v2/carts/:cartID/items.
+type SetContentsRequest struct {
+	Items []Line `json:"items" openapi:"required"`
+}
+
+func (r AddItemRequest) validate() error {
+	if r.ItemID <= 0 {
+		return fmt.Errorf("%w: item_id must be a positive integer", ErrInvalidItem)
+	}
+	if r.Quantity != nil && (*r.Quantity < 1 || *r.Quantity > MaxQuantity) {
+		return fmt.Errorf("%w: must be between 1 and %d", ErrInvalidQuantity, MaxQuantity)
+	}
+	return nil
+}
+
+func (r SetQuantityRequest) validate() error {
+	if r.Quantity == nil {
+		return fmt.Errorf("%w: quantity is required", ErrInvalidQuantity)
+	}
+	if *r.Quantity < 0 || *r.Quantity > MaxQuantity {
+		return fmt.Errorf("%w: must be between 0 and %d", ErrInvalidQuantity, MaxQuantity)
+	}
+	return nil
+}
+
+func (r SetContentsRequest) validate() error {
+	if r.Items == nil {
+		return fmt.Errorf("%w: items is required", ErrInvalidItem)
+	}
+	return Lines(r.Items).validate()
+}
+
+// CreateCart starts a new cart and returns it with a Location header naming
+// the new cart.
+func (h *V2RouteHandler) CreateCart(c *fiber.Ctx) error {
+	tenantID, err := tenant.FromRequest(c)
+
+	if err != nil {
+		return fiber.NewError(http.StatusForbidden, err.Error())
+	}
+
+	var req CreateCartRequest
+	if len(c.Body()) > 0 {
+		if err := decodeBody(c, &req); err != nil {
+			return err
+		}
+	}
+
+	cart, err := h.usecase.CreateCart(c.Context(), tenantID, authentication.Subject(c), req.Region)
+
+	if err != nil {
+		return err
+	}
+
+	setCartHeaders(c, cart)
+	c.Location(childPath(c.Path(), cart.ID))
+	return c.Status(http.StatusCreated).JSON(cart)
+}
+
+func (h *V2RouteHandler) GetCart(c *fiber.Ctx) error {
+	tenantID, err := tenant.FromRequest(c)
+
+	if err != nil {
+		return fiber.NewError(http.StatusForbidden, err.Error())
+	}
+
+	cart, err := h.usecase.GetCartDetails(c.Context(), tenantID, c.Params("cartID"))
+
+	if err != nil {
+		return err
+	}
+
+	return sendCart(c, cart)
+}
+
+// AddItem adds the units of an item given in the JSON body to a cart and
+// returns the cart. If the item was not in the cart yet, it answers 201 with
+// a Location header naming the item in it.
+func (h *V2RouteHandler) AddItem(c *fiber.Ctx) error {
+	tenantID, err := tenant.FromRequest(c)
+
+	if err != nil {
+		return fiber.NewError(http.StatusForbidden, err.Error())
+	}
+
+	var req AddItemRequest
+	if err := decodeBody(c, &req); err != nil {
+		return err
+	}
+	if err := req.validate(); err != nil {
+		return err
+	}
+
+	quantity := 1
+	if req.Quantity != nil {
+		quantity = *req.Quantity
+	}
+
+	version, err := expectedVersion(c)
+
+	if err != nil {
+		return err
+	}
+
+	cart, err := h.usecase.AddItemsToCart(c.Context(), tenantID, c.Params("cartID"), req.ItemID, quantity, version)
+
+	if err != nil {
+		return err
+	}
+
+	setCartHeaders(c, cart)
+	// Adding only ever raises the units, so the line is new if it holds
+	// exactly the units just added.
+	if cart.Contents.Quantity(req.ItemID) != quantity {
+		return c.Status(http.StatusOK).JSON(cart)
+	}
+	c.Location(childPath(c.Path(), strconv.Itoa(req.ItemID)))
+	return c.Status(http.StatusCreated).JSON(cart)
+}
+
+// GetItem returns the line of an item in a cart, which AddItem names in its
+// Location header.
+func (h *V2RouteHandler) GetItem(c *fiber.Ctx) error {
+	tenantID, err := tenant.FromRequest(c)
+
+	if err != nil {
+		return fiber.NewError(http.StatusForbidden, err.Error())
+	}
+
+	itemID, err := c.ParamsInt("itemID")
+
+	if err != nil || itemID <= 0 {
+		return fiber.NewError(http.StatusBadRequest, "item ID must be a positive integer")
+	}
+
+	cart, err := h.usecase.GetCartDetails(c.Context(), tenantID, c.Params("cartID"))
+
+	if err != nil {
+		return err
+	}
+
+	quantity := cart.Contents.Quantity(itemID)
+	if quantity == 0 {
+		return ErrItemNotInCart
+	}
+
+	setCartHeaders(c, cart)
+	if notModified(c, cart) {
+		return c.SendStatus(http.StatusNotModified)
+	}
+	return c.Status(http.StatusOK).JSON(Line{ItemID: itemID, Quantity: quantity})
+}
+
+// SetItemQuantity sets the units of an item in a cart to the quantity in
+// the JSON body.
+func (h *V2RouteHandler) SetItemQuantity(c *fiber.Ctx) error {
+	tenantID, err := tenant.FromRequest(c)
+
+	if err != nil {
+		return fiber.NewError(http.StatusForbidden, err.Error())
+	}
+
+	itemID, err := c.ParamsInt("itemID")
+
+	if err != nil || itemID <= 0 {
+		return fiber.NewError(http.StatusBadRequest, "item ID must be a positive integer")
+	}
+
+	var req SetQuantityRequest
+	if err := decodeBody(c, &req); err != nil {
+		return err
+	}
+	if err := req.validate(); err != nil {
+		return err
+	}
+
+	version, err := expectedVersion(c)
+
+	if err != nil {
+		return err
+	}
+
+	cart, err := h.usecase.SetItemQuantity(c.Context(), tenantID, c.Params("cartID"), itemID, *req.Quantity, version)
+
+	if err != nil {
+		return err
+	}
+
+	setCartHeaders(c, cart)
+	return c.Status(http.StatusOK).JSON(cart)
+}
+
+// SetContents replaces the contents of a cart with the items of the JSON
+// body at once.
+func (h *V2RouteHandler) SetContents(c *fiber.Ctx) error {
+	tenantID, err := tenant.FromRequest(c)
+
+	if err != nil {
+		return fiber.NewError(http.StatusForbidden, err.Error())
+	}
+
+	var req SetContentsRequest
+	if err := decodeBody(c, &req); err != nil {
+		return err
+	}
+	if err := req.validate(); err != nil {
+		return err
+	}
+
+	version, err := expectedVersion(c)
+
+	if err != nil {
+		return err
+	}
+
+	cart, err := h.usecase.SetCartContents(c.Context(), tenantID, c.Params("cartID"), req.Items, version)
+
+	if err != nil {
+		return err
+	}
+
+	setCartHeaders(c, cart)
+	return c.Status(http.StatusOK).JSON(cart)
+}
+
+// RemoveItem removes every unit of an item from a cart.
+func (h *V2RouteHandler) RemoveItem(c *fiber.Ctx) error {
+	tenantID, err := tenant.FromRequest(c)
+
+	if err != nil {
+		return fiber.NewError(http.StatusForbidden, err.Error())
+	}
+
+	itemID, err := c.ParamsInt("itemID")
+
+	if err != nil || itemID <= 0 {
+		return fiber.NewError(http.StatusBadRequest, "item ID must be a positive integer")
+	}
+
+	version, err := expectedVersion(c)
+
+	if err != nil {
+		return err
+	}
+
+	cart, err := h.usecase.RemoveAllOfItem(c.Context(), tenantID, c.Params("cartID"), itemID, version)
+
+	if err != nil {
+		return err
+	}
+
+	setCartHeaders(c, cart)
+	return c.Status(http.StatusOK).JSON(cart)
+}
+
+func (h *V2RouteHandler) Checkout(c *fiber.Ctx) error {
+	tenantID, err := tenant.FromRequest(c)
+
+	if err != nil {
+		return fiber.NewError(http.StatusForbidden, err.Error())
+	}
+
+	version, err := expectedVersion(c)
+
+	if err != nil {
+		return err
+	}
+
+	cart, err := h.usecase.Checkout(c.Context(), tenantID, c.Params("cartID"), version)
+
+	if err != nil {
+		return err
+	}
+
+	setCartHeaders(c, cart)
+	return c.Status(http.StatusOK).JSON(cart)
+}
+
+// Deprecated marks the responses of routes replaced by the successor API
+// with a Deprecation header and a Link to the successor.
+func Deprecated(successor string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set("Deprecation", "true")
+		c.Set(fiber.HeaderLink, fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+		return c.Next()
+	}
+}
+
+// decodeBody decodes the JSON body of the request into v, rejecting other
+// media types and unknown fields.
+func decodeBody(c *fiber.Ctx, v any) error {
+	if !strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEApplicationJSON) {
+		return fiber.NewError(http.StatusUnsupportedMediaType, "request body must be application/json")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(c.Body()))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fiber.NewError(http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+	}
+	return nil
+}
+
+func childPath(path string, id string) string {
+	return strings.TrimSuffix(path, "/") + "/" + id
+}
@@ -0,0 +1,362 @@
+package checkout_test
+
+import (
+	"encoding/json"
+	"es/internal/checkout"
+	"es/internal/es"
+	"es/internal/openapi"
+	"es/internal/openapi/openapitest"
+	"es/internal/problem"
+	"es/internal/tenant"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newV2App serves the v2 routes, checking requests and responses against
+// their OpenAPI document and failing the test on the given mismatches.
+func newV2App(t *testing.T, failOn ...error) *fiber.App {
+	return newPricedV2App(t, memoryCatalog{
+		42: {ID: 42, Price: 1250},
+		7:  {ID: 7, Price: 399},
+		13: {ID: 13, Price: 500, Discontinued: true},
+	}, failOn...)
+}
+
+// newPricedV2App is newV2App selling the given products.
+func newPricedV2App(t *testing.T, products memoryCatalog, failOn ...error) *fiber.App {
+	bus := es.NewCommandBus()
+	repository := &memoryCartRepository{carts: map[string]*checkout.CartAggregate{}}
+	pricer := checkout.NewPricer(products, checkout.PricingConfig{
+		DefaultRegion: "DE",
+		Regions: map[string][]checkout.TaxRule{
+			"DE": {{Name: "VAT", Rate: 1900}},
+			"CH": {{Name: "VAT", Rate: 810}},
+		},
+	})
+	checkout.RegisterCommandHandlers(bus, repository, products, pricer)
+	h := checkout.NewV2RouteHandler(checkout.NewCheckoutUseCase(repository, bus, pricer))
+
+	app, doc := openapitest.NewApp(t, "Carts", failOn...)
+	// X-Tenant makes requests as another tenant.
+	carts := doc.Group(app, "/v2/carts", func(c *fiber.Ctx) error {
+		tenant.Set(c, c.Get("X-Tenant", "store-a"))
+		return c.Next()
+	})
+	carts.Post("/", checkout.V2Operations.CreateCart, h.CreateCart)
+	carts.Get("/:cartID", checkout.V2Operations.GetCart, h.GetCart)
+	carts.Get("/:cartID/items/:itemID", checkout.V2Operations.GetItem, h.GetItem)
+	carts.Post("/:cartID/items", checkout.V2Operations.AddItem, checkout.RequireIfMatch, h.AddItem)
+	carts.Put("/:cartID/items", checkout.V2Operations.SetContents, checkout.RequireIfMatch, h.SetContents)
+	carts.Put("/:cartID/items/:itemID", checkout.V2Operations.SetItemQuantity, checkout.RequireIfMatch, h.SetItemQuantity)
+	carts.Delete("/:cartID/items/:itemID", checkout.V2Operations.RemoveItem, checkout.RequireIfMatch, h.RemoveItem)
+	carts.Post("/:cartID/checkout", checkout.V2Operations.Checkout, checkout.RequireIfMatch, h.Checkout)
+	return app
+}
+
+// send makes a request with the given If-Match header, unless it is empty,
+// and decodes the cart of successful responses.
+func send(t *testing.T, app *fiber.App, method string, path string, ifMatch string, body string) (*http.Response, checkout.CartAggregate) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+
+	var cart checkout.CartAggregate
+	if resp.StatusCode < 300 {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&cart))
+	}
+	return resp, cart
+}
+
+func TestV2Routes(t *testing.T) {
+	t.Run("create a cart", func(t *testing.T) {
+		resp, cart := send(t, newV2App(t), http.MethodPost, "/v2/carts", "", "")
+
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "/v2/carts/"+cart.ID, resp.Header.Get("Location"))
+	})
+
+	t.Run("add and remove items", func(t *testing.T) {
+		app := newV2App(t)
+		resp, cart := send(t, app, http.MethodPost, "/v2/carts", "", "")
+		assert.Regexp(t, `^"1-[0-9a-f]{16}"$`, resp.Header.Get("ETag"))
+		assert.Equal(t, "1", resp.Header.Get("X-Event-Position"))
+
+		resp, cart = send(t, app, http.MethodPost, "/v2/carts/"+cart.ID+"/items", resp.Header.Get("ETag"), `{"item_id": 42, "quantity": 3}`)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "/v2/carts/"+cart.ID+"/items/42", resp.Header.Get("Location"))
+		assert.Regexp(t, `^"2-[0-9a-f]{16}"$`, resp.Header.Get("ETag"), "Adding several units should take a single event")
+		assert.Equal(t, "2", resp.Header.Get("X-Event-Position"))
+		assert.Equal(t, checkout.Lines{{ItemID: 42, Quantity: 3}}, cart.Contents)
+
+		resp, cart = send(t, app, http.MethodPost, "/v2/carts/"+cart.ID+"/items", resp.Header.Get("ETag"), `{"item_id": 7}`)
+		assert.Equal(t, checkout.Lines{{ItemID: 42, Quantity: 3}, {ItemID: 7, Quantity: 1}}, cart.Contents)
+
+		resp, cart = send(t, app, http.MethodDelete, "/v2/carts/"+cart.ID+"/items/42", resp.Header.Get("ETag"), "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, checkout.Lines{{ItemID: 7, Quantity: 1}}, cart.Contents)
+
+		resp, cart = send(t, app, http.MethodPost, "/v2/carts/"+cart.ID+"/checkout", resp.Header.Get("ETag"), "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, `"5"`, resp.Header.Get("ETag"))
+		assert.True(t, cart.CheckedOut)
+	})
+
+	t.Run("only new items are created", func(t *testing.T) {
+		app := newV2App(t)
+		resp, cart := send(t, app, http.MethodPost, "/v2/carts", "", "")
+
+		resp, cart = send(t, app, http.MethodPost, "/v2/carts/"+cart.ID+"/items", resp.Header.Get("ETag"), `{"item_id": 42}`)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		location := resp.Header.Get("Location")
+
+		get, err := app.Test(httptest.NewRequest(http.MethodGet, location, nil))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, get.StatusCode)
+		assert.Equal(t, resp.Header.Get("ETag"), get.Header.Get("ETag"))
+		var line checkout.Line
+		require.NoError(t, json.NewDecoder(get.Body).Decode(&line))
+		assert.Equal(t, checkout.Line{ItemID: 42, Quantity: 1}, line)
+
+		resp, cart = send(t, app, http.MethodPost, "/v2/carts/"+cart.ID+"/items", resp.Header.Get("ETag"), `{"item_id": 42, "quantity": 2}`)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Location"))
+		assert.Equal(t, checkout.Lines{{ItemID: 42, Quantity: 3}}, cart.Contents)
+
+		get, err = app.Test(httptest.NewRequest(http.MethodGet, "/v2/carts/"+cart.ID+"/items/7", nil))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, get.StatusCode)
+	})
+
+	t.Run("set item quantities and cart contents", func(t *testing.T) {
+		app := newV2App(t)
+		resp, cart := send(t, app, http.MethodPost, "/v2/carts", "", "")
+
+		resp, cart = send(t, app, http.MethodPut, "/v2/carts/"+cart.ID+"/items/42", resp.Header.Get("ETag"), `{"quantity": 10}`)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Regexp(t, `^"2-[0-9a-f]{16}"$`, resp.Header.Get("ETag"))
+		assert.Equal(t, checkout.Lines{{ItemID: 42, Quantity: 10}}, cart.Contents)
+
+		resp, cart = send(t, app, http.MethodPut, "/v2/carts/"+cart.ID+"/items", resp.Header.Get("ETag"),
+			`{"items": [{"item_id": 7, "quantity": 2}, {"item_id": 42, "quantity": 4}]}`)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Regexp(t, `^"4-[0-9a-f]{16}"$`, resp.Header.Get("ETag"))
+		assert.Equal(t, checkout.Lines{{ItemID: 42, Quantity: 4}, {ItemID: 7, Quantity: 2}}, cart.Contents)
+
+		resp, cart = send(t, app, http.MethodPut, "/v2/carts/"+cart.ID+"/items/42", resp.Header.Get("ETag"), `{"quantity": 0}`)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, checkout.Lines{{ItemID: 7, Quantity: 2}}, cart.Contents)
+
+		resp, _ = send(t, app, http.MethodPut, "/v2/carts/"+cart.ID+"/items", resp.Header.Get("ETag"),
+			`{"items": [{"item_id": 7, "quantity": 1}, {"item_id": 7, "quantity": 1}]}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	})
+
+	t.Run("reject products not on sale", func(t *testing.T) {
+		app := newV2App(t)
+		_, cart := send(t, app, http.MethodPost, "/v2/carts", "", "")
+
+		resp, _ := send(t, app, http.MethodPost, "/v2/carts/"+cart.ID+"/items", "*", `{"item_id": 99}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+		resp, _ = send(t, app, http.MethodPut, "/v2/carts/"+cart.ID+"/items/13", "*", `{"quantity": 1}`)
+		assert.Equal(t, http.StatusGone, resp.StatusCode)
+	})
+
+	t.Run("price carts in their region", func(t *testing.T) {
+		app := newV2App(t)
+		resp, cart := send(t, app, http.MethodPost, "/v2/carts", "", `{"region": "CH"}`)
+		assert.Equal(t, "CH", cart.Region)
+
+		resp, _ = send(t, app, http.MethodPut, "/v2/carts/"+cart.ID+"/items", resp.Header.Get("ETag"),
+			`{"items": [{"item_id": 42, "quantity": 2}, {"item_id": 7, "quantity": 1}]}`)
+		resp, cart = send(t, app, http.MethodGet, "/v2/carts/"+cart.ID, "", "")
+		require.NotNil(t, cart.Totals)
+		assert.Equal(t, []checkout.PricedLine{
+			{ItemID: 42, Quantity: 2, UnitPrice: 1250, Total: 2500},
+			{ItemID: 7, Quantity: 1, UnitPrice: 399, Total: 399},
+		}, cart.Totals.Lines)
+		assert.Equal(t, int64(2899), cart.Totals.Subtotal)
+		assert.Equal(t, []checkout.Tax{{Name: "VAT", Rate: 810, Amount: 235}}, cart.Totals.Taxes)
+		assert.Equal(t, int64(3134), cart.Totals.Total)
+
+		_, cart = send(t, app, http.MethodPost, "/v2/carts/"+cart.ID+"/checkout", resp.Header.Get("ETag"), "")
+		assert.Equal(t, int64(3134), cart.Totals.Total)
+
+		resp, _ = send(t, app, http.MethodPost, "/v2/carts", "", `{"region": "XX"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	})
+
+	t.Run("reject invalid item requests", func(t *testing.T) {
+		app := newV2App(t, openapi.ErrInvalidResponse)
+		_, cart := send(t, app, http.MethodPost, "/v2/carts", "", "")
+		path := "/v2/carts/" + cart.ID + "/items"
+
+		for body, expected := range map[string]problem.Problem{
+			`{"quantity": 1}`: {
+				Status: http.StatusUnprocessableEntity,
+				Detail: "invalid item: item_id must be a positive integer",
+			},
+			`{"item_id": 42, "quantity": 0}`: {
+				Status: http.StatusUnprocessableEntity,
+				Detail: "invalid quantity: must be between 1 and 100",
+			},
+			`{"item_id": 42, "colour": "red"}`: {
+				Status: http.StatusBadRequest,
+				Detail: `invalid request body: json: unknown field "colour"`,
+			},
+		} {
+			resp, _ := send(t, app, http.MethodPost, path, "*", body)
+			assert.Equal(t, expected.Status, resp.StatusCode, body)
+			assert.Equal(t, problem.ContentType, resp.Header.Get("Content-Type"))
+
+			var got problem.Problem
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+			assert.Equal(t, expected.Detail, got.Detail)
+		}
+
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader("item_id=42"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("If-Match", "*")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+	})
+
+	t.Run("unknown cart", func(t *testing.T) {
+		resp, _ := send(t, newV2App(t), http.MethodPost, "/v2/carts/cart-9999/items", "*", `{"item_id": 42}`)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("carts of other tenants are not found", func(t *testing.T) {
+		app := newV2App(t)
+		resp, cart := send(t, app, http.MethodPost, "/v2/carts", "", "")
+		etag := resp.Header.Get("ETag")
+
+		for _, req := range []*http.Request{
+			httptest.NewRequest(http.MethodGet, "/v2/carts/"+cart.ID, nil),
+			httptest.NewRequest(http.MethodPost, "/v2/carts/"+cart.ID+"/checkout", nil),
+		} {
+			req.Header.Set("X-Tenant", "store-b")
+			req.Header.Set("If-Match", etag)
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusNotFound, resp.StatusCode, req.Method)
+		}
+
+		resp, cart = send(t, app, http.MethodGet, "/v2/carts/"+cart.ID, "", "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.False(t, cart.CheckedOut)
+	})
+
+	t.Run("checked out cart", func(t *testing.T) {
+		app := newV2App(t)
+		_, cart := send(t, app, http.MethodPost, "/v2/carts", "", "")
+		send(t, app, http.MethodPost, "/v2/carts/"+cart.ID+"/checkout", "*", "")
+
+		resp, _ := send(t, app, http.MethodPost, "/v2/carts/"+cart.ID+"/checkout", "*", "")
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+		resp, _ = send(t, app, http.MethodPost, "/v2/carts/"+cart.ID+"/items", "*", `{"item_id": 42}`)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+
+	t.Run("conditional reads", func(t *testing.T) {
+		products := memoryCatalog{42: {ID: 42, Price: 1250}}
+		app := newPricedV2App(t, products)
+		_, cart := send(t, app, http.MethodPost, "/v2/carts", "", "")
+		path := "/v2/carts/" + cart.ID
+
+		get := func(ifNoneMatch string) *http.Response {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			if ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", ifNoneMatch)
+			}
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			return resp
+		}
+
+		resp := get("")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		etag := resp.Header.Get("ETag")
+		assert.Regexp(t, `^"1-[0-9a-f]{16}"$`, etag)
+
+		for _, ifNoneMatch := range []string{etag, "W/" + etag, `"0", ` + etag, "*"} {
+			resp = get(ifNoneMatch)
+			assert.Equal(t, http.StatusNotModified, resp.StatusCode, ifNoneMatch)
+			assert.Equal(t, etag, resp.Header.Get("ETag"))
+		}
+		assert.Equal(t, http.StatusOK, get(`"1"`).StatusCode, "The version alone should not match a priced cart")
+
+		resp, _ = send(t, app, http.MethodPost, path+"/items", etag, `{"item_id": 42}`)
+		etag = resp.Header.Get("ETag")
+		resp = get(etag)
+		assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+		products[42].Price = 1500
+		resp = get(etag)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "Price changes should change the ETag of open carts")
+		assert.Regexp(t, `^"2-[0-9a-f]{16}"$`, resp.Header.Get("ETag"))
+		assert.NotEqual(t, etag, resp.Header.Get("ETag"))
+
+		resp, _ = send(t, app, http.MethodPost, path+"/checkout", etag, "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "If-Match should compare the version only")
+		assert.Equal(t, `"3"`, resp.Header.Get("ETag"), "Checked out carts keep their totals")
+	})
+
+	t.Run("conditional changes", func(t *testing.T) {
+		app := newV2App(t, openapi.ErrInvalidResponse)
+		_, cart := send(t, app, http.MethodPost, "/v2/carts", "", "")
+		path := "/v2/carts/" + cart.ID
+
+		resp, _ := send(t, app, http.MethodPost, path+"/items", "", `{"item_id": 42}`)
+		assert.Equal(t, http.StatusPreconditionRequired, resp.StatusCode)
+
+		resp, _ = send(t, app, http.MethodPost, path+"/items", `"1"`, `{"item_id": 42}`)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		// Another client changed the cart since version 1.
+		for ifMatch, status := range map[string]int{
+			`"1"`:      http.StatusPreconditionFailed,
+			`W/"2"`:    http.StatusPreconditionFailed,
+			`"abc"`:    http.StatusPreconditionFailed,
+			`"1", "2"`: http.StatusBadRequest,
+		} {
+			resp, _ = send(t, app, http.MethodPost, path+"/checkout", ifMatch, "")
+			assert.Equal(t, status, resp.StatusCode, ifMatch)
+		}
+
+		resp, cart = send(t, app, http.MethodGet, path, "", "")
+		assert.Equal(t, checkout.Lines{{ItemID: 42, Quantity: 1}}, cart.Contents)
+		assert.False(t, cart.CheckedOut)
+
+		resp, cart = send(t, app, http.MethodPost, path+"/checkout", resp.Header.Get("ETag"), "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.True(t, cart.CheckedOut)
+	})
+
+	t.Run("deprecated routes link to their successor", func(t *testing.T) {
+		app := fiber.New()
+		app.Get("/cart/:cartID", checkout.Deprecated("/v2/carts"), func(c *fiber.Ctx) error {
+			return c.SendStatus(http.StatusOK)
+		})
+
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/cart/cart-1001", nil))
+		require.NoError(t, err)
+		assert.Equal(t, "true", resp.Header.Get("Deprecation"))
+		assert.Equal(t, `</v2/carts>; rel="successor-version"`, resp.Header.Get("Link"))
+	})
+}
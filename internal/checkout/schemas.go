@@ -0,0 +1,88 @@
+package checkout
+
+import "es/internal/es"
+
+const itemSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"properties": {
+		"item_id": {"type": "integer", "minimum": 1}
+	},
+	"required": ["item_id"]
+}`
+
+const itemQuantityChangedSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"properties": {
+		"item_id": {"type": "integer", "minimum": 1},
+		"quantity": {"type": "integer", "minimum": 0},
+		"previous_quantity": {"type": "integer", "minimum": 0}
+	},
+	"required": ["item_id", "quantity", "previous_quantity"]
+}`
+
+const cartCreatedSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"properties": {
+		"owner": {"type": "string"},
+		"region": {"type": "string"}
+	}
+}`
+
+const cartCheckedOutSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"properties": {
+		"totals": {
+			"type": "object",
+			"properties": {
+				"region": {"type": "string"},
+				"lines": {
+					"type": "array",
+					"items": {
+						"type": "object",
+						"properties": {
+							"item_id": {"type": "integer", "minimum": 1},
+							"quantity": {"type": "integer", "minimum": 1},
+							"unit_price": {"type": "integer", "minimum": 0},
+							"total": {"type": "integer", "minimum": 0}
+						},
+						"required": ["item_id", "quantity", "unit_price", "total"]
+					}
+				},
+				"subtotal": {"type": "integer", "minimum": 0},
+				"taxes": {
+					"type": "array",
+					"items": {
+						"type": "object",
+						"properties": {
+							"name": {"type": "string"},
+							"rate": {"type": "integer", "minimum": 0},
+							"amount": {"type": "integer", "minimum": 0}
+						},
+						"required": ["name", "rate", "amount"]
+					}
+				},
+				"tax": {"type": "integer", "minimum": 0},
+				"total": {"type": "integer", "minimum": 0}
+			},
+			"required": ["lines", "subtotal", "taxes", "tax", "total"]
+		}
+	}
+}`
+
+const emptySchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object"
+}`
+
+func init() {
+	es.MustRegisterSchema(CartCreated, cartCreatedSchema)
+	es.MustRegisterSchema(ItemAddedToCart, itemSchema)
+	es.MustRegisterSchema(ItemRemovedFromCart, itemSchema)
+	es.MustRegisterSchema(ItemQuantityChanged, itemQuantityChangedSchema)
+	es.MustRegisterSchema(CartCheckedOut, cartCheckedOutSchema)
+	es.MustRegisterSchema(CartExpired, emptySchema)
+}
@@ -1,83 +1,126 @@
 package checkout
 
-import "context"
-
+import (
+	"context"
+	"es/internal/es"
+)
+
+// CheckoutUseCase reads carts from the repository and changes them by
+// dispatching commands on the bus. Open carts it returns carry their
+// totals at the current prices, unless pricer is nil.
 type CheckoutUseCase struct {
 	repository CartRepository
+	bus        *es.CommandBus
+	pricer     *Pricer
 }
 
-func NewCheckoutUseCase(repository CartRepository) *CheckoutUseCase {
+func NewCheckoutUseCase(repository CartRepository, bus *es.CommandBus, pricer *Pricer) *CheckoutUseCase {
 	return &CheckoutUseCase{
 		repository: repository,
+		bus:        bus,
+		pricer:     pricer,
 	}
 }
 
-func (u *CheckoutUseCase) GetCartDetails(ctx context.Context, cartID int) (*CartAggregate, error) {
-	cart, err := u.repository.Get(ctx, cartID)
+// CreateCart starts a new, empty cart owned by the given user and priced
+// in the given region, or the default region if it is empty.
+func (u *CheckoutUseCase) CreateCart(ctx context.Context, tenantID string, owner string, region string) (*CartAggregate, error) {
+	return u.dispatch(ctx, CreateCart{TenantID: tenantID, Owner: owner, Region: region})
+}
+
+func (u *CheckoutUseCase) GetCartDetails(ctx context.Context, tenantID string, cartID string) (*CartAggregate, error) {
+	cart, err := u.repository.Get(ctx, tenantID, cartID)
 
 	if err != nil {
 		return nil, err
 	}
 
 	if cart == nil {
-		newCart, err := u.repository.New(ctx, cartID)
-		if err != nil {
-			return nil, err
-		}
-		cart = newCart
+		return nil, ErrCartNotFound
 	}
 
-	return cart, nil
+	return u.price(ctx, cart)
 }
 
-func (u *CheckoutUseCase) AddItemToCart(ctx context.Context, cartID int, itemID int) (*CartAggregate, error) {
-	cart, err := u.repository.Get(ctx, cartID)
+// GetCartHistory returns the cart as it was at the end of the given range.
+// The returned cart must only be read, never saved.
+func (u *CheckoutUseCase) GetCartHistory(ctx context.Context, tenantID string, cartID string, until es.EventRange) (*CartAggregate, error) {
+	cart, err := u.repository.GetAsOf(ctx, tenantID, cartID, until)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if err := cart.Add(itemID); err != nil {
-		return nil, err
-	}
-
-	if err := u.repository.Save(ctx, cart); err != nil {
-		return nil, err
+	if cart == nil {
+		return nil, ErrCartNotFound
 	}
 
 	return cart, nil
 }
 
-func (u *CheckoutUseCase) RemoveItemFromCart(ctx context.Context, cartID int, itemID int) (*CartAggregate, error) {
-	cart, err := u.repository.Get(ctx, cartID)
+// AddItemToCart adds an item to a cart. The cart must be at expectedVersion
+// unless it is zero, as for the other changes below.
+func (u *CheckoutUseCase) AddItemToCart(ctx context.Context, tenantID string, cartID string, itemID int, expectedVersion int) (*CartAggregate, error) {
+	return u.dispatch(ctx, AddItem{TenantID: tenantID, CartID: cartID, ItemID: itemID, ExpectedVersion: expectedVersion})
+}
 
-	if err != nil {
-		return nil, err
-	}
+// AddItemsToCart adds quantity units of an item to a cart at once.
+func (u *CheckoutUseCase) AddItemsToCart(ctx context.Context, tenantID string, cartID string, itemID int, quantity int, expectedVersion int) (*CartAggregate, error) {
+	return u.dispatch(ctx, AddItem{TenantID: tenantID, CartID: cartID, ItemID: itemID, Quantity: quantity, ExpectedVersion: expectedVersion})
+}
 
-	if err := cart.Remove(itemID); err != nil {
-		return nil, err
-	}
+func (u *CheckoutUseCase) RemoveItemFromCart(ctx context.Context, tenantID string, cartID string, itemID int, expectedVersion int) (*CartAggregate, error) {
+	return u.dispatch(ctx, RemoveItem{TenantID: tenantID, CartID: cartID, ItemID: itemID, ExpectedVersion: expectedVersion})
+}
 
-	if err := u.repository.Save(ctx, cart); err != nil {
-		return nil, err
-	}
-	return cart, nil
+// RemoveItemsFromCart removes quantity units of an item from a cart at once.
+func (u *CheckoutUseCase) RemoveItemsFromCart(ctx context.Context, tenantID string, cartID string, itemID int, quantity int, expectedVersion int) (*CartAggregate, error) {
+	return u.dispatch(ctx, RemoveItem{TenantID: tenantID, CartID: cartID, ItemID: itemID, Quantity: quantity, ExpectedVersion: expectedVersion})
+}
+
+// RemoveAllOfItem removes every unit of an item from a cart.
+func (u *CheckoutUseCase) RemoveAllOfItem(ctx context.Context, tenantID string, cartID string, itemID int, expectedVersion int) (*CartAggregate, error) {
+	return u.dispatch(ctx, RemoveItem{TenantID: tenantID, CartID: cartID, ItemID: itemID, All: true, ExpectedVersion: expectedVersion})
+}
+
+// SetItemQuantity sets the units of an item in a cart, removing it at zero.
+func (u *CheckoutUseCase) SetItemQuantity(ctx context.Context, tenantID string, cartID string, itemID int, quantity int, expectedVersion int) (*CartAggregate, error) {
+	return u.dispatch(ctx, SetItemQuantity{TenantID: tenantID, CartID: cartID, ItemID: itemID, Quantity: quantity, ExpectedVersion: expectedVersion})
 }
 
-func (u *CheckoutUseCase) Checkout(ctx context.Context, cartID int) (*CartAggregate, error) {
-	cart, err := u.repository.Get(ctx, cartID)
+// SetCartContents replaces the contents of a cart at once.
+func (u *CheckoutUseCase) SetCartContents(ctx context.Context, tenantID string, cartID string, lines Lines, expectedVersion int) (*CartAggregate, error) {
+	return u.dispatch(ctx, SetContents{TenantID: tenantID, CartID: cartID, Lines: lines, ExpectedVersion: expectedVersion})
+}
+
+func (u *CheckoutUseCase) Checkout(ctx context.Context, tenantID string, cartID string, expectedVersion int) (*CartAggregate, error) {
+	return u.dispatch(ctx, Checkout{TenantID: tenantID, CartID: cartID, ExpectedVersion: expectedVersion})
+}
 
+// dispatch dispatches a command changing a cart and prices the result.
+func (u *CheckoutUseCase) dispatch(ctx context.Context, cmd es.Command) (*CartAggregate, error) {
+	cart, err := es.Dispatch[*CartAggregate](ctx, u.bus, cmd)
 	if err != nil {
 		return nil, err
 	}
+	return u.price(ctx, cart)
+}
 
-	if err := cart.Checkout(); err != nil {
-		return nil, err
+// price sets the totals of an open cart. Checked out carts keep the totals
+// they were checked out with. Carts that cannot be priced, such as carts
+// holding items that were never in the catalog, are returned without.
+func (u *CheckoutUseCase) price(ctx context.Context, cart *CartAggregate) (*CartAggregate, error) {
+	if u.pricer == nil || cart.CheckedOut || cart.Expired {
+		return cart, nil
 	}
-
-	if err := u.repository.Save(ctx, cart); err != nil {
+	totals, err := u.pricer.Price(ctx, cart.TenantID, cart.Region, cart.Contents)
+	if unpriceable(err) {
+		cart.Totals = nil
+		return cart, nil
+	}
+	if err != nil {
 		return nil, err
 	}
+	cart.Totals = totals
 	return cart, nil
 }
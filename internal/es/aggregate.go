@@ -3,6 +3,7 @@ package es
 type EventSourcedAggregate struct {
 	comittedEvents    []Event
 	uncommittedEvents []Event
+	position          int64
 }
 
 func (c *EventSourcedAggregate) Apply(events ...Event) error {
@@ -15,6 +16,16 @@ func (c *EventSourcedAggregate) UncommittedEvents() []Event {
 }
 
 func (c *EventSourcedAggregate) Commit() {
+	for _, event := range c.uncommittedEvents {
+		c.position = max(c.position, event.Position)
+	}
 	c.comittedEvents = append(c.comittedEvents, c.uncommittedEvents...)
 	c.uncommittedEvents = []Event{}
 }
+
+// Position returns the global position of the latest committed event of the
+// aggregate, either loaded or appended, or zero if it is not known, e.g.
+// when the aggregate was restored from a snapshot alone.
+func (c *EventSourcedAggregate) Position() int64 {
+	return c.position
+}
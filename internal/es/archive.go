@@ -0,0 +1,303 @@
+package es
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ArchivedEvent is the NDJSON representation of an event in an archive file.
+type ArchivedEvent struct {
+	Position      int64           `json:"position"`
+	TenantID      string          `json:"tenant_id"`
+	AggregateID   string          `json:"aggregate_id"`
+	AggregateType AggregateType   `json:"aggregate_type"`
+	Type          EventType       `json:"event_type"`
+	At            time.Time       `json:"at"`
+	VersionID     int             `json:"version_id"`
+	Data          json.RawMessage `json:"data"`
+	Hash          string          `json:"hash"`
+}
+
+// ArchivedPartition describes a partition that was exported and detached.
+type ArchivedPartition struct {
+	Partition
+	File   string
+	Events int64
+}
+
+// Archiver exports old partitions of the events table to compressed NDJSON
+// files and detaches them. A partition is only archived once every aggregate
+// with events in it has a snapshot at or beyond its last archived version, so
+// aggregates can still be rehydrated from the snapshot and the live events.
+type Archiver struct {
+	pool         *pgxpool.Pool
+	stream       *EventStream
+	dir          string
+	snapshotters map[AggregateType]SnapshotFunc
+}
+
+func NewArchiver(pool *pgxpool.Pool, dir string) *Archiver {
+	return &Archiver{
+		pool:         pool,
+		stream:       NewEventStream(pool),
+		dir:          dir,
+		snapshotters: make(map[AggregateType]SnapshotFunc),
+	}
+}
+
+// RegisterSnapshotter registers how to snapshot aggregates of the given type
+// when a partition about to be archived is not yet fully snapshotted.
+func (a *Archiver) RegisterSnapshotter(aggType AggregateType, fn SnapshotFunc) {
+	a.snapshotters[aggType] = fn
+}
+
+// Archive exports and detaches every monthly partition that ends on or
+// before the given time, oldest first.
+func (a *Archiver) Archive(ctx context.Context, before time.Time) ([]ArchivedPartition, error) {
+	partitions, err := a.stream.Partitions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list partitions: %w", err)
+	}
+
+	if err := os.MkdirAll(a.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create archive directory: %w", err)
+	}
+
+	archived := []ArchivedPartition{}
+	for _, p := range partitions {
+		if p.To.After(before) {
+			continue
+		}
+
+		if err := a.snapshotPartition(ctx, p); err != nil {
+			return archived, fmt.Errorf("snapshot %s: %w", p.Name, err)
+		}
+
+		file, count, err := a.export(ctx, p)
+		if err != nil {
+			return archived, fmt.Errorf("export %s: %w", p.Name, err)
+		}
+
+		if err := a.detach(ctx, p, file, count); err != nil {
+			return archived, fmt.Errorf("detach %s: %w", p.Name, err)
+		}
+
+		archived = append(archived, ArchivedPartition{
+			Partition: p,
+			File:      file,
+			Events:    count,
+		})
+	}
+
+	return archived, nil
+}
+
+func (a *Archiver) snapshotPartition(ctx context.Context, p Partition) error {
+	missing, err := a.unsnapshotted(ctx, p)
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range missing {
+		snapshot, ok := a.snapshotters[ref.aggType]
+		if !ok {
+			return fmt.Errorf("no snapshotter registered for aggregate type %q", ref.aggType)
+		}
+		if err := snapshot(ctx, ref.tenantID, ref.aggID); err != nil {
+			return fmt.Errorf("snapshot %s/%s/%s: %w", ref.tenantID, ref.aggType, ref.aggID, err)
+		}
+	}
+
+	missing, err = a.unsnapshotted(ctx, p)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("partition is not fully snapshotted")
+	}
+
+	return nil
+}
+
+type aggregateRef struct {
+	tenantID string
+	aggType  AggregateType
+	aggID    string
+}
+
+// unsnapshotted returns the aggregates whose last version in the partition
+// is newer than their latest snapshot.
+func (a *Archiver) unsnapshotted(ctx context.Context, p Partition) ([]aggregateRef, error) {
+	query := fmt.Sprintf(`
+		SELECT p.tenant_id, p.aggregate_type, p.aggregate_id
+		FROM (
+			SELECT tenant_id, aggregate_type, aggregate_id, MAX(version_id) AS version_id
+			FROM %s
+			GROUP BY tenant_id, aggregate_type, aggregate_id
+		) p
+		LEFT JOIN snapshots s
+		ON s.tenant_id = p.tenant_id
+		AND s.aggregate_type = p.aggregate_type
+		AND s.aggregate_id = p.aggregate_id
+		WHERE s.version_id IS NULL OR s.version_id < p.version_id`,
+		pgx.Identifier{p.Name}.Sanitize(),
+	)
+
+	rows, err := a.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	missing := []aggregateRef{}
+	for rows.Next() {
+		var ref aggregateRef
+		if err := rows.Scan(&ref.tenantID, &ref.aggType, &ref.aggID); err != nil {
+			return nil, err
+		}
+		missing = append(missing, ref)
+	}
+
+	return missing, rows.Err()
+}
+
+func (a *Archiver) export(ctx context.Context, p Partition) (string, int64, error) {
+	path := filepath.Join(a.dir, p.Name+".ndjson.gz")
+	tmpPath := path + ".tmp"
+
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmpPath)
+	defer f.Close()
+
+	query := fmt.Sprintf(`
+		SELECT
+			position,
+			tenant_id,
+			aggregate_id,
+			aggregate_type,
+			event_type,
+			at,
+			version_id,
+			data,
+			COALESCE(hash, '')
+		FROM %s
+		ORDER BY position ASC`,
+		pgx.Identifier{p.Name}.Sanitize(),
+	)
+
+	rows, err := a.pool.Query(ctx, query)
+	if err != nil {
+		return "", 0, err
+	}
+	defer rows.Close()
+
+	zw := gzip.NewWriter(f)
+	enc := json.NewEncoder(zw)
+
+	var count int64
+	for rows.Next() {
+		var e ArchivedEvent
+		if err := rows.Scan(
+			&e.Position,
+			&e.TenantID,
+			&e.AggregateID,
+			&e.AggregateType,
+			&e.Type,
+			&e.At,
+			&e.VersionID,
+			&e.Data,
+			&e.Hash,
+		); err != nil {
+			return "", 0, err
+		}
+		e.At = WallClock(e.At)
+
+		if err := enc.Encode(e); err != nil {
+			return "", 0, err
+		}
+		count++
+	}
+
+	if err := rows.Err(); err != nil {
+		return "", 0, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return "", 0, err
+	}
+	if err := f.Sync(); err != nil {
+		return "", 0, err
+	}
+	if err := f.Close(); err != nil {
+		return "", 0, err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return "", 0, err
+	}
+
+	return path, count, nil
+}
+
+func (a *Archiver) detach(ctx context.Context, p Partition, file string, count int64) error {
+	tx, err := a.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	defer func(ctx context.Context) {
+		_ = tx.Rollback(ctx)
+	}(ctx)
+
+	table := pgx.Identifier{p.Name}.Sanitize()
+
+	// Remember the last archived hash of each aggregate so the live part of
+	// its chain can still be verified.
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`
+		INSERT INTO event_archive_anchors (tenant_id, aggregate_type, aggregate_id, version_id, hash)
+		SELECT DISTINCT ON (tenant_id, aggregate_type, aggregate_id)
+			tenant_id, aggregate_type, aggregate_id, version_id, COALESCE(hash, '')
+		FROM %s
+		ORDER BY tenant_id, aggregate_type, aggregate_id, version_id DESC
+		ON CONFLICT (tenant_id, aggregate_type, aggregate_id) DO UPDATE
+		SET version_id = EXCLUDED.version_id,
+			hash = EXCLUDED.hash
+		WHERE event_archive_anchors.version_id < EXCLUDED.version_id`,
+		table,
+	)); err != nil {
+		return fmt.Errorf("update archive anchors: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO archived_partitions (name, range_start, range_end, file, event_count)
+		VALUES ($1, $2, $3, $4, $5)`,
+		p.Name, p.From, p.To, file, count,
+	); err != nil {
+		return fmt.Errorf("record archived partition: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`ALTER TABLE events DETACH PARTITION %s`, table)); err != nil {
+		return fmt.Errorf("detach partition: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`DROP TABLE %s`, table)); err != nil {
+		return fmt.Errorf("drop partition: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,308 @@
+package es
+
+import (
+	"context"
+	"errors"
+	"es/internal/migrate"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrUnhashedEvent is returned by BackfillHashes for an event without a
+// hash that was not written before events were hashed, e.g. because its
+// hash was removed. Such events are left for the verifier to report.
+var ErrUnhashedEvent = NewError(KindConflict, "event without hash was written after events were hashed")
+
+// ErrChainBroken is returned by AssignTenant for an aggregate whose hash
+// chain does not verify, which rehashing would hide.
+var ErrChainBroken = NewError(KindConflict, "hash chain is broken")
+
+// BackfillHashes hashes the events written before events were hashed, which
+// the verifier would otherwise report as a broken chain forever. Those are
+// the unhashed events at the start of each aggregate from before the event
+// store's first migration added the hash column. They are hashed in version
+// order, chained to the archive anchor. Hashes are never rewritten: events
+// appended to the aggregate since were chained to an empty hash, so the
+// chain is recorded to restart at the first of them. Any other unhashed
+// event fails with ErrUnhashedEvent. It returns the number of events hashed.
+func (s *EventStream) BackfillHashes(ctx context.Context) (int, error) {
+	hashedSince, err := s.hashedSince(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT DISTINCT tenant_id, aggregate_type, aggregate_id
+		FROM events
+		WHERE hash IS NULL`)
+	if err != nil {
+		return 0, fmt.Errorf("find unhashed events: %w", err)
+	}
+
+	var refs []aggregateRef
+	for rows.Next() {
+		var ref aggregateRef
+		if err := rows.Scan(&ref.tenantID, &ref.aggType, &ref.aggID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		refs = append(refs, ref)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	hashed := 0
+	for _, ref := range refs {
+		n, err := s.inTx(ctx, func(tx pgx.Tx) (int, error) {
+			return fillHashes(ctx, tx, ref, hashedSince)
+		})
+		if err != nil {
+			return hashed, fmt.Errorf("hash %s/%s/%s: %w", ref.tenantID, ref.aggType, ref.aggID, err)
+		}
+		hashed += n
+	}
+	return hashed, nil
+}
+
+// hashedSince returns when the event store's first migration, which added
+// the hash column, was applied.
+func (s *EventStream) hashedSince(ctx context.Context) (time.Time, error) {
+	applied, err := migrate.NewMigrator(s.pool, MigrationNamespace, Migrations()).Status(ctx)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("read applied migrations: %w", err)
+	}
+	if len(applied) == 0 || applied[0].Version != 1 {
+		return time.Time{}, errors.New("the event store is not migrated")
+	}
+	// applied_at holds the UTC wall clock.
+	return applied[0].AppliedAt, nil
+}
+
+// inTx runs fn in a transaction, committing it if fn succeeds.
+func (s *EventStream) inTx(ctx context.Context, fn func(pgx.Tx) (int, error)) (int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	n, err := fn(tx)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+	return n, nil
+}
+
+// fillHashes locks the aggregate, hashes its unhashed events written before
+// hashedSince and records where its chain restarts, returning how many
+// events it hashed.
+func fillHashes(ctx context.Context, tx pgx.Tx, ref aggregateRef, hashedSince time.Time) (int, error) {
+	anchor, restart, events, err := lockChain(ctx, tx, ref)
+	if err != nil {
+		return 0, err
+	}
+
+	legacy := 0
+	for legacy < len(events) && events[legacy].Hash == "" && events[legacy].At.Before(hashedSince) {
+		legacy++
+	}
+	for _, e := range events[legacy:] {
+		if e.Hash == "" {
+			return 0, fmt.Errorf("%w: version %d", ErrUnhashedEvent, e.VersionID)
+		}
+	}
+	if legacy > 0 && restart != 0 {
+		return 0, fmt.Errorf("%w: version %d was hashed before", ErrUnhashedEvent, events[0].VersionID)
+	}
+
+	filled := events[:legacy]
+	if err := HashChain(anchor, filled); err != nil {
+		return 0, err
+	}
+	for _, e := range filled {
+		if _, err := tx.Exec(ctx, `UPDATE events SET hash = $1 WHERE position = $2 AND hash IS NULL`, e.Hash, e.Position); err != nil {
+			return 0, fmt.Errorf("update hash: %w", err)
+		}
+	}
+
+	if legacy > 0 && legacy < len(events) {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO event_chain_restarts (tenant_id, aggregate_type, aggregate_id, version_id)
+			VALUES ($1, $2, $3, $4)`,
+			ref.tenantID, ref.aggType, ref.aggID, events[legacy].VersionID,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("record chain restart: %w", err)
+		}
+	}
+	return len(filled), nil
+}
+
+// lockChain locks the aggregate and returns the start of its hash chain and
+// its events in version order.
+func lockChain(ctx context.Context, tx pgx.Tx, ref aggregateRef) (string, int, []Event, error) {
+	if _, _, err := lockAggregateHead(ctx, tx, Event{TenantID: ref.tenantID, AggregateType: ref.aggType, AggregateID: ref.aggID}); err != nil {
+		return "", 0, nil, fmt.Errorf("lock aggregate: %w", err)
+	}
+
+	anchor, restart, err := chainStart(ctx, tx, ref)
+	if err != nil {
+		return "", 0, nil, err
+	}
+
+	rows, err := tx.Query(ctx, selectEvents+`
+		WHERE tenant_id = $1
+		AND aggregate_type = $2
+		AND aggregate_id = $3
+		ORDER BY version_id, position`,
+		ref.tenantID, ref.aggType, ref.aggID,
+	)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return "", 0, nil, err
+		}
+		events = append(events, e)
+	}
+	return anchor, restart, events, rows.Err()
+}
+
+// ErrTenantTaken is returned by AssignTenant when an aggregate written
+// before tenants existed has the ID of one the tenant already has.
+var ErrTenantTaken = NewError(KindConflict, "aggregate already exists in the tenant")
+
+// AssignTenant moves the events, snapshots and archive anchors written
+// before tenants existed, which belong to the empty tenant, to the given
+// tenant. The tenant is part of what is hashed, so the chain of every moved
+// aggregate is verified and then hashed again; aggregates whose chain is
+// broken fail with ErrChainBroken. Events in archive files keep the empty
+// tenant. It returns the number of aggregates moved.
+func (s *EventStream) AssignTenant(ctx context.Context, tenantID string) (int, error) {
+	if tenantID == "" {
+		return 0, errors.New("tenant ID must not be empty")
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT DISTINCT aggregate_type, aggregate_id
+		FROM events
+		WHERE tenant_id = ''`)
+	if err != nil {
+		return 0, fmt.Errorf("find events without tenant: %w", err)
+	}
+	var refs []aggregateRef
+	for rows.Next() {
+		ref := aggregateRef{tenantID: tenantID}
+		if err := rows.Scan(&ref.aggType, &ref.aggID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		refs = append(refs, ref)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	for _, ref := range refs {
+		_, err := s.inTx(ctx, func(tx pgx.Tx) (int, error) {
+			return moveToTenant(ctx, tx, ref)
+		})
+		if err != nil {
+			return moved, fmt.Errorf("move %s/%s to %s: %w", ref.aggType, ref.aggID, tenantID, err)
+		}
+		moved++
+	}
+	return moved, nil
+}
+
+// moveToTenant verifies the chain of an aggregate of the empty tenant,
+// moves it to ref.tenantID and hashes its events again, returning how many
+// it rehashed.
+func moveToTenant(ctx context.Context, tx pgx.Tx, ref aggregateRef) (int, error) {
+	if _, _, err := lockAggregateHead(ctx, tx, Event{TenantID: ref.tenantID, AggregateType: ref.aggType, AggregateID: ref.aggID}); err != nil {
+		return 0, fmt.Errorf("lock aggregate: %w", err)
+	}
+
+	var taken bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM events
+			WHERE tenant_id = $1
+			AND aggregate_type = $2
+			AND aggregate_id = $3
+		)`,
+		ref.tenantID, ref.aggType, ref.aggID,
+	).Scan(&taken)
+	if err != nil {
+		return 0, err
+	}
+	if taken {
+		return 0, ErrTenantTaken
+	}
+
+	anchor, restart, events, err := lockChain(ctx, tx, aggregateRef{aggType: ref.aggType, aggID: ref.aggID})
+	if err != nil {
+		return 0, err
+	}
+	broken, err := VerifyRestartedChain(anchor, restart, events)
+	if err != nil {
+		return 0, err
+	}
+	if broken != nil {
+		return 0, fmt.Errorf("%w at version %d", ErrChainBroken, broken.VersionID)
+	}
+
+	for _, table := range []string{"events", "snapshots", "event_archive_anchors"} {
+		_, err := tx.Exec(ctx, fmt.Sprintf(`
+			UPDATE %s SET tenant_id = $1
+			WHERE tenant_id = ''
+			AND aggregate_type = $2
+			AND aggregate_id = $3`, table),
+			ref.tenantID, ref.aggType, ref.aggID,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("move %s: %w", table, err)
+		}
+	}
+
+	// The chain is hashed again in one piece, so it no longer restarts.
+	_, err = tx.Exec(ctx, `
+		DELETE FROM event_chain_restarts
+		WHERE tenant_id = ''
+		AND aggregate_type = $1
+		AND aggregate_id = $2`,
+		ref.aggType, ref.aggID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("remove chain restart: %w", err)
+	}
+
+	for i := range events {
+		events[i].TenantID = ref.tenantID
+	}
+	if err := HashChain(anchor, events); err != nil {
+		return 0, err
+	}
+	for _, e := range events {
+		if _, err := tx.Exec(ctx, `UPDATE events SET hash = $1 WHERE position = $2`, e.Hash, e.Position); err != nil {
+			return 0, fmt.Errorf("update hash: %w", err)
+		}
+	}
+	return len(events), nil
+}
@@ -0,0 +1,85 @@
+package es
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Command is a request to change an aggregate. Commands are plain structs
+// identified by name so handlers and middlewares can be registered per type.
+type Command interface {
+	CommandName() string
+}
+
+// CommandHandler executes a command and returns its result, usually the
+// aggregate after the change.
+type CommandHandler func(ctx context.Context, cmd Command) (any, error)
+
+// CommandMiddleware wraps a handler with cross-cutting behaviour such as
+// validation, logging or retries.
+type CommandMiddleware func(next CommandHandler) CommandHandler
+
+// ErrUnknownCommand is returned when no handler is registered for a command.
+var ErrUnknownCommand = errors.New("unknown command")
+
+// CommandBus dispatches commands to their handlers through a middleware
+// chain. Middlewares run in the order they were given, the first being the
+// outermost.
+type CommandBus struct {
+	handlers    map[string]CommandHandler
+	middlewares []CommandMiddleware
+}
+
+func NewCommandBus(middlewares ...CommandMiddleware) *CommandBus {
+	return &CommandBus{
+		handlers:    make(map[string]CommandHandler),
+		middlewares: middlewares,
+	}
+}
+
+// Register sets the handler for commands with the given name, wrapped in the
+// bus middlewares.
+func (b *CommandBus) Register(name string, handler CommandHandler) {
+	for i := len(b.middlewares) - 1; i >= 0; i-- {
+		handler = b.middlewares[i](handler)
+	}
+	b.handlers[name] = handler
+}
+
+// Dispatch runs the handler registered for the command.
+func (b *CommandBus) Dispatch(ctx context.Context, cmd Command) (any, error) {
+	handler, ok := b.handlers[cmd.CommandName()]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCommand, cmd.CommandName())
+	}
+	return handler(ctx, cmd)
+}
+
+// RegisterHandler registers a handler for a concrete command type.
+func RegisterHandler[C Command, R any](b *CommandBus, handler func(ctx context.Context, cmd C) (R, error)) {
+	var zero C
+	b.Register(zero.CommandName(), func(ctx context.Context, cmd Command) (any, error) {
+		c, ok := cmd.(C)
+		if !ok {
+			return nil, fmt.Errorf("command %q has unexpected type %T", cmd.CommandName(), cmd)
+		}
+		return handler(ctx, c)
+	})
+}
+
+// Dispatch sends the command through the bus and returns its typed result.
+func Dispatch[R any](ctx context.Context, b *CommandBus, cmd Command) (R, error) {
+	var zero R
+
+	result, err := b.Dispatch(ctx, cmd)
+	if err != nil {
+		return zero, err
+	}
+
+	r, ok := result.(R)
+	if !ok {
+		return zero, fmt.Errorf("command %q returned unexpected type %T", cmd.CommandName(), result)
+	}
+	return r, nil
+}
@@ -0,0 +1,163 @@
+package es
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	commandsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "commands_total",
+			Help: "Total number of dispatched commands",
+		},
+		[]string{"command", "outcome"},
+	)
+
+	commandDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "command_duration_seconds",
+			Help:    "Time taken to handle a command",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"command"},
+	)
+)
+
+// ErrUnauthorized is returned when a policy rejects a command.
+var ErrUnauthorized = NewError(KindForbidden, "command not authorized")
+
+// Validation rejects commands that implement Validate and are invalid,
+// before they reach the handler, with ErrInvalidCommand.
+func Validation() CommandMiddleware {
+	return func(next CommandHandler) CommandHandler {
+		return func(ctx context.Context, cmd Command) (any, error) {
+			if v, ok := cmd.(interface{ Validate() error }); ok {
+				if err := v.Validate(); err != nil {
+					return nil, fmt.Errorf("%w: %w", ErrInvalidCommand, err)
+				}
+			}
+			return next(ctx, cmd)
+		}
+	}
+}
+
+// Logging prints every command with its duration and outcome.
+func Logging() CommandMiddleware {
+	return func(next CommandHandler) CommandHandler {
+		return func(ctx context.Context, cmd Command) (any, error) {
+			start := time.Now()
+			result, err := next(ctx, cmd)
+
+			if err != nil {
+				fmt.Printf("command %s failed after %s: %v\n", cmd.CommandName(), time.Since(start), err)
+			} else {
+				fmt.Printf("command %s handled in %s\n", cmd.CommandName(), time.Since(start))
+			}
+			return result, err
+		}
+	}
+}
+
+// Metrics counts commands by outcome and records how long they take.
+func Metrics() CommandMiddleware {
+	return func(next CommandHandler) CommandHandler {
+		return func(ctx context.Context, cmd Command) (any, error) {
+			start := time.Now()
+			result, err := next(ctx, cmd)
+
+			outcome := "success"
+			if err != nil {
+				outcome = "error"
+			}
+			commandsTotal.WithLabelValues(cmd.CommandName(), outcome).Inc()
+			commandDuration.WithLabelValues(cmd.CommandName()).Observe(time.Since(start).Seconds())
+
+			return result, err
+		}
+	}
+}
+
+// Policy decides whether a command may be handled.
+type Policy func(ctx context.Context, cmd Command) error
+
+// Authorization runs the command through the policy before handling it.
+func Authorization(policy Policy) CommandMiddleware {
+	return func(next CommandHandler) CommandHandler {
+		return func(ctx context.Context, cmd Command) (any, error) {
+			if err := policy(ctx, cmd); err != nil {
+				return nil, err
+			}
+			return next(ctx, cmd)
+		}
+	}
+}
+
+// RequireTenant is a policy that rejects commands addressed to no tenant.
+// Commands expose their tenant by implementing Tenant.
+func RequireTenant(_ context.Context, cmd Command) error {
+	scoped, ok := cmd.(interface{ Tenant() string })
+	if !ok {
+		return nil
+	}
+	if scoped.Tenant() == "" {
+		return fmt.Errorf("%w: %s has no tenant", ErrUnauthorized, cmd.CommandName())
+	}
+	return nil
+}
+
+// RetryOnConflict re-runs the handler when it fails with
+// ErrConcurrencyConflict, waiting a little longer before every attempt.
+// Handlers must reload the aggregate each time they run.
+func RetryOnConflict(attempts int, backoff time.Duration) CommandMiddleware {
+	return func(next CommandHandler) CommandHandler {
+		return func(ctx context.Context, cmd Command) (any, error) {
+			var result any
+			var err error
+
+			for attempt := 1; attempt <= attempts; attempt++ {
+				result, err = next(ctx, cmd)
+				if !errors.Is(err, ErrConcurrencyConflict) || attempt == attempts {
+					break
+				}
+
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case <-time.After(backoff * time.Duration(attempt)):
+				}
+			}
+			return result, err
+		}
+	}
+}
+
+// Tracing wraps every command in a span of the global tracer provider.
+func Tracing() CommandMiddleware {
+	tracer := otel.Tracer("es/internal/es")
+
+	return func(next CommandHandler) CommandHandler {
+		return func(ctx context.Context, cmd Command) (any, error) {
+			ctx, span := tracer.Start(ctx, "command "+cmd.CommandName(),
+				trace.WithAttributes(attribute.String("command.name", cmd.CommandName())),
+			)
+			defer span.End()
+
+			result, err := next(ctx, cmd)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			return result, err
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package es_test
+
+import (
+	"context"
+	"errors"
+	"es/internal/es"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type greet struct {
+	TenantID string
+	Name     string
+}
+
+func (greet) CommandName() string { return "test.Greet" }
+func (g greet) Tenant() string    { return g.TenantID }
+
+func (g greet) Validate() error {
+	if g.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+func newGreetBus(calls *int, mw ...es.CommandMiddleware) *es.CommandBus {
+	bus := es.NewCommandBus(mw...)
+	es.RegisterHandler(bus, func(_ context.Context, cmd greet) (string, error) {
+		*calls++
+		return "hello " + cmd.Name, nil
+	})
+	return bus
+}
+
+func TestCommandBus(t *testing.T) {
+	t.Run("dispatches to the typed handler", func(t *testing.T) {
+		calls := 0
+		bus := newGreetBus(&calls)
+
+		result, err := es.Dispatch[string](context.Background(), bus, greet{Name: "bob"})
+		require.NoError(t, err)
+		assert.Equal(t, "hello bob", result)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("unknown command", func(t *testing.T) {
+		bus := es.NewCommandBus()
+
+		_, err := bus.Dispatch(context.Background(), greet{Name: "bob"})
+		assert.ErrorIs(t, err, es.ErrUnknownCommand)
+	})
+
+	t.Run("middlewares run in order", func(t *testing.T) {
+		order := []string{}
+		trace := func(name string) es.CommandMiddleware {
+			return func(next es.CommandHandler) es.CommandHandler {
+				return func(ctx context.Context, cmd es.Command) (any, error) {
+					order = append(order, name)
+					return next(ctx, cmd)
+				}
+			}
+		}
+
+		calls := 0
+		bus := newGreetBus(&calls, trace("outer"), trace("inner"))
+
+		_, err := bus.Dispatch(context.Background(), greet{Name: "bob"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"outer", "inner"}, order)
+	})
+}
+
+func TestCommandMiddlewares(t *testing.T) {
+	t.Run("validation rejects invalid commands", func(t *testing.T) {
+		calls := 0
+		bus := newGreetBus(&calls, es.Validation())
+
+		_, err := bus.Dispatch(context.Background(), greet{})
+		assert.ErrorIs(t, err, es.ErrInvalidCommand)
+		assert.EqualError(t, err, "invalid command: name is required")
+		assert.Equal(t, 0, calls)
+	})
+
+	t.Run("authorization requires a tenant", func(t *testing.T) {
+		calls := 0
+		bus := newGreetBus(&calls, es.Authorization(es.RequireTenant))
+
+		_, err := bus.Dispatch(context.Background(), greet{Name: "bob"})
+		assert.ErrorIs(t, err, es.ErrUnauthorized)
+
+		_, err = bus.Dispatch(context.Background(), greet{TenantID: "t1", Name: "bob"})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("retries on concurrency conflicts", func(t *testing.T) {
+		attempts := 0
+		bus := es.NewCommandBus(es.RetryOnConflict(3, time.Millisecond))
+		es.RegisterHandler(bus, func(_ context.Context, cmd greet) (string, error) {
+			attempts++
+			if attempts < 3 {
+				return "", es.ErrConcurrencyConflict
+			}
+			return "ok", nil
+		})
+
+		result, err := es.Dispatch[string](context.Background(), bus, greet{Name: "bob"})
+		require.NoError(t, err)
+		assert.Equal(t, "ok", result)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("gives up after the last attempt", func(t *testing.T) {
+		attempts := 0
+		bus := es.NewCommandBus(es.RetryOnConflict(2, time.Millisecond))
+		es.RegisterHandler(bus, func(_ context.Context, cmd greet) (string, error) {
+			attempts++
+			return "", es.ErrConcurrencyConflict
+		})
+
+		_, err := bus.Dispatch(context.Background(), greet{Name: "bob"})
+		assert.ErrorIs(t, err, es.ErrConcurrencyConflict)
+		assert.Equal(t, 2, attempts)
+	})
+
+	t.Run("other errors are not retried", func(t *testing.T) {
+		attempts := 0
+		bus := es.NewCommandBus(es.RetryOnConflict(3, time.Millisecond))
+		es.RegisterHandler(bus, func(_ context.Context, cmd greet) (string, error) {
+			attempts++
+			return "", errors.New("boom")
+		})
+
+		_, err := bus.Dispatch(context.Background(), greet{Name: "bob"})
+		assert.EqualError(t, err, "boom")
+		assert.Equal(t, 1, attempts)
+	})
+}
@@ -0,0 +1,119 @@
+package es
+
+import (
+	"context"
+	"errors"
+	"es/internal/openapi"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HeaderEventPosition carries the global position of the latest event of
+// the aggregate a command changed. Reads of projections given it as
+// min_position see the change.
+const HeaderEventPosition = "X-Event-Position"
+
+// MinPositionParam documents the min_position query parameter of reads
+// wrapped in ReadYourWrites.
+var MinPositionParam = openapi.Parameter{
+	Name:        "min_position",
+	Description: "Wait until the read model has processed the event at this position, e.g. the X-Event-Position of a command response",
+	Type:        int64(0),
+}
+
+// Checkpoint is the position a read model has processed events up to, which
+// every ProjectionWriter has.
+type Checkpoint interface {
+	Name() string
+	LatestPosition(context.Context) (int64, error)
+}
+
+// ErrNotCaughtUp is returned by AwaitPosition when the context ends before
+// the read models reach the position.
+var ErrNotCaughtUp = errors.New("read model has not caught up")
+
+// AwaitPosition polls the checkpoints every interval until all of them have
+// reached the position, or the context ends.
+func AwaitPosition(ctx context.Context, position int64, interval time.Duration, checkpoints ...Checkpoint) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for _, checkpoint := range checkpoints {
+		for {
+			latest, err := checkpoint.LatestPosition(ctx)
+			if err != nil && ctx.Err() == nil {
+				return fmt.Errorf("read position of %s: %w", checkpoint.Name(), err)
+			}
+			if err == nil && latest >= position {
+				break
+			}
+
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("%w: %s has not reached position %d", ErrNotCaughtUp, checkpoint.Name(), position)
+			case <-ticker.C:
+			}
+		}
+	}
+	return nil
+}
+
+type ConsistencyConfig struct {
+	// Timeout bounds how long a read waits for the read models.
+	Timeout time.Duration
+	// PollInterval is how often the checkpoints are read while waiting.
+	PollInterval time.Duration
+	// RetryAfter is sent to clients whose read timed out.
+	RetryAfter time.Duration
+}
+
+// LoadConsistencyConfig waits up to READ_YOUR_WRITES_TIMEOUT_MS (default
+// 2000) for read models, polling them every 50ms.
+func LoadConsistencyConfig() ConsistencyConfig {
+	cfg := ConsistencyConfig{
+		Timeout:      2 * time.Second,
+		PollInterval: 50 * time.Millisecond,
+		RetryAfter:   time.Second,
+	}
+	if ms, err := strconv.Atoi(os.Getenv("READ_YOUR_WRITES_TIMEOUT_MS")); err == nil && ms > 0 {
+		cfg.Timeout = time.Duration(ms) * time.Millisecond
+	}
+	return cfg
+}
+
+// ReadYourWrites makes reads of the read models behind the checkpoints
+// consistent with earlier commands of the client. Requests with a
+// min_position query parameter wait until every checkpoint has reached it,
+// and are answered with 503 and a Retry-After header if that takes longer
+// than the timeout. Requests without one are served right away.
+func ReadYourWrites(cfg ConsistencyConfig, checkpoints ...Checkpoint) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		v := c.Query(MinPositionParam.Name)
+		if v == "" {
+			return c.Next()
+		}
+
+		position, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || position < 0 {
+			return fiber.NewError(http.StatusBadRequest, "min_position must be a non-negative integer")
+		}
+
+		ctx, cancel := context.WithTimeout(c.Context(), cfg.Timeout)
+		defer cancel()
+
+		err = AwaitPosition(ctx, position, cfg.PollInterval, checkpoints...)
+		if errors.Is(err, ErrNotCaughtUp) {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(max(cfg.RetryAfter.Seconds(), 1))))
+			return fiber.NewError(http.StatusServiceUnavailable, err.Error())
+		}
+		if err != nil {
+			return err
+		}
+		return c.Next()
+	}
+}
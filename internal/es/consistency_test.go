@@ -0,0 +1,85 @@
+package es_test
+
+import (
+	"context"
+	"es/internal/es"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// checkpoint is a read model that advances one position per read, starting
+// at its position, until it reaches until.
+type checkpoint struct {
+	position atomic.Int64
+	until    int64
+}
+
+func (*checkpoint) Name() string { return "inventory_v2" }
+
+func (c *checkpoint) LatestPosition(context.Context) (int64, error) {
+	position := c.position.Load()
+	if position < c.until {
+		c.position.Add(1)
+	}
+	return position, nil
+}
+
+func newReadApp(checkpoints ...es.Checkpoint) *fiber.App {
+	app := fiber.New()
+	cfg := es.ConsistencyConfig{Timeout: 100 * time.Millisecond, PollInterval: time.Millisecond, RetryAfter: 2 * time.Second}
+	app.Get("/inventory", es.ReadYourWrites(cfg, checkpoints...), func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusOK)
+	})
+	return app
+}
+
+func get(t *testing.T, app *fiber.App, path string) *http.Response {
+	t.Helper()
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil), -1)
+	require.NoError(t, err)
+	return resp
+}
+
+func TestReadYourWrites(t *testing.T) {
+	t.Run("reads without min_position are served right away", func(t *testing.T) {
+		resp := get(t, newReadApp(&checkpoint{}), "/inventory")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("waits until every read model reaches the position", func(t *testing.T) {
+		first, second := &checkpoint{until: 10}, &checkpoint{until: 10}
+		second.position.Store(3)
+
+		resp := get(t, newReadApp(first, second), "/inventory?min_position=5")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.GreaterOrEqual(t, first.position.Load(), int64(5))
+		assert.GreaterOrEqual(t, second.position.Load(), int64(5))
+	})
+
+	t.Run("answers 503 when the read model lags behind", func(t *testing.T) {
+		resp := get(t, newReadApp(&checkpoint{until: 4}), "/inventory?min_position=5")
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, "2", resp.Header.Get("Retry-After"))
+	})
+
+	t.Run("rejects invalid positions", func(t *testing.T) {
+		resp := get(t, newReadApp(&checkpoint{}), "/inventory?min_position=latest")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestAwaitPosition(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := es.AwaitPosition(ctx, 5, time.Millisecond, &checkpoint{until: 2})
+	assert.ErrorIs(t, err, es.ErrNotCaughtUp)
+	assert.ErrorContains(t, err, "inventory_v2 has not reached position 5")
+}
@@ -0,0 +1,50 @@
+package es
+
+import "errors"
+
+// ErrorKind classifies domain errors, so that transports can answer them
+// with a matching status without knowing every error of every aggregate.
+type ErrorKind int
+
+const (
+	KindUnknown ErrorKind = iota
+	// KindNotFound means the aggregate or resource does not exist.
+	KindNotFound
+	// KindConflict means the request conflicts with the current state.
+	KindConflict
+	// KindInvalid means the request itself is malformed or out of range.
+	KindInvalid
+	// KindGone means the resource existed but no longer accepts requests.
+	KindGone
+	// KindForbidden means the caller may not make the request.
+	KindForbidden
+	// KindPrecondition means a precondition of the request, such as the
+	// expected version of an aggregate, does not hold.
+	KindPrecondition
+)
+
+// Error is a domain error of a known kind. Declare them as package level
+// sentinels with NewError and wrap them with fmt.Errorf to add detail.
+type Error struct {
+	Kind    ErrorKind
+	Message string
+}
+
+func NewError(kind ErrorKind, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// KindOf returns the kind of the first domain error in err's chain, or
+// KindUnknown if there is none.
+func KindOf(err error) ErrorKind {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Kind
+	}
+	return KindUnknown
+}
+
+// ErrInvalidCommand is returned when a command fails validation.
+var ErrInvalidCommand = NewError(KindInvalid, "invalid command")
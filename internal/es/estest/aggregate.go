@@ -0,0 +1,146 @@
+// Package estest provides Given/When/Then fixtures for testing event
+// sourced aggregates and projections.
+package estest
+
+import (
+	"encoding/json"
+	"errors"
+	"es/internal/es"
+	"es/internal/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Epoch is the time the fixtures' clocks start from.
+var Epoch = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Aggregate is an aggregate that records the events raised by its commands.
+type Aggregate interface {
+	es.Aggregate
+	UncommittedEvents() []es.Event
+	Commit()
+}
+
+// AggregateFixture runs a command against an aggregate rehydrated from
+// prior events and checks the events it raises:
+//
+//	estest.NewAggregateFixture(t, newCart).
+//		Given(created).
+//		When(func(c *checkout.CartAggregate) error { return c.Add(42) }).
+//		Then(itemAdded)
+type AggregateFixture[A Aggregate] struct {
+	t         testing.TB
+	aggregate A
+	given     []es.Event
+	err       error
+	ran       bool
+}
+
+// NewAggregateFixture creates the aggregate under test. The factory gets a
+// sequenced clock starting at Epoch so raised events have predictable times.
+func NewAggregateFixture[A Aggregate](t testing.TB, factory func(now util.Timestamp) A) *AggregateFixture[A] {
+	t.Helper()
+
+	return &AggregateFixture[A]{
+		t:         t,
+		aggregate: factory(util.SequencedTime(Epoch)),
+	}
+}
+
+// Given applies prior events to the aggregate. Events without a version are
+// numbered after the previous ones.
+func (f *AggregateFixture[A]) Given(events ...es.Event) *AggregateFixture[A] {
+	f.t.Helper()
+
+	for _, event := range events {
+		if event.VersionID == 0 {
+			event.VersionID = len(f.given) + 1
+		}
+		if event.At.IsZero() {
+			event.At = Epoch
+		}
+		require.NoError(f.t, f.aggregate.Apply(event), "apply given %s", event.Type)
+		f.given = append(f.given, event)
+	}
+	f.aggregate.Commit()
+	return f
+}
+
+// When runs the command against the aggregate.
+func (f *AggregateFixture[A]) When(command func(A) error) *AggregateFixture[A] {
+	f.err = command(f.aggregate)
+	f.ran = true
+	return f
+}
+
+// Then expects the command to succeed and raise exactly the given events.
+// Events are compared by type and data, and by version when one is set.
+func (f *AggregateFixture[A]) Then(expected ...es.Event) A {
+	f.t.Helper()
+	f.requireRan()
+
+	if !assert.NoError(f.t, f.err) {
+		return f.aggregate
+	}
+
+	actual := f.aggregate.UncommittedEvents()
+	if !assert.Len(f.t, actual, len(expected), "raised events") {
+		return f.aggregate
+	}
+
+	for i := range expected {
+		assertEvent(f.t, expected[i], actual[i])
+	}
+	return f.aggregate
+}
+
+// ThenError expects the command to fail with the given error and raise no
+// events.
+func (f *AggregateFixture[A]) ThenError(expected error) A {
+	f.t.Helper()
+	f.requireRan()
+
+	if errorsMatch(f.err, expected) {
+		assert.Empty(f.t, f.aggregate.UncommittedEvents(), "raised events")
+	} else {
+		f.t.Errorf("expected error %q, got %v", expected, f.err)
+	}
+	return f.aggregate
+}
+
+func (f *AggregateFixture[A]) requireRan() {
+	f.t.Helper()
+	if !f.ran {
+		f.t.Fatalf("When must be called before Then")
+	}
+}
+
+// errorsMatch accepts either the same error chain or an error with the same
+// message, since aggregates often return ad hoc errors.
+func errorsMatch(actual, expected error) bool {
+	if actual == nil {
+		return false
+	}
+	return errors.Is(actual, expected) || actual.Error() == expected.Error()
+}
+
+func assertEvent(t testing.TB, expected, actual es.Event) {
+	t.Helper()
+
+	assert.Equal(t, expected.Type, actual.Type, "event type")
+	if expected.VersionID != 0 {
+		assert.Equal(t, expected.VersionID, actual.VersionID, "version of %s", expected.Type)
+	}
+	assert.JSONEq(t, mustJSON(t, expected.Data), mustJSON(t, actual.Data), "data of %s", expected.Type)
+}
+
+func mustJSON(t testing.TB, v any) string {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(data)
+}
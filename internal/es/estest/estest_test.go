@@ -0,0 +1,151 @@
+package estest_test
+
+import (
+	"context"
+	"errors"
+	"es/internal/es"
+	"es/internal/es/estest"
+	"es/internal/util"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errClosed = errors.New("tally is closed")
+
+type tally struct {
+	es.EventSourcedAggregate
+	now     util.Timestamp
+	Total   int
+	Closed  bool
+	version int
+}
+
+func (a *tally) Apply(events ...es.Event) error {
+	for _, e := range events {
+		switch e.Type {
+		case "added":
+			a.Total += e.Data.(map[string]int)["n"]
+		case "closed":
+			a.Closed = true
+		default:
+			return errors.New("not implemented")
+		}
+		a.version = e.VersionID
+	}
+	return a.EventSourcedAggregate.Apply(events...)
+}
+
+func (a *tally) Add(n int) error {
+	if a.Closed {
+		return errClosed
+	}
+	return a.Apply(es.Event{Type: "added", VersionID: a.version + 1, At: a.now(), Data: map[string]int{"n": n}})
+}
+
+func newTally(now util.Timestamp) *tally {
+	return &tally{now: now}
+}
+
+// recorder captures failures so the fixtures' own failures can be tested.
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) FailNow() {
+	r.failures = append(r.failures, "FailNow")
+}
+
+func TestAggregateFixture(t *testing.T) {
+	t.Run("expected events", func(t *testing.T) {
+		result := estest.NewAggregateFixture(t, newTally).
+			Given(es.Event{Type: "added", Data: map[string]int{"n": 1}}).
+			When(func(a *tally) error { return a.Add(2) }).
+			Then(es.Event{Type: "added", VersionID: 2, Data: map[string]any{"n": 2}})
+
+		assert.Equal(t, 3, result.Total)
+		assert.Equal(t, estest.Epoch, result.UncommittedEvents()[0].At)
+	})
+
+	t.Run("expected error", func(t *testing.T) {
+		estest.NewAggregateFixture(t, newTally).
+			Given(es.Event{Type: "closed"}).
+			When(func(a *tally) error { return a.Add(2) }).
+			ThenError(errClosed)
+	})
+
+	t.Run("unexpected data fails", func(t *testing.T) {
+		r := &recorder{TB: t}
+		estest.NewAggregateFixture(r, newTally).
+			When(func(a *tally) error { return a.Add(2) }).
+			Then(es.Event{Type: "added", Data: map[string]int{"n": 3}})
+
+		assert.NotEmpty(t, r.failures)
+	})
+
+	t.Run("missing error fails", func(t *testing.T) {
+		r := &recorder{TB: t}
+		estest.NewAggregateFixture(r, newTally).
+			When(func(a *tally) error { return a.Add(2) }).
+			ThenError(errClosed)
+
+		assert.NotEmpty(t, r.failures)
+	})
+}
+
+type memoryProjection struct {
+	totals   map[string]int
+	position int64
+}
+
+func (p *memoryProjection) Name() string                     { return "memory" }
+func (p *memoryProjection) SubscribedEvents() []es.EventType { return []es.EventType{"added"} }
+
+func (p *memoryProjection) ApplyMigration(context.Context) error {
+	if p.totals == nil {
+		p.totals = make(map[string]int)
+	}
+	return nil
+}
+
+func (p *memoryProjection) LatestPosition(context.Context) (int64, error) {
+	return p.position, nil
+}
+
+func (p *memoryProjection) Apply(_ context.Context, events ...es.Event) error {
+	for _, e := range events {
+		p.totals[e.AggregateID] += e.Data.(map[string]int)["n"]
+		p.position = e.Position
+	}
+	return nil
+}
+
+func (p *memoryProjection) SavePosition(_ context.Context, position int64) error {
+	p.position = max(p.position, position)
+	return nil
+}
+
+func TestProjectionFixture(t *testing.T) {
+	projection := &memoryProjection{}
+
+	estest.NewProjectionFixture(t, projection).
+		Given(
+			es.Event{Type: "added", AggregateID: "a", Data: map[string]int{"n": 1}},
+			es.Event{Type: "added", AggregateID: "b", Data: map[string]int{"n": 2}},
+		).
+		Given(es.Event{Type: "added", AggregateID: "a", Data: map[string]int{"n": 3}}).
+		ThenPosition().
+		Then(func(_ context.Context, t testing.TB) {
+			assert.Equal(t, map[string]int{"a": 4, "b": 2}, projection.totals)
+		})
+
+	assert.Equal(t, int64(3), projection.position)
+}
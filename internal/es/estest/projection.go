@@ -0,0 +1,86 @@
+package estest
+
+import (
+	"context"
+	"es/internal/es"
+	"es/internal/util"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ProjectionFixture feeds events into a projection and lets the test assert
+// on its read model:
+//
+//	estest.NewProjectionFixture(t, v2.NewProjection(pool)).
+//		Given(itemAdded, itemAdded).
+//		Then(func(ctx context.Context, t testing.TB) { ... })
+type ProjectionFixture struct {
+	t        testing.TB
+	ctx      context.Context
+	writer   es.ProjectionWriter
+	now      util.Timestamp
+	position int64
+}
+
+// NewProjectionFixture applies the projection's migration and starts its
+// event positions after the last one it has processed.
+func NewProjectionFixture(t testing.TB, writer es.ProjectionWriter) *ProjectionFixture {
+	t.Helper()
+
+	ctx := context.Background()
+	require.NoError(t, writer.ApplyMigration(ctx), "apply migration of %s", writer.Name())
+
+	position, err := writer.LatestPosition(ctx)
+	require.NoError(t, err, "read latest position of %s", writer.Name())
+
+	return &ProjectionFixture{
+		t:        t,
+		ctx:      ctx,
+		writer:   writer,
+		now:      util.SequencedTime(Epoch),
+		position: position,
+	}
+}
+
+// Given applies the events to the projection as one batch. Events without a
+// position or time get the next position and the next tick of the clock.
+func (f *ProjectionFixture) Given(events ...es.Event) *ProjectionFixture {
+	f.t.Helper()
+
+	batch := make([]es.Event, len(events))
+	for i, event := range events {
+		if event.Position == 0 {
+			f.position++
+			event.Position = f.position
+		} else if event.Position > f.position {
+			f.position = event.Position
+		}
+		if event.At.IsZero() {
+			event.At = f.now()
+		}
+		batch[i] = event
+	}
+
+	require.NoError(f.t, f.writer.Apply(f.ctx, batch...), "apply events to %s", f.writer.Name())
+	return f
+}
+
+// Then runs assertions against the read model.
+func (f *ProjectionFixture) Then(assertion func(ctx context.Context, t testing.TB)) *ProjectionFixture {
+	f.t.Helper()
+
+	assertion(f.ctx, f.t)
+	return f
+}
+
+// ThenPosition expects the projection to have recorded the position of the
+// last event given.
+func (f *ProjectionFixture) ThenPosition() *ProjectionFixture {
+	f.t.Helper()
+
+	position, err := f.writer.LatestPosition(f.ctx)
+	require.NoError(f.t, err)
+	require.Equal(f.t, f.position, position, "latest position of %s", f.writer.Name())
+	return f
+}
@@ -0,0 +1,53 @@
+package estest
+
+import (
+	"context"
+	"es/internal/es"
+	"es/internal/migrate"
+	"slices"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+)
+
+// StoredEvents appends the events to the event store of the pool, creating
+// its tables if needed, and returns them as the store reads them back, so
+// projections given them see the times and data they see in production.
+// Events without a version continue their aggregate from the versions given
+// in the same call.
+func StoredEvents(t testing.TB, pool *pgxpool.Pool, events ...es.Event) []es.Event {
+	t.Helper()
+
+	ctx := context.Background()
+	_, err := migrate.NewMigrator(pool, es.MigrationNamespace, es.Migrations()).Up(ctx)
+	require.NoError(t, err, "migrate event store")
+
+	type aggregateKey struct {
+		tenantID string
+		aggType  es.AggregateType
+		aggID    string
+	}
+	versions := make(map[aggregateKey]int)
+	var types []es.EventType
+
+	appended := slices.Clone(events)
+	for i := range appended {
+		event := &appended[i]
+		key := aggregateKey{event.TenantID, event.AggregateType, event.AggregateID}
+		if event.VersionID == 0 {
+			event.VersionID = versions[key] + 1
+		}
+		versions[key] = event.VersionID
+		if !slices.Contains(types, event.Type) {
+			types = append(types, event.Type)
+		}
+	}
+
+	stream := es.NewEventStream(pool)
+	require.NoError(t, stream.Append(ctx, appended...), "append events")
+
+	stored, err := stream.GetEvents(ctx, appended[0].Position, appended[len(appended)-1].Position, types)
+	require.NoError(t, err, "read events back")
+	return stored
+}
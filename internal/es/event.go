@@ -1,26 +1,51 @@
 package es
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 )
 
 type EventType string
 type AggregateType string
 
+// MaxAggregateIDLength is the longest aggregate ID the events table can hold.
+const MaxAggregateIDLength = 64
+
 type Event struct {
 	Position      int64
+	TenantID      string
 	Type          EventType
 	At            time.Time
 	VersionID     int
 	AggregateType AggregateType
-	AggregateID   int
+	AggregateID   string
 	Data          any
+	Hash          string
+}
+
+// WallClock turns a time read from a TIMESTAMP column of the event store
+// into the instant it was written at. The columns hold the wall clock of
+// time.Local, which is what writers record, but pgx reads them back labelled
+// UTC.
+func WallClock(t time.Time) time.Time {
+	return time.Date(
+		t.Year(), t.Month(), t.Day(),
+		t.Hour(), t.Minute(), t.Second(), t.Nanosecond(),
+		time.Local,
+	)
 }
 
 func (e Event) Validate() error {
-	if e.AggregateID <= 0 {
-		return errors.New("invalid aggregate ID")
+	if e.TenantID == "" {
+		return errors.New("tenant ID must not be empty")
+	}
+	if e.AggregateID == "" {
+		return errors.New("aggregate ID must not be empty")
+	}
+	if len(e.AggregateID) > MaxAggregateIDLength {
+		return errors.New("aggregate ID is too long")
 	}
 	if e.AggregateType == "" {
 		return errors.New("aggregate type must not be empty")
@@ -33,3 +58,18 @@ func (e Event) Validate() error {
 	}
 	return nil
 }
+
+// DecodeData converts the event data into T through JSON, so it works both
+// for events raised in process and for events read back from the store.
+func DecodeData[T any](e Event) (T, error) {
+	var data T
+
+	raw, err := json.Marshal(e.Data)
+	if err != nil {
+		return data, fmt.Errorf("marshal %s data: %w", e.Type, err)
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return data, fmt.Errorf("decode %s data: %w", e.Type, err)
+	}
+	return data, nil
+}
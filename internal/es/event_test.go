@@ -0,0 +1,39 @@
+package es_test
+
+import (
+	"es/internal/es"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWallClock(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("UTC-5", -5*60*60)
+	t.Cleanup(func() { time.Local = local })
+
+	written := time.Date(2026, 1, 1, 9, 30, 0, 0, time.Local)
+	// pgx reads the wall clock stored in a TIMESTAMP column labelled UTC
+	scanned := time.Date(2026, 1, 1, 9, 30, 0, 0, time.UTC)
+
+	t.Run("reads the instant the time was written at", func(t *testing.T) {
+		at := es.WallClock(scanned)
+		assert.True(t, at.Equal(written))
+		assert.Equal(t, time.Date(2026, 1, 1, 14, 30, 0, 0, time.UTC), at.UTC())
+	})
+
+	t.Run("keeps the hash of the event", func(t *testing.T) {
+		event := newTestEvent(1, map[string]int{"item_id": 42})
+		event.At = written
+		writtenHash, err := event.ComputeHash("")
+		require.NoError(t, err)
+
+		event.At = es.WallClock(scanned)
+		readHash, err := event.ComputeHash("")
+		require.NoError(t, err)
+
+		assert.Equal(t, writtenHash, readHash)
+	})
+}
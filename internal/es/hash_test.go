@@ -0,0 +1,157 @@
+package es_test
+
+import (
+	"es/internal/es"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventHashChain(t *testing.T) {
+	t.Run("typed and decoded payloads hash the same", func(t *testing.T) {
+		typed := newTestEvent(1, map[string]int{"item_id": 42})
+		decoded := newTestEvent(1, map[string]any{"item_id": float64(42)})
+
+		typedHash, err := typed.ComputeHash("")
+		require.NoError(t, err)
+		decodedHash, err := decoded.ComputeHash("")
+		require.NoError(t, err)
+
+		assert.Equal(t, typedHash, decodedHash)
+	})
+
+	t.Run("hash depends on previous hash", func(t *testing.T) {
+		event := newTestEvent(2, map[string]int{"item_id": 42})
+
+		first, err := event.ComputeHash("a")
+		require.NoError(t, err)
+		second, err := event.ComputeHash("b")
+		require.NoError(t, err)
+
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("integer-like IDs hash as before string IDs", func(t *testing.T) {
+		event := newTestEvent(2, map[string]int{"item_id": 42})
+		event.TenantID = "t1"
+
+		hash, err := event.ComputeHash("")
+		require.NoError(t, err)
+		// Hash of the same event when aggregate IDs were integers.
+		assert.Equal(t, "c0347d20fb17e8757e29eb306b5bce3317cbfafefd372db5fb2d38a5e4ce1d10", hash)
+
+		event.AggregateID = "01001"
+		padded, err := event.ComputeHash("")
+		require.NoError(t, err)
+		assert.NotEqual(t, hash, padded)
+	})
+
+	t.Run("intact chain", func(t *testing.T) {
+		events := newTestChain(t)
+
+		broken, err := es.VerifyChain("", events)
+		assert.NoError(t, err)
+		assert.Nil(t, broken)
+	})
+
+	t.Run("edited payload breaks the chain", func(t *testing.T) {
+		events := newTestChain(t)
+		events[1].Data = map[string]int{"item_id": 99}
+
+		broken, err := es.VerifyChain("", events)
+		assert.NoError(t, err)
+		require.NotNil(t, broken)
+		assert.Equal(t, 2, broken.VersionID)
+		assert.Equal(t, events[1].Hash, broken.ActualHash)
+	})
+
+	t.Run("deleted event breaks the chain", func(t *testing.T) {
+		events := newTestChain(t)
+		events = append(events[:1], events[2:]...)
+
+		broken, err := es.VerifyChain("", events)
+		assert.NoError(t, err)
+		require.NotNil(t, broken)
+		assert.Equal(t, 3, broken.VersionID)
+	})
+
+	t.Run("hashing unhashed events continues the chain", func(t *testing.T) {
+		events := newTestChain(t)
+		unhashed := []es.Event{newTestEvent(2, map[string]int{"item_id": 42}), newTestEvent(3, map[string]int{"item_id": 43})}
+
+		require.NoError(t, es.HashChain(events[0].Hash, unhashed))
+		assert.Equal(t, events[1].Hash, unhashed[0].Hash)
+
+		broken, err := es.VerifyChain("", append(events[:1], unhashed...))
+		assert.NoError(t, err)
+		assert.Nil(t, broken)
+	})
+
+	t.Run("backfilled chains restart where hashing began", func(t *testing.T) {
+		legacy := []es.Event{newTestEvent(1, map[string]any{}), newTestEvent(2, map[string]int{"item_id": 42})}
+		appended := []es.Event{newTestEvent(3, map[string]int{"item_id": 43}), newTestEvent(4, map[string]int{"item_id": 44})}
+		require.NoError(t, es.HashChain("", appended))
+		require.NoError(t, es.HashChain("", legacy))
+		events := append(legacy, appended...)
+
+		broken, err := es.VerifyRestartedChain("", 3, events)
+		assert.NoError(t, err)
+		assert.Nil(t, broken)
+
+		broken, err = es.VerifyChain("", events)
+		assert.NoError(t, err)
+		require.NotNil(t, broken)
+		assert.Equal(t, 3, broken.VersionID)
+
+		events[3].Data = map[string]int{"item_id": 99}
+		broken, err = es.VerifyRestartedChain("", 3, events)
+		assert.NoError(t, err)
+		require.NotNil(t, broken, "A restart must not hide later edits")
+		assert.Equal(t, 4, broken.VersionID)
+	})
+
+	t.Run("archived prefix verifies from anchor", func(t *testing.T) {
+		events := newTestChain(t)
+
+		broken, err := es.VerifyChain(events[0].Hash, events[1:])
+		assert.NoError(t, err)
+		assert.Nil(t, broken)
+
+		broken, err = es.VerifyChain("", events[1:])
+		assert.NoError(t, err)
+		require.NotNil(t, broken)
+		assert.Equal(t, 2, broken.VersionID)
+	})
+}
+
+func newTestChain(t *testing.T) []es.Event {
+	t.Helper()
+
+	events := []es.Event{
+		newTestEvent(1, map[string]any{}),
+		newTestEvent(2, map[string]int{"item_id": 42}),
+		newTestEvent(3, map[string]int{"item_id": 43}),
+	}
+
+	prevHash := ""
+	for i := range events {
+		hash, err := events[i].ComputeHash(prevHash)
+		require.NoError(t, err)
+		events[i].Hash = hash
+		prevHash = hash
+	}
+	return events
+}
+
+func newTestEvent(version int, data any) es.Event {
+	return es.Event{
+		Type:          "cart.item_added",
+		At:            time.Date(2026, 1, 1, 0, 0, 0, version, time.UTC),
+		VersionID:     version,
+		AggregateType: "cart",
+		AggregateID:   "1001",
+		Data:          data,
+	}
+}
@@ -0,0 +1,17 @@
+package es
+
+import (
+	"embed"
+	"es/internal/migrate"
+)
+
+// MigrationNamespace is the schema_migrations namespace of the event store.
+const MigrationNamespace = "events"
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migrations returns the versioned migrations of the event store tables.
+func Migrations() []migrate.Migration {
+	return migrate.MustLoad(migrationFiles, "migrations")
+}
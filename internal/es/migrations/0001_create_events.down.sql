@@ -0,0 +1,5 @@
+-- Reverting would drop the event log, which nothing can rebuild.
+DO $$
+BEGIN
+    RAISE EXCEPTION 'the event store cannot be reverted: it would delete every event';
+END $$;
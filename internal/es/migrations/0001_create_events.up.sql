@@ -0,0 +1,184 @@
+-- Tamper-evident chain: each event hashes its content with the previous
+-- event of the same aggregate. Rows written before this column existed
+-- are left NULL and will be reported by the verifier.
+ALTER TABLE IF EXISTS events ADD COLUMN IF NOT EXISTS hash CHAR(64);
+
+-- Every event belongs to a tenant (storefront). Events written before
+-- tenants existed belong to the empty tenant.
+ALTER TABLE IF EXISTS events ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT '';
+
+-- Move a pre-partitioning events table out of the way so its rows can be
+-- copied into the partitioned table below.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM pg_class
+        WHERE relname = 'events' AND relkind = 'r'
+    ) THEN
+        ALTER TABLE events RENAME TO events_unpartitioned;
+        DROP INDEX IF EXISTS idx_events_type;
+        DROP INDEX IF EXISTS idx_events_at;
+        DROP INDEX IF EXISTS idx_events_aggregate_type;
+        DROP INDEX IF EXISTS idx_events_aggregate_at;
+    END IF;
+END $$;
+
+-- Events are partitioned by month of "at" so old months can be archived.
+CREATE TABLE IF NOT EXISTS events (
+    position BIGSERIAL NOT NULL,
+    tenant_id VARCHAR(64) NOT NULL DEFAULT '',
+    aggregate_id VARCHAR(64) NOT NULL,
+    aggregate_type VARCHAR(50) NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    at TIMESTAMP NOT NULL,
+    version_id INTEGER NOT NULL,
+    data JSONB,
+    hash CHAR(64),
+    PRIMARY KEY (position, at)
+) PARTITION BY RANGE (at);
+
+-- Catches events outside the pre-created monthly partitions. It should stay
+-- empty: a month with rows here cannot get its own partition.
+CREATE TABLE IF NOT EXISTS events_default PARTITION OF events DEFAULT;
+
+-- Partitions for the current month and the next three. The archive command
+-- keeps creating partitions ahead of time.
+DO $$
+DECLARE
+    m TIMESTAMP;
+BEGIN
+    FOR m IN
+        SELECT generate_series(
+            date_trunc('month', now() AT TIME ZONE 'UTC'),
+            date_trunc('month', now() AT TIME ZONE 'UTC') + INTERVAL '3 months',
+            INTERVAL '1 month'
+        )
+    LOOP
+        EXECUTE format(
+            'CREATE TABLE IF NOT EXISTS %I PARTITION OF events FOR VALUES FROM (%L) TO (%L)',
+            'events_y' || to_char(m, 'YYYY') || 'm' || to_char(m, 'MM'),
+            m,
+            m + INTERVAL '1 month'
+        );
+    END LOOP;
+END $$;
+
+-- Copy rows from a pre-partitioning table, creating a partition for every
+-- month they cover, then drop it.
+DO $$
+DECLARE
+    m TIMESTAMP;
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_class WHERE relname = 'events_unpartitioned') THEN
+        FOR m IN SELECT DISTINCT date_trunc('month', at) FROM events_unpartitioned
+        LOOP
+            EXECUTE format(
+                'CREATE TABLE IF NOT EXISTS %I PARTITION OF events FOR VALUES FROM (%L) TO (%L)',
+                'events_y' || to_char(m, 'YYYY') || 'm' || to_char(m, 'MM'),
+                m,
+                m + INTERVAL '1 month'
+            );
+        END LOOP;
+
+        INSERT INTO events (position, tenant_id, aggregate_id, aggregate_type, event_type, at, version_id, data, hash)
+        SELECT position, tenant_id, aggregate_id::text, aggregate_type, event_type, at, version_id, data, hash
+        FROM events_unpartitioned;
+
+        PERFORM setval(
+            pg_get_serial_sequence('events', 'position'),
+            (SELECT COALESCE(MAX(position), 0) + 1 FROM events),
+            false
+        );
+
+        DROP TABLE events_unpartitioned;
+    END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS idx_events_position ON events (position);
+CREATE INDEX IF NOT EXISTS idx_events_type ON events (event_type);
+CREATE INDEX IF NOT EXISTS idx_events_at ON events (at);
+CREATE INDEX IF NOT EXISTS idx_events_aggregate_type ON events (aggregate_id, aggregate_type);
+CREATE INDEX IF NOT EXISTS idx_events_aggregate_at ON events (aggregate_id, at);
+CREATE INDEX IF NOT EXISTS idx_events_tenant_aggregate ON events (tenant_id, aggregate_type, aggregate_id, version_id);
+
+-- Latest snapshot per aggregate, used to rehydrate aggregates whose early
+-- history has been archived.
+CREATE TABLE IF NOT EXISTS snapshots (
+    tenant_id VARCHAR(64) NOT NULL DEFAULT '',
+    aggregate_type VARCHAR(50) NOT NULL,
+    aggregate_id VARCHAR(64) NOT NULL,
+    version_id INTEGER NOT NULL,
+    at TIMESTAMP NOT NULL,
+    data JSONB NOT NULL,
+    PRIMARY KEY (tenant_id, aggregate_type, aggregate_id)
+);
+
+-- Hash of the last archived event per aggregate, so the live remainder of
+-- its chain can still be verified.
+CREATE TABLE IF NOT EXISTS event_archive_anchors (
+    tenant_id VARCHAR(64) NOT NULL DEFAULT '',
+    aggregate_type VARCHAR(50) NOT NULL,
+    aggregate_id VARCHAR(64) NOT NULL,
+    version_id INTEGER NOT NULL,
+    hash TEXT NOT NULL,
+    PRIMARY KEY (tenant_id, aggregate_type, aggregate_id)
+);
+
+-- Scope snapshots and anchors created before tenants existed.
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY['snapshots', 'event_archive_anchors']
+    LOOP
+        EXECUTE format(
+            'ALTER TABLE %I ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT %L',
+            t, ''
+        );
+
+        IF NOT EXISTS (
+            SELECT 1 FROM information_schema.key_column_usage
+            WHERE table_name = t
+            AND constraint_name = t || '_pkey'
+            AND column_name = 'tenant_id'
+        ) THEN
+            EXECUTE format('ALTER TABLE %I DROP CONSTRAINT %I', t, t || '_pkey');
+            EXECUTE format(
+                'ALTER TABLE %I ADD PRIMARY KEY (tenant_id, aggregate_type, aggregate_id)',
+                t
+            );
+        END IF;
+    END LOOP;
+END $$;
+
+-- Aggregate IDs used to be client chosen integers and are now opaque
+-- strings (UUIDv7 for new carts). Existing integer streams keep their IDs
+-- as decimal strings, which hash the same as before so their chains verify.
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY['events', 'snapshots', 'event_archive_anchors']
+    LOOP
+        IF EXISTS (
+            SELECT 1 FROM information_schema.columns
+            WHERE table_name = t
+            AND column_name = 'aggregate_id'
+            AND data_type = 'integer'
+        ) THEN
+            EXECUTE format(
+                'ALTER TABLE %I ALTER COLUMN aggregate_id TYPE VARCHAR(64) USING aggregate_id::text',
+                t
+            );
+        END IF;
+    END LOOP;
+END $$;
+
+CREATE TABLE IF NOT EXISTS archived_partitions (
+    name TEXT PRIMARY KEY,
+    range_start TIMESTAMP NOT NULL,
+    range_end TIMESTAMP NOT NULL,
+    file TEXT NOT NULL,
+    event_count BIGINT NOT NULL,
+    archived_at TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'UTC')
+);
@@ -0,0 +1,6 @@
+-- Reverting would drop the chain restarts, after which backfilled chains
+-- no longer verify.
+DO $$
+BEGIN
+    RAISE EXCEPTION 'the event store cannot be reverted: backfilled hash chains would no longer verify';
+END $$;
@@ -0,0 +1,11 @@
+-- Events written before events were hashed are hashed once by
+-- BackfillHashes. Events appended to the same aggregate before that were
+-- chained to an empty hash, which the backfill records here instead of
+-- rewriting their hashes, so the verifier restarts the chain at that version.
+CREATE TABLE IF NOT EXISTS event_chain_restarts (
+    tenant_id VARCHAR(64) NOT NULL DEFAULT '',
+    aggregate_type VARCHAR(50) NOT NULL,
+    aggregate_id VARCHAR(64) NOT NULL,
+    version_id INTEGER NOT NULL,
+    PRIMARY KEY (tenant_id, aggregate_type, aggregate_id)
+);
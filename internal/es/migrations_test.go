@@ -0,0 +1,19 @@
+package es_test
+
+import (
+	"es/internal/es"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrations(t *testing.T) {
+	migrations := es.Migrations()
+
+	require.NotEmpty(t, migrations)
+	assert.Equal(t, 1, migrations[0].Version)
+	for _, m := range migrations {
+		assert.Contains(t, m.Down, "RAISE EXCEPTION", "Reverting %d_%s would delete events", m.Version, m.Name)
+	}
+}
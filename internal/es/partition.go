@@ -0,0 +1,99 @@
+package es
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const partitionTimeLayout = "2006-01-02 15:04:05"
+
+// Partition is a monthly range partition of the events table over "at".
+type Partition struct {
+	Name string
+	From time.Time
+	To   time.Time
+}
+
+// MonthlyPartition returns the partition holding events at the given time.
+func MonthlyPartition(t time.Time) Partition {
+	from := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return Partition{
+		Name: fmt.Sprintf("events_y%04dm%02d", from.Year(), from.Month()),
+		From: from,
+		To:   from.AddDate(0, 1, 0),
+	}
+}
+
+// ParsePartitionName is the inverse of MonthlyPartition. It returns false
+// for tables that are not monthly partitions, such as the default partition.
+func ParsePartitionName(name string) (Partition, bool) {
+	var year, month int
+	if _, err := fmt.Sscanf(name, "events_y%04dm%02d", &year, &month); err != nil {
+		return Partition{}, false
+	}
+	if month < 1 || month > 12 {
+		return Partition{}, false
+	}
+
+	p := MonthlyPartition(time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC))
+	if p.Name != name {
+		return Partition{}, false
+	}
+	return p, true
+}
+
+// EnsurePartitions creates the monthly partitions starting at the month of
+// "from" and covering the given number of following months.
+func (s *EventStream) EnsurePartitions(ctx context.Context, from time.Time, months int) error {
+	for i := 0; i <= months; i++ {
+		p := MonthlyPartition(from.AddDate(0, i, 0))
+
+		sql := fmt.Sprintf(
+			`CREATE TABLE IF NOT EXISTS %s PARTITION OF events FOR VALUES FROM ('%s') TO ('%s')`,
+			pgx.Identifier{p.Name}.Sanitize(),
+			p.From.Format(partitionTimeLayout),
+			p.To.Format(partitionTimeLayout),
+		)
+
+		if _, err := s.pool.Exec(ctx, sql); err != nil {
+			return fmt.Errorf("create partition %s: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// Partitions lists the monthly partitions currently attached to the events
+// table, oldest first.
+func (s *EventStream) Partitions(ctx context.Context) ([]Partition, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT c.relname
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = 'events'::regclass
+		ORDER BY c.relname ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	partitions := []Partition{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+
+		if p, ok := ParsePartitionName(name); ok {
+			partitions = append(partitions, p)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return partitions, nil
+}
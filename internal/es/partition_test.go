@@ -0,0 +1,33 @@
+package es_test
+
+import (
+	"es/internal/es"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMonthlyPartition(t *testing.T) {
+	t.Run("bounds cover the calendar month", func(t *testing.T) {
+		p := es.MonthlyPartition(time.Date(2026, 12, 31, 23, 59, 0, 0, time.UTC))
+
+		assert.Equal(t, "events_y2026m12", p.Name)
+		assert.Equal(t, time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC), p.From)
+		assert.Equal(t, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), p.To)
+	})
+
+	t.Run("parse partition name", func(t *testing.T) {
+		p, ok := es.ParsePartitionName("events_y2026m03")
+
+		assert.True(t, ok)
+		assert.Equal(t, es.MonthlyPartition(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)), p)
+	})
+
+	t.Run("ignore other tables", func(t *testing.T) {
+		for _, name := range []string{"events_default", "events_y2026m13", "events_y2026m3", "snapshots"} {
+			_, ok := es.ParsePartitionName(name)
+			assert.False(t, ok, name)
+		}
+	})
+}
@@ -13,6 +13,9 @@ type ProjectionWriter interface {
 	ApplyMigration(context.Context) error
 	LatestPosition(context.Context) (int64, error)
 	Apply(context.Context, ...Event) error
+	// SavePosition records that every event up to the position has been
+	// processed, including events the projection does not subscribe to.
+	SavePosition(context.Context, int64) error
 }
 
 type Subscription struct {
@@ -56,7 +59,8 @@ func (bp *Subscription) Listen(ctx context.Context, stream *EventStream) error {
 			)
 			return nil
 		case <-ticker.C:
-			if err := bp.Refresh(ctx, stream, lastPosition); err != nil {
+			lastPosition, err = bp.Refresh(ctx, stream, lastPosition)
+			if err != nil {
 				return fmt.Errorf(
 					"%s failed to refresh subscription: %w",
 					bp.writer.Name(),
@@ -67,46 +71,52 @@ func (bp *Subscription) Listen(ctx context.Context, stream *EventStream) error {
 	}
 }
 
+// Refresh applies the subscribed events after lastPosition in batches and
+// returns the position it has processed up to.
 func (bp *Subscription) Refresh(
 	ctx context.Context,
 	stream *EventStream,
 	lastPosition int64,
-) error {
+) (int64, error) {
 	subscribedEvents := bp.writer.SubscribedEvents()
 
 	if len(subscribedEvents) == 0 {
-		return errors.New("projection must subscribe to at least one event")
+		return lastPosition, errors.New("projection must subscribe to at least one event")
 	}
 
 	maxPosition, err := stream.GetMaxPosition(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get max position: %w", err)
+		return lastPosition, fmt.Errorf("failed to get max position: %w", err)
 	}
 
 	for lastPosition < maxPosition {
-		nextPosition := min(lastPosition+bp.batchSize+1, maxPosition)
+		nextPosition := min(lastPosition+bp.batchSize, maxPosition)
 		events, err := stream.GetEvents(
 			ctx,
-			lastPosition,
+			lastPosition+1,
 			nextPosition,
 			subscribedEvents,
 		)
 
 		if err != nil {
-			return fmt.Errorf("failed to get events: %w", err)
+			return lastPosition, fmt.Errorf("failed to get events: %w", err)
 		}
 
-		if len(events) == 0 {
-			continue
+		if err := bp.writer.Apply(ctx, events...); err != nil {
+			return lastPosition, fmt.Errorf("failed to apply events: %w", err)
 		}
 
-		if err := bp.writer.Apply(ctx, events...); err != nil {
-			return fmt.Errorf("failed to apply events: %w", err)
+		// Move the checkpoint past the events of other types too, so that
+		// readers waiting for a position see the projection reach it.
+		if len(events) == 0 || events[len(events)-1].Position < nextPosition {
+			if err := bp.writer.SavePosition(ctx, nextPosition); err != nil {
+				return lastPosition, fmt.Errorf("failed to save position: %w", err)
+			}
 		}
 
 		lastPosition = nextPosition
 	}
 
 	fmt.Printf("%s position=%d\n", bp.writer.Name(), lastPosition)
-	return nil
+	return lastPosition, nil
 }
@@ -1,6 +1,9 @@
 package es
 
 import (
+	"encoding/json"
+	"es/internal/openapi"
+	"es/internal/tenant"
 	"net/http"
 
 	"github.com/gofiber/fiber/v2"
@@ -16,20 +19,52 @@ func NewRouteHandler(eventStream *EventStream) *RouteHandler {
 	}
 }
 
+// Operations documents the handlers of RouteHandler.
+var Operations = struct {
+	AggregateEvents, VerifyAggregate, AggregateTimeline, EventSchemas openapi.Operation
+}{
+	AggregateEvents: openapi.Operation{
+		Summary:   "Events of an aggregate",
+		Tags:      []string{"events"},
+		Responses: map[int]any{http.StatusOK: []Event{}},
+	},
+	VerifyAggregate: openapi.Operation{
+		Summary:   "Verify the hash chain of an aggregate",
+		Tags:      []string{"events"},
+		Responses: map[int]any{http.StatusOK: verificationResult{}},
+	},
+	AggregateTimeline: openapi.Operation{
+		Summary:   "Replay an aggregate step by step",
+		Tags:      []string{"events"},
+		Responses: map[int]any{http.StatusOK: []TimelineEntry{}},
+	},
+	EventSchemas: openapi.Operation{
+		Summary:   "JSON Schema of the data of each event type",
+		Tags:      []string{"events"},
+		Responses: map[int]any{http.StatusOK: map[EventType]json.RawMessage{}},
+	},
+}
+
 func (h *RouteHandler) AggregateEvents(c *fiber.Ctx) error {
+	tenantID, err := tenant.FromRequest(c)
+
+	if err != nil {
+		return fiber.NewError(http.StatusForbidden, err.Error())
+	}
+
 	aggType := c.Params("aggType")
 
 	if aggType == "" {
-		return c.Status(http.StatusBadRequest).SendString("aggType is required")
+		return fiber.NewError(http.StatusBadRequest, "aggType is required")
 	}
 
-	aggID, err := c.ParamsInt("aggID")
+	aggID := c.Params("aggID")
 
-	if err != nil {
-		return c.Status(http.StatusBadRequest).SendString(err.Error())
+	if aggID == "" {
+		return fiber.NewError(http.StatusBadRequest, "aggID is required")
 	}
 
-	events, err := h.eventStream.GetAggregateEvents(c.Context(), AggregateType(aggType), aggID)
+	events, err := h.eventStream.GetAggregateEvents(c.Context(), tenantID, AggregateType(aggType), aggID)
 
 	if err != nil {
 		return err
@@ -37,3 +72,72 @@ func (h *RouteHandler) AggregateEvents(c *fiber.Ctx) error {
 
 	return c.Status(http.StatusOK).JSON(events)
 }
+
+type verificationResult struct {
+	Valid      bool        `json:"valid"`
+	BrokenLink *ChainBreak `json:"broken_link,omitempty"`
+}
+
+func (h *RouteHandler) VerifyAggregate(c *fiber.Ctx) error {
+	tenantID, err := tenant.FromRequest(c)
+
+	if err != nil {
+		return fiber.NewError(http.StatusForbidden, err.Error())
+	}
+
+	aggType := c.Params("aggType")
+
+	if aggType == "" {
+		return fiber.NewError(http.StatusBadRequest, "aggType is required")
+	}
+
+	aggID := c.Params("aggID")
+
+	if aggID == "" {
+		return fiber.NewError(http.StatusBadRequest, "aggID is required")
+	}
+
+	broken, err := h.eventStream.VerifyAggregate(c.Context(), tenantID, AggregateType(aggType), aggID)
+
+	if err != nil {
+		return err
+	}
+
+	return c.Status(http.StatusOK).JSON(verificationResult{
+		Valid:      broken == nil,
+		BrokenLink: broken,
+	})
+}
+
+func (h *RouteHandler) AggregateTimeline(c *fiber.Ctx) error {
+	tenantID, err := tenant.FromRequest(c)
+
+	if err != nil {
+		return fiber.NewError(http.StatusForbidden, err.Error())
+	}
+
+	aggType := c.Params("aggType")
+
+	if aggType == "" {
+		return fiber.NewError(http.StatusBadRequest, "aggType is required")
+	}
+
+	aggID := c.Params("aggID")
+
+	if aggID == "" {
+		return fiber.NewError(http.StatusBadRequest, "aggID is required")
+	}
+
+	timeline, err := h.eventStream.Timeline(c.Context(), tenantID, AggregateType(aggType), aggID)
+
+	if err != nil {
+		return err
+	}
+
+	return c.Status(http.StatusOK).JSON(timeline)
+}
+
+// EventSchemas returns the JSON Schema of each event type's data.
+func (h *RouteHandler) EventSchemas(c *fiber.Ctx) error {
+	return c.Status(http.StatusOK).JSON(h.eventStream.schemas.Schemas())
+}
@@ -0,0 +1,105 @@
+package es
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// ErrInvalidEventData is returned when an event payload does not match the
+// JSON Schema registered for its type.
+var ErrInvalidEventData = errors.New("invalid event data")
+
+// SchemaRegistry holds a JSON Schema per event type. Payloads of event
+// types without a schema are not validated.
+type SchemaRegistry struct {
+	mu       sync.RWMutex
+	raw      map[EventType]json.RawMessage
+	compiled map[EventType]*jsonschema.Schema
+}
+
+func NewSchemaRegistry() *SchemaRegistry {
+	return &SchemaRegistry{
+		raw:      make(map[EventType]json.RawMessage),
+		compiled: make(map[EventType]*jsonschema.Schema),
+	}
+}
+
+// DefaultSchemas is the registry used by every EventStream. Packages that
+// own event types register their schemas with it from an init function.
+var DefaultSchemas = NewSchemaRegistry()
+
+// MustRegisterSchema registers the schema with DefaultSchemas and panics if
+// it does not compile.
+func MustRegisterSchema(eventType EventType, schema string) {
+	if err := DefaultSchemas.Register(eventType, json.RawMessage(schema)); err != nil {
+		panic(err)
+	}
+}
+
+// Register compiles the schema and uses it for events of the given type,
+// replacing any schema registered before.
+func (r *SchemaRegistry) Register(eventType EventType, schema json.RawMessage) error {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema))
+	if err != nil {
+		return fmt.Errorf("parse schema for %s: %w", eventType, err)
+	}
+
+	url := "urn:event:" + string(eventType)
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource(url, doc); err != nil {
+		return fmt.Errorf("add schema for %s: %w", eventType, err)
+	}
+
+	compiled, err := c.Compile(url)
+	if err != nil {
+		return fmt.Errorf("compile schema for %s: %w", eventType, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.raw[eventType] = schema
+	r.compiled[eventType] = compiled
+	return nil
+}
+
+// Validate checks the event data against the schema of its type.
+func (r *SchemaRegistry) Validate(e Event) error {
+	r.mu.RLock()
+	schema, ok := r.compiled[e.Type]
+	r.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return fmt.Errorf("marshal event data: %w", err)
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("decode event data: %w", err)
+	}
+
+	if err := schema.Validate(doc); err != nil {
+		return fmt.Errorf("%w for %s: %v", ErrInvalidEventData, e.Type, err)
+	}
+	return nil
+}
+
+// Schemas returns the registered schemas by event type.
+func (r *SchemaRegistry) Schemas() map[EventType]json.RawMessage {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	schemas := make(map[EventType]json.RawMessage, len(r.raw))
+	for eventType, schema := range r.raw {
+		schemas[eventType] = schema
+	}
+	return schemas
+}
@@ -0,0 +1,50 @@
+package es_test
+
+import (
+	"encoding/json"
+	"es/internal/es"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaRegistry(t *testing.T) {
+	registry := es.NewSchemaRegistry()
+	require.NoError(t, registry.Register("item_added", json.RawMessage(`{
+		"type": "object",
+		"properties": {"item_id": {"type": "integer", "minimum": 1}},
+		"required": ["item_id"]
+	}`)))
+
+	t.Run("valid data", func(t *testing.T) {
+		err := registry.Validate(es.Event{Type: "item_added", Data: map[string]int{"item_id": 42}})
+		assert.NoError(t, err)
+	})
+
+	t.Run("missing field", func(t *testing.T) {
+		err := registry.Validate(es.Event{Type: "item_added", Data: map[string]any{}})
+		assert.ErrorIs(t, err, es.ErrInvalidEventData)
+	})
+
+	t.Run("wrong type", func(t *testing.T) {
+		err := registry.Validate(es.Event{Type: "item_added", Data: map[string]any{"item_id": "42"}})
+		assert.ErrorIs(t, err, es.ErrInvalidEventData)
+	})
+
+	t.Run("event types without schema are not validated", func(t *testing.T) {
+		err := registry.Validate(es.Event{Type: "other", Data: "anything"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("invalid schema is rejected", func(t *testing.T) {
+		err := registry.Register("broken", json.RawMessage(`{"type": 12}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("schemas are listed by event type", func(t *testing.T) {
+		schemas := registry.Schemas()
+		assert.Len(t, schemas, 1)
+		assert.Contains(t, schemas, es.EventType("item_added"))
+	})
+}
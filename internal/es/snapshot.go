@@ -0,0 +1,80 @@
+package es
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Snapshot captures the state of an aggregate at a given version so that
+// it can be rehydrated without replaying (possibly archived) earlier events.
+type Snapshot struct {
+	TenantID      string
+	AggregateType AggregateType
+	AggregateID   string
+	VersionID     int
+	At            time.Time
+	Data          json.RawMessage
+}
+
+// SnapshotFunc builds and stores a snapshot of the tenant's aggregate with the
+// given ID at its latest version.
+type SnapshotFunc func(ctx context.Context, tenantID string, aggID string) error
+
+// SaveSnapshot stores the snapshot unless a newer one already exists.
+func (s *EventStream) SaveSnapshot(ctx context.Context, snapshot Snapshot) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO snapshots (tenant_id, aggregate_type, aggregate_id, version_id, at, data)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (tenant_id, aggregate_type, aggregate_id) DO UPDATE
+		SET version_id = EXCLUDED.version_id,
+			at = EXCLUDED.at,
+			data = EXCLUDED.data
+		WHERE snapshots.version_id < EXCLUDED.version_id`,
+		snapshot.TenantID,
+		snapshot.AggregateType,
+		snapshot.AggregateID,
+		snapshot.VersionID,
+		snapshot.At.In(time.Local),
+		snapshot.Data,
+	)
+	return err
+}
+
+// GetSnapshot returns the latest snapshot of an aggregate, or nil if none exists.
+func (s *EventStream) GetSnapshot(ctx context.Context, tenantID string, aggType AggregateType, aggID string) (*Snapshot, error) {
+	query := `
+		SELECT
+			tenant_id,
+			aggregate_type,
+			aggregate_id,
+			version_id,
+			at,
+			data
+		FROM snapshots
+		WHERE tenant_id = $1
+		AND aggregate_id = $2
+		AND aggregate_type = $3`
+
+	var snapshot Snapshot
+	err := s.pool.QueryRow(ctx, query, tenantID, aggID, aggType).Scan(
+		&snapshot.TenantID,
+		&snapshot.AggregateType,
+		&snapshot.AggregateID,
+		&snapshot.VersionID,
+		&snapshot.At,
+		&snapshot.Data,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	snapshot.At = WallClock(snapshot.At)
+
+	return &snapshot, nil
+}
@@ -0,0 +1,169 @@
+package sqlprojection
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Row maps column names to values.
+type Row map[string]any
+
+// Op is a change to rows of a projection table, returned by handlers.
+type Op interface {
+	tableName() string
+	sql(t *table) (string, []any, error)
+}
+
+type upsert struct {
+	table string
+	row   Row
+}
+
+// Upsert inserts the row, or overwrites the given non-key columns of the
+// existing row with the same key. A row of only key columns is inserted if
+// it is missing and left alone otherwise.
+func Upsert(table string, row Row) Op {
+	return upsert{table, row}
+}
+
+func (o upsert) tableName() string { return o.table }
+
+func (o upsert) sql(t *table) (string, []any, error) {
+	return insertSQL(t, o.row, func(col string) string {
+		return fmt.Sprintf("%s = EXCLUDED.%s", col, col)
+	})
+}
+
+type increment struct {
+	table  string
+	key    Row
+	deltas Row
+}
+
+// Increment adds the deltas to the columns of the row with the given key,
+// inserting the row with the deltas as values if it is missing.
+func Increment(table string, key Row, deltas Row) Op {
+	return increment{table, key, deltas}
+}
+
+func (o increment) tableName() string { return o.table }
+
+func (o increment) sql(t *table) (string, []any, error) {
+	row := Row{}
+	for k, v := range o.key {
+		row[k] = v
+	}
+	for k, v := range o.deltas {
+		row[k] = v
+	}
+	return insertSQL(t, row, func(col string) string {
+		return fmt.Sprintf("%s = %s.%s + EXCLUDED.%s", col, t.qualified, col, col)
+	})
+}
+
+type update struct {
+	table string
+	match Row
+	set   Row
+}
+
+// Update sets columns on every row matching the given column values.
+func Update(table string, match Row, set Row) Op {
+	return update{table, match, set}
+}
+
+func (o update) tableName() string { return o.table }
+
+func (o update) sql(t *table) (string, []any, error) {
+	if len(o.match) == 0 || len(o.set) == 0 {
+		return "", nil, fmt.Errorf("update of %s needs columns to match and set", o.table)
+	}
+
+	args := []any{}
+	sets := []string{}
+	for _, col := range columns(o.set) {
+		args = append(args, o.set[col])
+		sets = append(sets, fmt.Sprintf("%s = $%d", ident(col), len(args)))
+	}
+	where, args := whereSQL(o.match, args)
+	return fmt.Sprintf("UPDATE %s SET %s WHERE %s", t.qualified, strings.Join(sets, ", "), where), args, nil
+}
+
+type deleteRows struct {
+	table string
+	match Row
+}
+
+// Delete removes every row matching the given column values.
+func Delete(table string, match Row) Op {
+	return deleteRows{table, match}
+}
+
+func (o deleteRows) tableName() string { return o.table }
+
+func (o deleteRows) sql(t *table) (string, []any, error) {
+	if len(o.match) == 0 {
+		return "", nil, fmt.Errorf("delete from %s needs columns to match", o.table)
+	}
+
+	where, args := whereSQL(o.match, nil)
+	return fmt.Sprintf("DELETE FROM %s WHERE %s", t.qualified, where), args, nil
+}
+
+// insertSQL builds an INSERT of the row that, on a key conflict, updates each
+// non-key column of the row with the assignment returned by onConflict.
+func insertSQL(t *table, row Row, onConflict func(col string) string) (string, []any, error) {
+	for _, col := range t.Key {
+		if _, ok := row[col]; !ok {
+			return "", nil, fmt.Errorf("row of %s is missing key column %s", t.Name, col)
+		}
+	}
+
+	cols := columns(row)
+	names := make([]string, len(cols))
+	params := make([]string, len(cols))
+	args := make([]any, len(cols))
+	sets := []string{}
+	for i, col := range cols {
+		names[i] = ident(col)
+		params[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = row[col]
+		if !slices.Contains(t.Key, col) {
+			sets = append(sets, onConflict(ident(col)))
+		}
+	}
+
+	action := "DO NOTHING"
+	if len(sets) > 0 {
+		action = "DO UPDATE SET " + strings.Join(sets, ", ")
+	}
+
+	return fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) %s",
+		t.qualified,
+		strings.Join(names, ", "),
+		strings.Join(params, ", "),
+		identList(t.Key),
+		action,
+	), args, nil
+}
+
+func whereSQL(match Row, args []any) (string, []any) {
+	conds := []string{}
+	for _, col := range columns(match) {
+		args = append(args, match[col])
+		conds = append(conds, fmt.Sprintf("%s = $%d", ident(col), len(args)))
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+func columns(row Row) []string {
+	cols := make([]string, 0, len(row))
+	for col := range row {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+	return cols
+}
@@ -0,0 +1,246 @@
+// Package sqlprojection builds projections into SQL tables from declarations:
+// the tables, their keys and a handler per event type returning row changes.
+// The framework creates the tables, applies each batch of events in one
+// transaction and records the position of the last event applied.
+package sqlprojection
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"es/internal/es"
+	"es/internal/migrate"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Column is a table column and its SQL type, including constraints such as
+// NOT NULL or DEFAULT.
+type Column struct {
+	Name string
+	Type string
+}
+
+// Table declares a table of the read model. Indexes are column lists, e.g.
+// "tenant_id, item_id".
+type Table struct {
+	Name    string
+	Columns []Column
+	Key     []string
+	Indexes []string
+}
+
+type table struct {
+	Table
+	qualified string
+}
+
+// Handler turns an event into changes to the read model.
+type Handler func(es.Event) ([]Op, error)
+
+// Projection is an es.ProjectionWriter into the tables of its own schema,
+// named after the projection.
+type Projection struct {
+	pool     *pgxpool.Pool
+	name     string
+	tables   map[string]*table
+	order    []string
+	handlers map[es.EventType]Handler
+}
+
+func New(pool *pgxpool.Pool, name string) *Projection {
+	return &Projection{
+		pool:     pool,
+		name:     name,
+		tables:   make(map[string]*table),
+		handlers: make(map[es.EventType]Handler),
+	}
+}
+
+// Table declares a table of the read model.
+func (p *Projection) Table(t Table) *Projection {
+	p.tables[t.Name] = &table{
+		Table:     t,
+		qualified: pgx.Identifier{p.name, t.Name}.Sanitize(),
+	}
+	p.order = append(p.order, t.Name)
+	return p
+}
+
+// On registers the handler of an event type.
+func (p *Projection) On(eventType es.EventType, handler Handler) *Projection {
+	p.handlers[eventType] = handler
+	return p
+}
+
+func (p *Projection) Name() string {
+	return p.name
+}
+
+func (p *Projection) SubscribedEvents() []es.EventType {
+	types := make([]es.EventType, 0, len(p.handlers))
+	for eventType := range p.handlers {
+		types = append(types, eventType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// Migrations returns the migration creating the schema of the projection.
+// It is generated from the table declarations, so changing them changes its
+// checksum and ApplyMigration rebuilds the read model from scratch.
+func (p *Projection) Migrations() []migrate.Migration {
+	schema := pgx.Identifier{p.name}.Sanitize()
+	stmts := []string{
+		fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schema),
+		fmt.Sprintf("CREATE SCHEMA %s", schema),
+	}
+
+	for _, name := range p.order {
+		t := p.tables[name]
+		defs := make([]string, 0, len(t.Columns)+1)
+		for _, c := range t.Columns {
+			defs = append(defs, fmt.Sprintf("%s %s", pgx.Identifier{c.Name}.Sanitize(), c.Type))
+		}
+		defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", identList(t.Key)))
+		stmts = append(stmts, fmt.Sprintf("CREATE TABLE %s (\n    %s\n)", t.qualified, strings.Join(defs, ",\n    ")))
+
+		for i, index := range t.Indexes {
+			stmts = append(stmts, fmt.Sprintf(
+				"CREATE INDEX %s ON %s (%s)",
+				pgx.Identifier{fmt.Sprintf("idx_%s_%d", t.Name, i+1)}.Sanitize(), t.qualified, index,
+			))
+		}
+	}
+
+	stmts = append(stmts,
+		fmt.Sprintf("CREATE TABLE %s.last_processed_position (position BIGINT NOT NULL CHECK (position >= 0))", schema),
+		fmt.Sprintf("INSERT INTO %s.last_processed_position (position) VALUES (0)", schema),
+	)
+
+	up := strings.Join(stmts, ";\n\n") + ";\n"
+	down := fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE;\n", schema)
+	return []migrate.Migration{
+		{Version: 1, Name: "create_read_model", Up: up, Down: down, Checksum: migrate.Checksum(up)},
+	}
+}
+
+// ApplyMigration creates the tables, rebuilding them when their declarations
+// changed since they were created.
+func (p *Projection) ApplyMigration(ctx context.Context) error {
+	migrator := migrate.NewMigrator(p.pool, p.name, p.Migrations())
+
+	_, err := migrator.Up(ctx)
+	if !migrate.IsOutdated(err) {
+		return err
+	}
+
+	fmt.Printf("%s schema changed, rebuilding read model\n", p.name)
+	if _, err := migrator.Down(ctx, 1); err != nil {
+		return fmt.Errorf("drop outdated read model: %w", err)
+	}
+	_, err = migrator.Up(ctx)
+	return err
+}
+
+func (p *Projection) LatestPosition(ctx context.Context) (int64, error) {
+	var position int64
+	err := p.pool.QueryRow(ctx, fmt.Sprintf(
+		"SELECT position FROM %s.last_processed_position LIMIT 1",
+		pgx.Identifier{p.name}.Sanitize(),
+	)).Scan(&position)
+	if err != nil {
+		return 0, fmt.Errorf("read latest position: %w", err)
+	}
+	return position, nil
+}
+
+// SavePosition moves the recorded position forward to the given one.
+func (p *Projection) SavePosition(ctx context.Context, position int64) error {
+	_, err := p.pool.Exec(ctx, fmt.Sprintf(
+		"UPDATE %s.last_processed_position SET position = GREATEST(position, $1)",
+		pgx.Identifier{p.name}.Sanitize(),
+	), position)
+	if err != nil {
+		return fmt.Errorf("save position: %w", err)
+	}
+	return nil
+}
+
+// Apply runs the handlers of the events and writes their changes together
+// with the position of the last event in a single transaction.
+func (p *Projection) Apply(ctx context.Context, events ...es.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	batch := &pgx.Batch{}
+	maxPosition := int64(0)
+
+	for _, event := range events {
+		if event.Position > maxPosition {
+			maxPosition = event.Position
+		}
+
+		handler, ok := p.handlers[event.Type]
+		if !ok {
+			continue
+		}
+
+		ops, err := handler(event)
+		if err != nil {
+			return fmt.Errorf("handle %s at position %d: %w", event.Type, event.Position, err)
+		}
+
+		for _, op := range ops {
+			t, ok := p.tables[op.tableName()]
+			if !ok {
+				return fmt.Errorf("handle %s: unknown table %q", event.Type, op.tableName())
+			}
+			query, args, err := op.sql(t)
+			if err != nil {
+				return fmt.Errorf("handle %s: %w", event.Type, err)
+			}
+			batch.Queue(query, args...)
+		}
+	}
+
+	batch.Queue(fmt.Sprintf(
+		"UPDATE %s.last_processed_position SET position = GREATEST(position, $1)",
+		pgx.Identifier{p.name}.Sanitize(),
+	), maxPosition)
+
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	defer func(ctx context.Context) {
+		_ = tx.Rollback(ctx)
+	}(ctx)
+
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("apply changes: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func identList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = ident(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func ident(name string) string {
+	return pgx.Identifier{name}.Sanitize()
+}
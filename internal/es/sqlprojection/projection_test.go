@@ -0,0 +1,99 @@
+package sqlprojection_test
+
+import (
+	"context"
+	"errors"
+	"es/internal/es"
+	"es/internal/es/sqlprojection"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCounterProjection(handler sqlprojection.Handler) *sqlprojection.Projection {
+	return sqlprojection.New(nil, "counters").
+		Table(sqlprojection.Table{
+			Name: "counts",
+			Columns: []sqlprojection.Column{
+				{Name: "tenant_id", Type: "VARCHAR(64) NOT NULL"},
+				{Name: "name", Type: "TEXT NOT NULL"},
+				{Name: "total", Type: "INTEGER NOT NULL"},
+			},
+			Key:     []string{"tenant_id", "name"},
+			Indexes: []string{"total"},
+		}).
+		On("counted", handler).
+		On("added", handler)
+}
+
+func TestProjection(t *testing.T) {
+	t.Run("subscribes to handled events", func(t *testing.T) {
+		p := newCounterProjection(nil)
+
+		assert.Equal(t, "counters", p.Name())
+		assert.Equal(t, []es.EventType{"added", "counted"}, p.SubscribedEvents())
+	})
+
+	t.Run("migration is generated from the tables", func(t *testing.T) {
+		migrations := newCounterProjection(nil).Migrations()
+		require.Len(t, migrations, 1)
+
+		up := migrations[0].Up
+		assert.Contains(t, up, `DROP SCHEMA IF EXISTS "counters" CASCADE;`)
+		assert.Contains(t, up, `CREATE TABLE "counters"."counts" (
+    "tenant_id" VARCHAR(64) NOT NULL,
+    "name" TEXT NOT NULL,
+    "total" INTEGER NOT NULL,
+    PRIMARY KEY ("tenant_id", "name")
+);`)
+		assert.Contains(t, up, `CREATE INDEX "idx_counts_1" ON "counters"."counts" (total);`)
+		assert.Contains(t, up, `"counters".last_processed_position`)
+		assert.Equal(t, `DROP SCHEMA IF EXISTS "counters" CASCADE;`+"\n", migrations[0].Down)
+	})
+
+	t.Run("changing a table changes the checksum", func(t *testing.T) {
+		before := newCounterProjection(nil).Migrations()[0].Checksum
+		after := newCounterProjection(nil).Table(sqlprojection.Table{Name: "more", Key: []string{"id"}}).Migrations()[0].Checksum
+
+		assert.NotEqual(t, before, after)
+	})
+
+	t.Run("handler errors name the event", func(t *testing.T) {
+		p := newCounterProjection(func(es.Event) ([]sqlprojection.Op, error) {
+			return nil, errors.New("boom")
+		})
+
+		err := p.Apply(context.Background(), es.Event{Type: "counted", Position: 7})
+		assert.EqualError(t, err, "handle counted at position 7: boom")
+	})
+
+	t.Run("unknown tables are rejected", func(t *testing.T) {
+		p := newCounterProjection(func(es.Event) ([]sqlprojection.Op, error) {
+			return []sqlprojection.Op{sqlprojection.Upsert("missing", sqlprojection.Row{"id": 1})}, nil
+		})
+
+		err := p.Apply(context.Background(), es.Event{Type: "counted"})
+		assert.EqualError(t, err, `handle counted: unknown table "missing"`)
+	})
+
+	t.Run("rows must include the key", func(t *testing.T) {
+		p := newCounterProjection(func(es.Event) ([]sqlprojection.Op, error) {
+			return []sqlprojection.Op{
+				sqlprojection.Increment("counts", sqlprojection.Row{"name": "a"}, sqlprojection.Row{"total": 1}),
+			}, nil
+		})
+
+		err := p.Apply(context.Background(), es.Event{Type: "counted"})
+		assert.EqualError(t, err, "handle counted: row of counts is missing key column tenant_id")
+	})
+
+	t.Run("deletes must match columns", func(t *testing.T) {
+		p := newCounterProjection(func(es.Event) ([]sqlprojection.Op, error) {
+			return []sqlprojection.Op{sqlprojection.Delete("counts", nil)}, nil
+		})
+
+		err := p.Apply(context.Background(), es.Event{Type: "counted"})
+		assert.EqualError(t, err, "handle counted: delete from counts needs columns to match")
+	})
+}
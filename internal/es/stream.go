@@ -3,17 +3,26 @@ package es
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type EventStream struct {
-	pool *pgxpool.Pool
+	pool       *pgxpool.Pool
+	aggregates map[AggregateType]AggregateFactory
+	schemas    *SchemaRegistry
 }
 
 func NewEventStream(pool *pgxpool.Pool) *EventStream {
 	return &EventStream{
-		pool: pool,
+		pool:       pool,
+		aggregates: make(map[AggregateType]AggregateFactory),
+		schemas:    DefaultSchemas,
 	}
 }
 
@@ -31,22 +40,98 @@ func (s *EventStream) GetMaxPosition(ctx context.Context) (int64, error) {
 	return maxPosition, nil
 }
 
-func (s *EventStream) GetAggregateEvents(ctx context.Context, aggType AggregateType, aggID int) ([]Event, error) {
-	query := `
+const selectEvents = `
 		SELECT
 			position,
+			tenant_id,
 			aggregate_id,
 			aggregate_type,
 			event_type,
 			at,
 			version_id,
-			data
-		FROM events
-		WHERE aggregate_id = $1
-		AND aggregate_type = $2
+			data,
+			COALESCE(hash, '')
+		FROM events`
+
+func (s *EventStream) GetAggregateEvents(ctx context.Context, tenantID string, aggType AggregateType, aggID string) ([]Event, error) {
+	query := selectEvents + `
+		WHERE tenant_id = $1
+		AND aggregate_id = $2
+		AND aggregate_type = $3
 		ORDER BY position ASC`
 
-	rows, err := s.pool.Query(ctx, query, aggID, aggType)
+	return s.queryEvents(ctx, query, tenantID, aggID, aggType)
+}
+
+// EventRange bounds a read of a single aggregate's events. Zero values
+// leave the corresponding side of the range open.
+type EventRange struct {
+	// AfterVersion excludes events up to and including this version.
+	AfterVersion int
+	// UntilVersion excludes events after this version.
+	UntilVersion int
+	// Until excludes events that happened after this time.
+	Until time.Time
+}
+
+// GetAggregateEventsAfter returns the events of an aggregate with a version
+// greater than the given one, e.g. those not yet covered by a snapshot.
+func (s *EventStream) GetAggregateEventsAfter(ctx context.Context, tenantID string, aggType AggregateType, aggID string, versionID int) ([]Event, error) {
+	return s.GetAggregateEventsInRange(ctx, tenantID, aggType, aggID, EventRange{AfterVersion: versionID})
+}
+
+// GetAggregateEventsInRange returns the events of an aggregate within the
+// given range ordered by version, e.g. to rehydrate it as of a point in time.
+func (s *EventStream) GetAggregateEventsInRange(ctx context.Context, tenantID string, aggType AggregateType, aggID string, r EventRange) ([]Event, error) {
+	query := selectEvents + `
+		WHERE tenant_id = $1
+		AND aggregate_id = $2
+		AND aggregate_type = $3
+		AND version_id > $4
+		AND ($5 = 0 OR version_id <= $5)
+		AND ($6::TIMESTAMP IS NULL OR at <= $6)
+		ORDER BY version_id ASC`
+
+	// events.at holds the wall clock of time.Local, see WallClock
+	var until *time.Time
+	if !r.Until.IsZero() {
+		local := r.Until.In(time.Local)
+		until = &local
+	}
+
+	return s.queryEvents(ctx, query, tenantID, aggID, aggType, r.AfterVersion, r.UntilVersion, until)
+}
+
+// GetEvents reads events of every tenant by position. It is meant for
+// projections, which keep the tenant of each event in their read models.
+func (s *EventStream) GetEvents(ctx context.Context, startPos, endPos int64, eventTypes []EventType) ([]Event, error) {
+	query := selectEvents + `
+		WHERE position >= $1 AND position <= $2
+		AND event_type = ANY($3)
+		ORDER BY position ASC`
+
+	return s.queryEvents(ctx, query, startPos, endPos, eventTypes)
+}
+
+// GetTenantEvents reads up to limit events of a tenant after the given
+// position, oldest first. Events of every type are read when eventTypes is
+// empty.
+func (s *EventStream) GetTenantEvents(ctx context.Context, tenantID string, afterPos int64, limit int, eventTypes []EventType) ([]Event, error) {
+	if len(eventTypes) == 0 {
+		eventTypes = nil
+	}
+
+	query := selectEvents + `
+		WHERE tenant_id = $1 AND position > $2
+		AND ($3::TEXT[] IS NULL OR event_type = ANY($3))
+		ORDER BY position ASC
+		LIMIT $4`
+
+	return s.queryEvents(ctx, query, tenantID, afterPos, eventTypes, limit)
+}
+
+func (s *EventStream) queryEvents(ctx context.Context, query string, args ...any) ([]Event, error) {
+	rows, err := s.pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -54,29 +139,11 @@ func (s *EventStream) GetAggregateEvents(ctx context.Context, aggType AggregateT
 
 	events := []Event{}
 	for rows.Next() {
-		var e Event
-		var dataJSON []byte
-
-		err := rows.Scan(
-			&e.Position,
-			&e.AggregateID,
-			&e.AggregateType,
-			&e.Type,
-			&e.At,
-			&e.VersionID,
-			&dataJSON,
-		)
+		e, err := scanEvent(rows)
 		if err != nil {
 			return nil, err
 		}
 
-		// Unmarshal JSON data if present
-		if dataJSON != nil {
-			if err := json.Unmarshal(dataJSON, &e.Data); err != nil {
-				return nil, err
-			}
-		}
-
 		events = append(events, e)
 	}
 
@@ -88,59 +155,304 @@ func (s *EventStream) GetAggregateEvents(ctx context.Context, aggType AggregateT
 	return events, nil
 }
 
-func (s *EventStream) GetEvents(ctx context.Context, startPos, endPos int64, eventTypes []EventType) ([]Event, error) {
+// ErrConcurrencyConflict is returned by Append when the aggregate has been
+// changed since it was loaded, i.e. the events do not follow its last version.
+var ErrConcurrencyConflict = NewError(KindConflict, "aggregate was modified concurrently")
+
+// Append validates events, including their data against the schemas
+// registered for their types, and persists them in a single transaction.
+// Appends to the same aggregate are serialised and must continue from its
+// last version, otherwise ErrConcurrencyConflict is returned. Each event is
+// hashed together with the hash of the previous event of its aggregate, and
+// the computed hash and the position assigned to the event are written back
+// onto the given events. Their times are recorded in time.Local.
+func (s *EventStream) Append(ctx context.Context, events ...Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	defer func(ctx context.Context) {
+		_ = tx.Rollback(ctx)
+	}(ctx)
+
+	type aggregateKey struct {
+		tenantID string
+		aggType  AggregateType
+		aggID    string
+	}
+	lastHash := make(map[aggregateKey]string)
+
+	for i := range events {
+		event := &events[i]
+		if err := event.Validate(); err != nil {
+			return err
+		}
+		if err := s.schemas.Validate(*event); err != nil {
+			return err
+		}
+
+		// at is stored as the wall clock of time.Local and hashed as stored
+		event.At = event.At.In(time.Local)
+
+		key := aggregateKey{event.TenantID, event.AggregateType, event.AggregateID}
+		prevHash, ok := lastHash[key]
+		if !ok {
+			headVersion, headHash, err := lockAggregateHead(ctx, tx, *event)
+			if err != nil {
+				return fmt.Errorf("read aggregate head: %w", err)
+			}
+			if event.VersionID != headVersion+1 {
+				return fmt.Errorf(
+					"%w: expected version %d, got %d",
+					ErrConcurrencyConflict, headVersion+1, event.VersionID,
+				)
+			}
+			prevHash = headHash
+		}
+
+		hash, err := event.ComputeHash(prevHash)
+		if err != nil {
+			return fmt.Errorf("compute hash: %w", err)
+		}
+
+		err = tx.QueryRow(ctx, `
+			INSERT INTO events (tenant_id, aggregate_id, aggregate_type, event_type, at, version_id, data, hash)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING position`,
+			event.TenantID, event.AggregateID, event.AggregateType, event.Type,
+			event.At, event.VersionID, event.Data, hash,
+		).Scan(&event.Position)
+		if err != nil {
+			return fmt.Errorf("insert event: %w", err)
+		}
+
+		event.Hash = hash
+		lastHash[key] = hash
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyAggregate recomputes the hash chain of a single aggregate and
+// returns the first broken link, or nil if the chain is intact.
+func (s *EventStream) VerifyAggregate(ctx context.Context, tenantID string, aggType AggregateType, aggID string) (*ChainBreak, error) {
+	events, err := s.GetAggregateEvents(ctx, tenantID, aggType, aggID)
+	if err != nil {
+		return nil, err
+	}
+
+	anchor, restart, err := chainStart(ctx, s.pool, aggregateRef{tenantID: tenantID, aggType: aggType, aggID: aggID})
+	if err != nil {
+		return nil, err
+	}
+
+	return VerifyRestartedChain(anchor, restart, orderByVersion(events))
+}
+
+// rowQuerier is a pool or a transaction.
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// chainStart returns the archive anchor of an aggregate's hash chain and
+// the version BackfillHashes restarted it at, or 0.
+func chainStart(ctx context.Context, db rowQuerier, ref aggregateRef) (string, int, error) {
+	var anchor string
+	var restart int
+	err := db.QueryRow(ctx, `
+		SELECT
+			COALESCE((
+				SELECT hash
+				FROM event_archive_anchors
+				WHERE tenant_id = $1
+				AND aggregate_type = $2
+				AND aggregate_id = $3
+			), ''),
+			COALESCE((
+				SELECT version_id
+				FROM event_chain_restarts
+				WHERE tenant_id = $1
+				AND aggregate_type = $2
+				AND aggregate_id = $3
+			), 0)`,
+		ref.tenantID, ref.aggType, ref.aggID,
+	).Scan(&anchor, &restart)
+	if err != nil {
+		return "", 0, fmt.Errorf("read chain start: %w", err)
+	}
+	return anchor, restart, nil
+}
+
+// Verify walks the whole event log aggregate by aggregate and returns the
+// first broken link, or nil if every chain is intact.
+func (s *EventStream) Verify(ctx context.Context) (*ChainBreak, error) {
 	query := `
 		SELECT
-			position,
-			aggregate_id,
-			aggregate_type,
-			event_type,
-			at,
-			version_id,
-			data
-		FROM events
-		WHERE position >= $1 AND position <= $2
-		AND event_type = ANY($3)
-		ORDER BY position ASC`
+			e.position,
+			e.tenant_id,
+			e.aggregate_id,
+			e.aggregate_type,
+			e.event_type,
+			e.at,
+			e.version_id,
+			e.data,
+			COALESCE(e.hash, ''),
+			COALESCE(a.hash, ''),
+			COALESCE(r.version_id, 0)
+		FROM events e
+		LEFT JOIN event_archive_anchors a
+		ON a.tenant_id = e.tenant_id
+		AND a.aggregate_id = e.aggregate_id
+		AND a.aggregate_type = e.aggregate_type
+		LEFT JOIN event_chain_restarts r
+		ON r.tenant_id = e.tenant_id
+		AND r.aggregate_id = e.aggregate_id
+		AND r.aggregate_type = e.aggregate_type
+		ORDER BY e.tenant_id, e.aggregate_type, e.aggregate_id, e.version_id, e.position`
 
-	rows, err := s.pool.Query(ctx, query, startPos, endPos, eventTypes)
+	rows, err := s.pool.Query(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var events []Event
+	var chain []Event
+	var anchor string
+	var restart int
 	for rows.Next() {
 		var e Event
 		var dataJSON []byte
+		var eventAnchor string
+		var eventRestart int
 
-		err := rows.Scan(
+		if err := rows.Scan(
 			&e.Position,
+			&e.TenantID,
 			&e.AggregateID,
 			&e.AggregateType,
 			&e.Type,
 			&e.At,
 			&e.VersionID,
 			&dataJSON,
-		)
-		if err != nil {
+			&e.Hash,
+			&eventAnchor,
+			&eventRestart,
+		); err != nil {
 			return nil, err
 		}
+		e.At = WallClock(e.At)
 
-		// Unmarshal JSON data if present
 		if dataJSON != nil {
 			if err := json.Unmarshal(dataJSON, &e.Data); err != nil {
 				return nil, err
 			}
 		}
 
-		events = append(events, e)
+		if len(chain) > 0 {
+			last := chain[len(chain)-1]
+			if last.TenantID != e.TenantID ||
+				last.AggregateType != e.AggregateType ||
+				last.AggregateID != e.AggregateID {
+				if broken, err := VerifyRestartedChain(anchor, restart, chain); err != nil || broken != nil {
+					return broken, err
+				}
+				chain = chain[:0]
+			}
+		}
+
+		if len(chain) == 0 {
+			anchor = eventAnchor
+			restart = eventRestart
+		}
+		chain = append(chain, e)
 	}
 
-	// Check for any errors during iteration
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return events, nil
+	return VerifyRestartedChain(anchor, restart, chain)
+}
+
+// lockAggregateHead locks the aggregate of the event for the rest of the
+// transaction and returns its last version and hash, looking at the archive
+// anchor when all of its events have been archived.
+func lockAggregateHead(ctx context.Context, tx pgx.Tx, event Event) (int, string, error) {
+	lockKey := fmt.Sprintf("%s/%s/%s", event.TenantID, event.AggregateType, event.AggregateID)
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, lockKey); err != nil {
+		return 0, "", err
+	}
+
+	var version int
+	var hash string
+	err := tx.QueryRow(ctx, `
+		SELECT version_id, hash
+		FROM (
+			SELECT version_id, COALESCE(hash, '') AS hash, position
+			FROM events
+			WHERE tenant_id = $1
+			AND aggregate_id = $2
+			AND aggregate_type = $3
+			UNION ALL
+			SELECT version_id, hash, 0
+			FROM event_archive_anchors
+			WHERE tenant_id = $1
+			AND aggregate_id = $2
+			AND aggregate_type = $3
+		) head
+		ORDER BY version_id DESC, position DESC
+		LIMIT 1`,
+		event.TenantID, event.AggregateID, event.AggregateType,
+	).Scan(&version, &hash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, "", nil
+	}
+	return version, hash, err
+}
+
+func orderByVersion(events []Event) []Event {
+	ordered := slices.Clone(events)
+	slices.SortStableFunc(ordered, func(a, b Event) int {
+		return a.VersionID - b.VersionID
+	})
+	return ordered
+}
+
+func scanEvent(rows pgx.Rows) (Event, error) {
+	var e Event
+	var dataJSON []byte
+
+	err := rows.Scan(
+		&e.Position,
+		&e.TenantID,
+		&e.AggregateID,
+		&e.AggregateType,
+		&e.Type,
+		&e.At,
+		&e.VersionID,
+		&dataJSON,
+		&e.Hash,
+	)
+	if err != nil {
+		return Event{}, err
+	}
+	e.At = WallClock(e.At)
+
+	// Unmarshal JSON data if present
+	if dataJSON != nil {
+		if err := json.Unmarshal(dataJSON, &e.Data); err != nil {
+			return Event{}, err
+		}
+	}
+
+	return e, nil
 }
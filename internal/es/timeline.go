@@ -0,0 +1,283 @@
+package es
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+)
+
+// Aggregate is the behaviour the event store needs to replay an aggregate.
+type Aggregate interface {
+	Apply(...Event) error
+}
+
+// AggregateFactory creates an empty aggregate ready to have events replayed.
+type AggregateFactory func(tenantID string, aggID string) Aggregate
+
+// Restorer is implemented by aggregates that can start from a snapshot.
+type Restorer interface {
+	Restore(Snapshot) error
+}
+
+// TimelineEntry is one step of an aggregate's history: the event, the state
+// after applying it and what changed compared to the previous state.
+type TimelineEntry struct {
+	Event Event           `json:"event"`
+	State json.RawMessage `json:"state"`
+	Diff  []Change        `json:"diff"`
+}
+
+// Change is a structural difference between two JSON states. Lists of plain
+// values are compared as bags, so an item added to a list is reported as an
+// "add" of that item rather than a change of every following index. Lists
+// of objects identified by one of elementKeys, like the lines of a cart, are
+// matched by it, so changes of an element have paths like
+// /contents/item_id=42/quantity.
+type Change struct {
+	Path string `json:"path"`
+	Op   string `json:"op"`
+	From any    `json:"from,omitempty"`
+	To   any    `json:"to,omitempty"`
+}
+
+// ErrUnknownAggregateType is returned for aggregate types that have not
+// been registered with the event stream.
+var ErrUnknownAggregateType = NewError(KindNotFound, "unknown aggregate type")
+
+const (
+	ChangeAdd     = "add"
+	ChangeRemove  = "remove"
+	ChangeReplace = "replace"
+)
+
+// RegisterAggregate makes an aggregate type known to the event stream so
+// that its history can be replayed generically, e.g. for timelines.
+func (s *EventStream) RegisterAggregate(aggType AggregateType, factory AggregateFactory) {
+	s.aggregates[aggType] = factory
+}
+
+// Timeline replays a registered aggregate event by event. When the start of
+// its history has been archived the timeline starts from its snapshot.
+func (s *EventStream) Timeline(ctx context.Context, tenantID string, aggType AggregateType, aggID string) ([]TimelineEntry, error) {
+	factory, ok := s.aggregates[aggType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownAggregateType, aggType)
+	}
+
+	aggregate := factory(tenantID, aggID)
+	fromVersion := 0
+
+	snapshot, err := s.GetSnapshot(ctx, tenantID, aggType, aggID)
+	if err != nil {
+		return nil, err
+	}
+
+	if restorer, ok := aggregate.(Restorer); ok && snapshot != nil {
+		first, err := s.GetAggregateEventsInRange(ctx, tenantID, aggType, aggID, EventRange{UntilVersion: 1})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(first) == 0 {
+			if err := restorer.Restore(*snapshot); err != nil {
+				return nil, err
+			}
+			fromVersion = snapshot.VersionID
+		}
+	}
+
+	events, err := s.GetAggregateEventsAfter(ctx, tenantID, aggType, aggID, fromVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return BuildTimeline(aggregate, events)
+}
+
+// BuildTimeline applies the events to the aggregate one at a time, recording
+// its JSON state and the diff from the previous state after each event.
+func BuildTimeline(aggregate Aggregate, events []Event) ([]TimelineEntry, error) {
+	_, prev, err := jsonState(aggregate)
+	if err != nil {
+		return nil, err
+	}
+
+	timeline := []TimelineEntry{}
+	for _, event := range events {
+		if err := aggregate.Apply(event); err != nil {
+			return nil, fmt.Errorf("apply event at version %d: %w", event.VersionID, err)
+		}
+
+		state, next, err := jsonState(aggregate)
+		if err != nil {
+			return nil, err
+		}
+
+		timeline = append(timeline, TimelineEntry{
+			Event: event,
+			State: state,
+			Diff:  Diff(prev, next),
+		})
+		prev = next
+	}
+
+	return timeline, nil
+}
+
+// Diff returns the structural changes between two decoded JSON values.
+func Diff(before, after any) []Change {
+	changes := []Change{}
+	diffValues("", before, after, &changes)
+	return changes
+}
+
+func diffValues(path string, before, after any, changes *[]Change) {
+	switch b := before.(type) {
+	case map[string]any:
+		if a, ok := after.(map[string]any); ok {
+			diffObjects(path, b, a, changes)
+			return
+		}
+	case []any:
+		if a, ok := after.([]any); ok {
+			diffLists(path, b, a, changes)
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		*changes = append(*changes, Change{Path: path, Op: ChangeReplace, From: before, To: after})
+	}
+}
+
+func diffObjects(path string, before, after map[string]any, changes *[]Change) {
+	keys := make([]string, 0, len(before)+len(after))
+	for k := range before {
+		keys = append(keys, k)
+	}
+	for k := range after {
+		if _, ok := before[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		b, inBefore := before[k]
+		a, inAfter := after[k]
+		childPath := path + "/" + k
+
+		switch {
+		case !inBefore:
+			*changes = append(*changes, Change{Path: childPath, Op: ChangeAdd, To: a})
+		case !inAfter:
+			*changes = append(*changes, Change{Path: childPath, Op: ChangeRemove, From: b})
+		default:
+			diffValues(childPath, b, a, changes)
+		}
+	}
+}
+
+// elementKeys are the fields identifying the elements of lists of objects.
+var elementKeys = []string{"item_id"}
+
+func diffLists(path string, before, after []any, changes *[]Change) {
+	if !allScalars(before) || !allScalars(after) {
+		for _, key := range elementKeys {
+			if keyedBy(key, before) && keyedBy(key, after) {
+				diffKeyedLists(path, key, before, after, changes)
+				return
+			}
+		}
+
+		for i := 0; i < max(len(before), len(after)); i++ {
+			childPath := fmt.Sprintf("%s/%d", path, i)
+			switch {
+			case i >= len(before):
+				*changes = append(*changes, Change{Path: childPath, Op: ChangeAdd, To: after[i]})
+			case i >= len(after):
+				*changes = append(*changes, Change{Path: childPath, Op: ChangeRemove, From: before[i]})
+			default:
+				diffValues(childPath, before[i], after[i], changes)
+			}
+		}
+		return
+	}
+
+	remaining := slices.Clone(after)
+	for _, b := range before {
+		if i := slices.IndexFunc(remaining, func(a any) bool { return a == b }); i >= 0 {
+			remaining = slices.Delete(remaining, i, i+1)
+			continue
+		}
+		*changes = append(*changes, Change{Path: path, Op: ChangeRemove, From: b})
+	}
+	for _, a := range remaining {
+		*changes = append(*changes, Change{Path: path, Op: ChangeAdd, To: a})
+	}
+}
+
+// diffKeyedLists matches the elements of two lists by their key, reporting
+// elements only in one of them like those of scalar lists.
+func diffKeyedLists(path string, key string, before, after []any, changes *[]Change) {
+	id := func(v any) any { return v.(map[string]any)[key] }
+
+	remaining := slices.Clone(after)
+	for _, b := range before {
+		i := slices.IndexFunc(remaining, func(a any) bool { return id(a) == id(b) })
+		if i < 0 {
+			*changes = append(*changes, Change{Path: path, Op: ChangeRemove, From: b})
+			continue
+		}
+		diffValues(fmt.Sprintf("%s/%s=%v", path, key, id(b)), b, remaining[i], changes)
+		remaining = slices.Delete(remaining, i, i+1)
+	}
+	for _, a := range remaining {
+		*changes = append(*changes, Change{Path: path, Op: ChangeAdd, To: a})
+	}
+}
+
+// keyedBy reports whether every element of the list is an object with a
+// distinct scalar value of the key.
+func keyedBy(key string, values []any) bool {
+	seen := map[any]bool{}
+	for _, v := range values {
+		object, ok := v.(map[string]any)
+		if !ok {
+			return false
+		}
+		id, ok := object[key]
+		if !ok || id == nil || !allScalars([]any{id}) || seen[id] {
+			return false
+		}
+		seen[id] = true
+	}
+	return true
+}
+
+func allScalars(values []any) bool {
+	for _, v := range values {
+		switch v.(type) {
+		case map[string]any, []any:
+			return false
+		}
+	}
+	return true
+}
+
+func jsonState(aggregate Aggregate) (json.RawMessage, any, error) {
+	raw, err := json.Marshal(aggregate)
+	if err != nil {
+		return nil, nil, fmt.Errorf("marshal aggregate state: %w", err)
+	}
+
+	var decoded any
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return nil, nil, err
+	}
+
+	return raw, decoded, nil
+}
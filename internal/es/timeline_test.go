@@ -0,0 +1,117 @@
+package es_test
+
+import (
+	"errors"
+	"es/internal/es"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type counter struct {
+	Items []int `json:"items"`
+	Done  bool  `json:"done"`
+}
+
+func (c *counter) Apply(events ...es.Event) error {
+	for _, e := range events {
+		switch e.Type {
+		case "added":
+			c.Items = append(c.Items, e.Data.(int))
+		case "done":
+			c.Done = true
+		default:
+			return errors.New("not implemented")
+		}
+	}
+	return nil
+}
+
+func TestBuildTimeline(t *testing.T) {
+	t.Run("diff per event", func(t *testing.T) {
+		timeline, err := es.BuildTimeline(&counter{Items: []int{}}, []es.Event{
+			{Type: "added", VersionID: 1, Data: 42},
+			{Type: "added", VersionID: 2, Data: 43},
+			{Type: "done", VersionID: 3},
+		})
+		require.NoError(t, err)
+		require.Len(t, timeline, 3)
+
+		assert.JSONEq(t, `{"items":[42],"done":false}`, string(timeline[0].State))
+		assert.Equal(t, []es.Change{
+			{Path: "/items", Op: es.ChangeAdd, To: float64(42)},
+		}, timeline[0].Diff)
+
+		assert.Equal(t, []es.Change{
+			{Path: "/items", Op: es.ChangeAdd, To: float64(43)},
+		}, timeline[1].Diff)
+
+		assert.JSONEq(t, `{"items":[42,43],"done":true}`, string(timeline[2].State))
+		assert.Equal(t, []es.Change{
+			{Path: "/done", Op: es.ChangeReplace, From: false, To: true},
+		}, timeline[2].Diff)
+	})
+
+	t.Run("apply error reports the version", func(t *testing.T) {
+		_, err := es.BuildTimeline(&counter{}, []es.Event{
+			{Type: "unknown", VersionID: 7},
+		})
+		assert.EqualError(t, err, "apply event at version 7: not implemented")
+	})
+}
+
+func TestDiff(t *testing.T) {
+	t.Run("scalar lists are compared as bags", func(t *testing.T) {
+		changes := es.Diff(
+			map[string]any{"contents": []any{10.0, 20.0, 20.0, 30.0}},
+			map[string]any{"contents": []any{10.0, 30.0, 20.0, 40.0}},
+		)
+
+		assert.Equal(t, []es.Change{
+			{Path: "/contents", Op: es.ChangeRemove, From: 20.0},
+			{Path: "/contents", Op: es.ChangeAdd, To: 40.0},
+		}, changes)
+	})
+
+	t.Run("nested objects and keys", func(t *testing.T) {
+		changes := es.Diff(
+			map[string]any{"a": map[string]any{"b": 1.0}, "gone": true},
+			map[string]any{"a": map[string]any{"b": 2.0}, "new": "x"},
+		)
+
+		assert.Equal(t, []es.Change{
+			{Path: "/a/b", Op: es.ChangeReplace, From: 1.0, To: 2.0},
+			{Path: "/gone", Op: es.ChangeRemove, From: true},
+			{Path: "/new", Op: es.ChangeAdd, To: "x"},
+		}, changes)
+	})
+
+	t.Run("cart lines are matched by item", func(t *testing.T) {
+		line := func(itemID, quantity float64) map[string]any {
+			return map[string]any{"item_id": itemID, "quantity": quantity}
+		}
+		changes := es.Diff(
+			map[string]any{"contents": []any{line(7, 1), line(42, 2), line(13, 1)}},
+			map[string]any{"contents": []any{line(42, 5), line(13, 1), line(9, 1)}},
+		)
+
+		assert.Equal(t, []es.Change{
+			{Path: "/contents", Op: es.ChangeRemove, From: line(7, 1)},
+			{Path: "/contents/item_id=42/quantity", Op: es.ChangeReplace, From: 2.0, To: 5.0},
+			{Path: "/contents", Op: es.ChangeAdd, To: line(9, 1)},
+		}, changes, "Removing a line should not change the lines after it")
+	})
+
+	t.Run("lists of objects are compared by index", func(t *testing.T) {
+		changes := es.Diff(
+			[]any{map[string]any{"id": 1.0}},
+			[]any{map[string]any{"id": 2.0}, map[string]any{"id": 3.0}},
+		)
+
+		assert.Equal(t, []es.Change{
+			{Path: "/0/id", Op: es.ChangeReplace, From: 1.0, To: 2.0},
+			{Path: "/1", Op: es.ChangeAdd, To: map[string]any{"id": 3.0}},
+		}, changes)
+	})
+}
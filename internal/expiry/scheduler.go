@@ -0,0 +1,89 @@
+// Package expiry periodically expires carts that have been idle for too
+// long, so the stock they hold is released.
+package expiry
+
+import (
+	"context"
+	"errors"
+	"es/internal/cartsummary"
+	"es/internal/checkout"
+	"es/internal/es"
+	"fmt"
+	"time"
+)
+
+// IdleCartFinder finds open carts that have not changed since idleSince.
+type IdleCartFinder interface {
+	IdleCarts(ctx context.Context, idleSince time.Time, limit int) ([]cartsummary.CartRef, error)
+}
+
+type Scheduler struct {
+	finder    IdleCartFinder
+	bus       *es.CommandBus
+	idleFor   time.Duration
+	batchSize int
+	now       func() time.Time
+}
+
+func NewScheduler(finder IdleCartFinder, bus *es.CommandBus, idleFor time.Duration, batchSize int) *Scheduler {
+	return &Scheduler{
+		finder:    finder,
+		bus:       bus,
+		idleFor:   idleFor,
+		batchSize: batchSize,
+		now:       time.Now,
+	}
+}
+
+// RunOnce expires up to a batch of carts idle for longer than the idle
+// period and returns how many it expired. A cart that fails to expire does
+// not stop the others; the errors are returned together.
+func (s *Scheduler) RunOnce(ctx context.Context) (int, error) {
+	idleSince := s.now().Add(-s.idleFor)
+
+	carts, err := s.finder.IdleCarts(ctx, idleSince, s.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	errs := []error{}
+	for _, ref := range carts {
+		cart, err := es.Dispatch[*checkout.CartAggregate](ctx, s.bus, checkout.ExpireCart{
+			TenantID:  ref.TenantID,
+			CartID:    ref.CartID,
+			IdleSince: idleSince,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("expire cart %s/%s: %w", ref.TenantID, ref.CartID, err))
+			continue
+		}
+		if cart.Expired {
+			expired++
+		}
+	}
+
+	return expired, errors.Join(errs...)
+}
+
+// Run calls RunOnce every interval until the context is done.
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		expired, err := s.RunOnce(ctx)
+		if err != nil {
+			fmt.Printf("expiring idle carts: %v\n", err)
+		}
+		if expired > 0 {
+			fmt.Printf("Expired %d idle carts\n", expired)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
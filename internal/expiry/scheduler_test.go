@@ -0,0 +1,77 @@
+package expiry_test
+
+import (
+	"context"
+	"errors"
+	"es/internal/cartsummary"
+	"es/internal/checkout"
+	"es/internal/es"
+	"es/internal/expiry"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeFinder struct {
+	carts     []cartsummary.CartRef
+	idleSince time.Time
+}
+
+func (f *fakeFinder) IdleCarts(_ context.Context, idleSince time.Time, limit int) ([]cartsummary.CartRef, error) {
+	f.idleSince = idleSince
+	return f.carts[:min(limit, len(f.carts))], nil
+}
+
+func TestScheduler(t *testing.T) {
+	t.Run("expires idle carts", func(t *testing.T) {
+		finder := &fakeFinder{carts: []cartsummary.CartRef{
+			{TenantID: "store-a", CartID: "cart-1001"},
+			{TenantID: "store-b", CartID: "cart-1002"},
+			{TenantID: "store-a", CartID: "cart-1003"},
+		}}
+
+		commands := []checkout.ExpireCart{}
+		bus := es.NewCommandBus()
+		es.RegisterHandler(bus, func(_ context.Context, cmd checkout.ExpireCart) (*checkout.CartAggregate, error) {
+			commands = append(commands, cmd)
+			cart := checkout.NewCartAggregate(cmd.CartID)
+			// The second cart was used since it was found idle.
+			cart.Expired = cmd.CartID != "cart-1002"
+			return cart, nil
+		})
+
+		before := time.Now()
+		expired, err := expiry.NewScheduler(finder, bus, time.Hour, 10).RunOnce(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 2, expired)
+
+		assert.WithinDuration(t, before.Add(-time.Hour), finder.idleSince, time.Second)
+		assert.Len(t, commands, 3)
+		for _, cmd := range commands {
+			assert.Equal(t, finder.idleSince, cmd.IdleSince)
+		}
+		assert.Equal(t, "store-b", commands[1].TenantID)
+	})
+
+	t.Run("failures do not stop the batch", func(t *testing.T) {
+		finder := &fakeFinder{carts: []cartsummary.CartRef{
+			{TenantID: "store-a", CartID: "cart-1001"},
+			{TenantID: "store-a", CartID: "cart-1002"},
+		}}
+
+		bus := es.NewCommandBus()
+		es.RegisterHandler(bus, func(_ context.Context, cmd checkout.ExpireCart) (*checkout.CartAggregate, error) {
+			if cmd.CartID == "cart-1001" {
+				return nil, errors.New("boom")
+			}
+			cart := checkout.NewCartAggregate(cmd.CartID)
+			cart.Expired = true
+			return cart, nil
+		})
+
+		expired, err := expiry.NewScheduler(finder, bus, time.Hour, 10).RunOnce(context.Background())
+		assert.EqualError(t, err, "expire cart store-a/cart-1001: boom")
+		assert.Equal(t, 1, expired)
+	})
+}
@@ -0,0 +1,158 @@
+package graphqlapi
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"es/internal/authentication"
+	"es/internal/checkout"
+	"es/internal/es"
+	"es/internal/openapi"
+	"es/internal/tenant"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/graph-gophers/graphql-go"
+)
+
+type RouteHandler struct {
+	schema    *graphql.Schema
+	heartbeat time.Duration
+}
+
+// NewRouteHandler serves the schema. Server-sent event streams get a comment
+// every heartbeat, so a client that has gone away is noticed even while its
+// subscription has nothing to send.
+func NewRouteHandler(schema *graphql.Schema, heartbeat time.Duration) *RouteHandler {
+	return &RouteHandler{
+		schema:    schema,
+		heartbeat: heartbeat,
+	}
+}
+
+// Operations documents the handlers of RouteHandler.
+var Operations = struct {
+	Serve openapi.Operation
+}{
+	Serve: openapi.Operation{
+		Summary:     "Execute a GraphQL request",
+		Description: "With Accept: text/event-stream the results arrive as server-sent events.",
+		Tags:        []string{"graphql"},
+		Request:     request{},
+		Responses:   map[int]any{http.StatusOK: map[string]any{}},
+	},
+}
+
+type request struct {
+	Query         string         `json:"query" openapi:"required"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// Serve executes a GraphQL request. Requests accepting text/event-stream
+// get their responses as server-sent events, one next event per response
+// followed by a complete event, which is how subscriptions are delivered.
+// Comments are sent in between as heartbeats.
+func (h *RouteHandler) Serve(c *fiber.Ctx) error {
+	tenantID, err := tenant.FromRequest(c)
+
+	if err != nil {
+		return fiber.NewError(http.StatusForbidden, err.Error())
+	}
+
+	var req request
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(http.StatusBadRequest, "body must be a GraphQL request")
+	}
+
+	ctx := tenant.WithTenant(c.UserContext(), tenantID)
+	if claims, ok := c.Locals("user").(jwt.MapClaims); ok {
+		ctx = authentication.WithClaims(ctx, claims)
+	}
+
+	if !strings.Contains(c.Get(fiber.HeaderAccept), "text/event-stream") {
+		return c.Status(http.StatusOK).JSON(h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	responses, err := h.schema.Subscribe(ctx, req.Query, req.OperationName, req.Variables)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+
+		ticker := time.NewTicker(h.heartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case response, ok := <-responses:
+				if !ok {
+					fmt.Fprint(w, "event: complete\ndata:\n\n")
+					_ = w.Flush()
+					return
+				}
+				data, err := json.Marshal(response)
+				if err != nil {
+					return
+				}
+				fmt.Fprintf(w, "event: next\ndata: %s\n\n", data)
+			case <-ticker.C:
+				fmt.Fprint(w, ": ping\n\n")
+			}
+			// A failed flush means the client has gone away, which cancels
+			// the subscription.
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+
+	return nil
+}
+
+// resolverError adds a machine readable code to the errors of resolvers,
+// reported in the extensions of the GraphQL error.
+type resolverError struct {
+	error
+	code string
+}
+
+func (e resolverError) Extensions() map[string]any {
+	return map[string]any{"code": e.code}
+}
+
+func (e resolverError) Unwrap() error {
+	return e.error
+}
+
+// toError maps errors from the use cases to coded GraphQL errors by their
+// kind, like problem.Status does for HTTP.
+func toError(err error) error {
+	if errors.Is(err, checkout.ErrCartExpired) {
+		return resolverError{err, "CART_EXPIRED"}
+	}
+	switch es.KindOf(err) {
+	case es.KindNotFound:
+		return resolverError{err, "NOT_FOUND"}
+	case es.KindConflict:
+		return resolverError{err, "CONFLICT"}
+	case es.KindInvalid:
+		return resolverError{err, "BAD_USER_INPUT"}
+	case es.KindGone:
+		return resolverError{err, "GONE"}
+	case es.KindForbidden:
+		return resolverError{err, "FORBIDDEN"}
+	}
+	return err
+}
@@ -0,0 +1,351 @@
+package graphqlapi_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"es/internal/checkout"
+	"es/internal/es"
+	"es/internal/graphqlapi"
+	v2 "es/internal/inventory/v2"
+	"es/internal/tenant"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryCartRepository struct {
+	mu     sync.Mutex
+	carts  map[string]*checkout.CartAggregate
+	events []es.Event
+	// reads counts the reads of events, which fail with readErr if set.
+	reads   int
+	readErr error
+}
+
+func (r *memoryCartRepository) New(_ context.Context, tenantID string, cartID string, options ...checkout.CartOption) (*checkout.CartAggregate, error) {
+	cart := checkout.NewCartAggregate(cartID, append([]checkout.CartOption{checkout.ForTenant(tenantID)}, options...)...)
+	if err := cart.Init(); err != nil {
+		return nil, err
+	}
+	return cart, r.Save(context.Background(), cart)
+}
+
+func (r *memoryCartRepository) Get(_ context.Context, tenantID string, cartID string) (*checkout.CartAggregate, error) {
+	cart, ok := r.carts[cartID]
+	if !ok || cart.TenantID != tenantID {
+		return nil, nil
+	}
+	return cart, nil
+}
+
+func (r *memoryCartRepository) GetAsOf(_ context.Context, _ string, _ string, _ es.EventRange) (*checkout.CartAggregate, error) {
+	return nil, errors.New("not supported")
+}
+
+func (r *memoryCartRepository) Save(_ context.Context, cart *checkout.CartAggregate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, event := range cart.UncommittedEvents() {
+		event.Position = int64(len(r.events) + 1)
+		r.events = append(r.events, event)
+	}
+	cart.Commit()
+	r.carts[cart.ID] = cart
+	return nil
+}
+
+func (r *memoryCartRepository) GetAggregateEvents(ctx context.Context, tenantID string, aggType es.AggregateType, aggID string) ([]es.Event, error) {
+	return r.GetAggregateEventsAfter(ctx, tenantID, aggType, aggID, 0)
+}
+
+func (r *memoryCartRepository) GetAggregateEventsAfter(_ context.Context, tenantID string, _ es.AggregateType, aggID string, versionID int) ([]es.Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reads++
+	if r.readErr != nil {
+		return nil, r.readErr
+	}
+	events := []es.Event{}
+	for _, e := range r.events {
+		if e.TenantID == tenantID && e.AggregateID == aggID && e.VersionID > versionID {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+type fakeItemCounts struct{}
+
+func (fakeItemCounts) GetItemCounts(_ context.Context, _ string) ([]v2.Result, error) {
+	return []v2.Result{
+		{ID: 42, Count: v2.ItemCount{SoldCount: 2, StagedCount: 1}},
+		{ID: 99, Count: v2.ItemCount{SoldCount: 5, StagedCount: 0}},
+	}, nil
+}
+
+func newTestSchema() (*graphql.Schema, *memoryCartRepository) {
+	return newPricedTestSchema(nil)
+}
+
+// newPricedTestSchema is newTestSchema pricing carts with the pricer.
+func newPricedTestSchema(pricer *checkout.Pricer) (*graphql.Schema, *memoryCartRepository) {
+	repo := &memoryCartRepository{carts: map[string]*checkout.CartAggregate{}}
+	bus := es.NewCommandBus(es.Authorization(es.RequireTenant), es.Validation())
+	checkout.RegisterCommandHandlers(bus, repo, nil, pricer)
+
+	schema := graphqlapi.NewSchema(checkout.NewCheckoutUseCase(repo, bus, pricer), fakeItemCounts{}, repo, 10*time.Millisecond)
+	return schema, repo
+}
+
+type memoryCatalog map[int]*checkout.Product
+
+func (c memoryCatalog) FindProduct(_ context.Context, _ string, productID int) (*checkout.Product, error) {
+	return c[productID], nil
+}
+
+func newTestApp(schema *graphql.Schema, heartbeat time.Duration) *fiber.App {
+	app := fiber.New()
+	app.Post("/graphql", func(c *fiber.Ctx) error {
+		c.Locals("user", jwt.MapClaims{"sub": "alice"})
+		tenant.Set(c, "store-a")
+		return c.Next()
+	}, graphqlapi.NewRouteHandler(schema, heartbeat).Serve)
+	return app
+}
+
+type response struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+func post(t *testing.T, app *fiber.App, query string, variables map[string]any) response {
+	t.Helper()
+
+	body, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	res, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var r response
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&r))
+	return r
+}
+
+func TestGraphQL(t *testing.T) {
+	schema, _ := newTestSchema()
+	app := newTestApp(schema, time.Hour)
+
+	created := post(t, app, `mutation { createCart { id owner } }`, nil)
+	require.Empty(t, created.Errors)
+	var cart struct{ ID, Owner string }
+	require.NoError(t, json.Unmarshal(created.Data["createCart"], &cart))
+	assert.Equal(t, "alice", cart.Owner)
+
+	for _, itemID := range []int{42, 42, 7} {
+		r := post(t, app, `mutation($cart: ID!, $item: Int!) { addItem(cartId: $cart, itemId: $item) { version } }`,
+			map[string]any{"cart": cart.ID, "item": itemID})
+		require.Empty(t, r.Errors)
+	}
+
+	t.Run("cart with inventory levels and events in one request", func(t *testing.T) {
+		r := post(t, app, `query($id: ID!) {
+			cart(id: $id) {
+				contents
+				lines { itemId quantity }
+				items { itemId sold reserved }
+				events { type version data }
+			}
+		}`, map[string]any{"id": cart.ID})
+		require.Empty(t, r.Errors)
+		assert.JSONEq(t, `{
+			"contents": [42, 42, 7],
+			"lines": [{"itemId": 42, "quantity": 2}, {"itemId": 7, "quantity": 1}],
+			"items": [{"itemId": 42, "sold": 2, "reserved": 1}],
+			"events": [
+				{"type": "cart.created", "version": 1, "data": "{\"owner\":\"alice\"}"},
+				{"type": "cart.item_added", "version": 2, "data": "{\"item_id\":42}"},
+				{"type": "cart.item_added", "version": 3, "data": "{\"item_id\":42}"},
+				{"type": "cart.item_added", "version": 4, "data": "{\"item_id\":7}"}
+			]
+		}`, string(r.Data["cart"]))
+	})
+
+	t.Run("unknown cart is null", func(t *testing.T) {
+		r := post(t, app, `{ cart(id: "cart-9999") { id } }`, nil)
+		require.Empty(t, r.Errors)
+		assert.JSONEq(t, `null`, string(r.Data["cart"]))
+	})
+
+	t.Run("mutation errors carry a code", func(t *testing.T) {
+		r := post(t, app, `mutation { checkout(cartId: "cart-9999") { id } }`, nil)
+		require.Len(t, r.Errors, 1)
+		assert.Equal(t, "NOT_FOUND", r.Errors[0].Extensions["code"])
+	})
+
+	t.Run("responses as server-sent events", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader([]byte(`{"query": "{ inventory { itemId } }"}`)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "text/event-stream")
+		res, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		assert.Equal(t,
+			"event: next\ndata: {\"data\":{\"inventory\":[{\"itemId\":42},{\"itemId\":99}]}}\n\n"+
+				"event: complete\ndata:\n\n",
+			string(body))
+	})
+}
+
+func TestTotals(t *testing.T) {
+	pricer := checkout.NewPricer(memoryCatalog{42: {ID: 42, Price: 1_500_000_000}}, checkout.PricingConfig{
+		DefaultRegion: "DE",
+		Regions:       map[string][]checkout.TaxRule{"DE": {{Name: "VAT", Rate: 1900}}},
+	})
+	schema, _ := newPricedTestSchema(pricer)
+	app := newTestApp(schema, time.Hour)
+
+	created := post(t, app, `mutation { createCart { id } }`, nil)
+	require.Empty(t, created.Errors)
+	var cart struct{ ID string }
+	require.NoError(t, json.Unmarshal(created.Data["createCart"], &cart))
+	r := post(t, app, `mutation($cart: ID!) { setItemQuantity(cartId: $cart, itemId: 42, quantity: 2) { version } }`,
+		map[string]any{"cart": cart.ID})
+	require.Empty(t, r.Errors)
+
+	r = post(t, app, `query($id: ID!) {
+		cart(id: $id) {
+			totals {
+				lines { itemId quantity unitPrice total }
+				subtotal
+				taxes { name rate amount }
+				tax
+				total
+			}
+		}
+	}`, map[string]any{"id": cart.ID})
+	require.Empty(t, r.Errors)
+	assert.JSONEq(t, `{"totals": {
+		"lines": [{"itemId": 42, "quantity": 2, "unitPrice": 1500000000, "total": 3000000000}],
+		"subtotal": 3000000000,
+		"taxes": [{"name": "VAT", "rate": 1900, "amount": 570000000}],
+		"tax": 570000000,
+		"total": 3570000000
+	}}`, string(r.Data["cart"]), "Amounts beyond 32 bits should not wrap")
+}
+
+func TestCartEventsSubscription(t *testing.T) {
+	schema, repo := newTestSchema()
+
+	ctx := tenant.WithTenant(context.Background(), "store-a")
+	cart, err := repo.New(ctx, "store-a", "cart-1001")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	responses, err := schema.Subscribe(ctx, `subscription { cartEvents(cartId: "cart-1001") { type version } }`, "", nil)
+	require.NoError(t, err)
+
+	first := (<-responses).(*graphql.Response)
+	require.Empty(t, first.Errors)
+	assert.JSONEq(t, `{"cartEvents": {"type": "cart.created", "version": 1}}`, string(first.Data))
+
+	// Events appended later are picked up by the next poll.
+	require.NoError(t, cart.Add(42))
+	require.NoError(t, repo.Save(ctx, cart))
+
+	second := (<-responses).(*graphql.Response)
+	require.Empty(t, second.Errors)
+	assert.JSONEq(t, `{"cartEvents": {"type": "cart.item_added", "version": 2}}`, string(second.Data))
+
+	t.Run("ends with the error when reading events fails", func(t *testing.T) {
+		repo.mu.Lock()
+		repo.readErr = errors.New("connection reset")
+		repo.mu.Unlock()
+
+		failed := (<-responses).(*graphql.Response)
+		require.NotEmpty(t, failed.Errors)
+		assert.Equal(t, "read cart events: connection reset", failed.Errors[0].Message)
+
+		_, open := <-responses
+		assert.False(t, open)
+	})
+}
+
+func TestCartEventsStream(t *testing.T) {
+	schema, repo := newTestSchema()
+	_, err := repo.New(context.Background(), "store-a", "cart-1001")
+	require.NoError(t, err)
+
+	app := newTestApp(schema, 10*time.Millisecond)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = app.Listener(listener) }()
+	t.Cleanup(func() { _ = app.ShutdownWithTimeout(time.Second) })
+
+	ctx, disconnect := context.WithCancel(context.Background())
+	defer disconnect()
+	body := `{"query": "subscription { cartEvents(cartId: \"cart-1001\") { version } }"}`
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+listener.Addr().String()+"/graphql", strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	stream := bufio.NewReader(res.Body)
+	line, err := stream.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "event: next\n", line)
+
+	t.Run("sends heartbeats while nothing happens", func(t *testing.T) {
+		for line != ": ping\n" {
+			line, err = stream.ReadString('\n')
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("stops polling once the client has gone away", func(t *testing.T) {
+		disconnect()
+
+		assert.Eventually(t, func() bool {
+			repo.mu.Lock()
+			before := repo.reads
+			repo.mu.Unlock()
+
+			time.Sleep(50 * time.Millisecond)
+
+			repo.mu.Lock()
+			defer repo.mu.Unlock()
+			return repo.reads == before
+		}, 2*time.Second, 10*time.Millisecond)
+	})
+}
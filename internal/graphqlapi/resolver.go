@@ -0,0 +1,406 @@
+// Package graphqlapi serves carts, their inventory levels and event history
+// over GraphQL, with mutations dispatched through CheckoutUseCase and a
+// subscription for live cart events.
+package graphqlapi
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"es/internal/authentication"
+	"es/internal/checkout"
+	"es/internal/es"
+	v2 "es/internal/inventory/v2"
+	"es/internal/tenant"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+// CartEventReader reads the events of an aggregate, see es.EventStream.
+type CartEventReader interface {
+	GetAggregateEvents(ctx context.Context, tenantID string, aggType es.AggregateType, aggID string) ([]es.Event, error)
+	GetAggregateEventsAfter(ctx context.Context, tenantID string, aggType es.AggregateType, aggID string, versionID int) ([]es.Event, error)
+}
+
+type Resolver struct {
+	usecase      *checkout.CheckoutUseCase
+	itemCounts   v2.ItemCountRepository
+	events       CartEventReader
+	pollInterval time.Duration
+}
+
+// NewSchema parses the schema with resolvers backed by the given use case
+// and repositories. Subscriptions poll for new events every pollInterval.
+func NewSchema(
+	usecase *checkout.CheckoutUseCase,
+	itemCounts v2.ItemCountRepository,
+	events CartEventReader,
+	pollInterval time.Duration,
+) *graphql.Schema {
+	return graphql.MustParseSchema(schemaSDL, &Resolver{
+		usecase:      usecase,
+		itemCounts:   itemCounts,
+		events:       events,
+		pollInterval: pollInterval,
+	})
+}
+
+func (r *Resolver) Cart(ctx context.Context, args struct{ ID graphql.ID }) (*cartResolver, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := r.usecase.GetCartDetails(ctx, tenantID, string(args.ID))
+	if errors.Is(err, checkout.ErrCartNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, toError(err)
+	}
+	return r.cart(tenantID, cart), nil
+}
+
+func (r *Resolver) Inventory(ctx context.Context) ([]*inventoryItemResolver, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := r.itemCounts.GetItemCounts(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*inventoryItemResolver, len(results))
+	for i, result := range results {
+		items[i] = &inventoryItemResolver{result}
+	}
+	return items, nil
+}
+
+func (r *Resolver) CreateCart(ctx context.Context) (*cartResolver, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := r.usecase.CreateCart(ctx, tenantID, authentication.SubjectFromContext(ctx), "")
+	if err != nil {
+		return nil, toError(err)
+	}
+	return r.cart(tenantID, cart), nil
+}
+
+type itemArgs struct {
+	CartID graphql.ID
+	ItemID int32
+}
+
+func (r *Resolver) AddItem(ctx context.Context, args itemArgs) (*cartResolver, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := r.usecase.AddItemToCart(ctx, tenantID, string(args.CartID), int(args.ItemID), 0)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return r.cart(tenantID, cart), nil
+}
+
+func (r *Resolver) RemoveItem(ctx context.Context, args itemArgs) (*cartResolver, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := r.usecase.RemoveItemFromCart(ctx, tenantID, string(args.CartID), int(args.ItemID), 0)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return r.cart(tenantID, cart), nil
+}
+
+func (r *Resolver) SetItemQuantity(ctx context.Context, args struct {
+	CartID   graphql.ID
+	ItemID   int32
+	Quantity int32
+}) (*cartResolver, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := r.usecase.SetItemQuantity(ctx, tenantID, string(args.CartID), int(args.ItemID), int(args.Quantity), 0)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return r.cart(tenantID, cart), nil
+}
+
+func (r *Resolver) Checkout(ctx context.Context, args struct{ CartID graphql.ID }) (*cartResolver, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := r.usecase.Checkout(ctx, tenantID, string(args.CartID), 0)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return r.cart(tenantID, cart), nil
+}
+
+// CartEvents sends the cart's events after the given version, then polls
+// for new ones until the subscription's context is done. If reading events
+// fails, the subscription ends with a result carrying the error.
+func (r *Resolver) CartEvents(ctx context.Context, args struct {
+	CartID       graphql.ID
+	AfterVersion *int32
+}) (<-chan *eventResolver, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	version := 0
+	if args.AfterVersion != nil {
+		version = int(*args.AfterVersion)
+	}
+
+	ch := make(chan *eventResolver)
+	go func() {
+		defer close(ch)
+
+		ticker := time.NewTicker(r.pollInterval)
+		defer ticker.Stop()
+
+		for {
+			events, err := r.events.GetAggregateEventsAfter(ctx, tenantID, checkout.CartType, string(args.CartID), version)
+			if err != nil {
+				select {
+				case ch <- &eventResolver{err: fmt.Errorf("read cart events: %w", err)}:
+				case <-ctx.Done():
+				}
+				return
+			}
+
+			for _, event := range events {
+				select {
+				case ch <- &eventResolver{event: event}:
+					version = event.VersionID
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+func (r *Resolver) cart(tenantID string, cart *checkout.CartAggregate) *cartResolver {
+	return &cartResolver{root: r, tenantID: tenantID, cart: cart}
+}
+
+type cartResolver struct {
+	root     *Resolver
+	tenantID string
+	cart     *checkout.CartAggregate
+}
+
+func (c *cartResolver) ID() graphql.ID { return graphql.ID(c.cart.ID) }
+
+func (c *cartResolver) Owner() *string {
+	if c.cart.Owner == "" {
+		return nil
+	}
+	return &c.cart.Owner
+}
+
+func (c *cartResolver) Contents() []int32 {
+	items := c.cart.Contents.Items()
+	contents := make([]int32, len(items))
+	for i, itemID := range items {
+		contents[i] = int32(itemID)
+	}
+	return contents
+}
+
+func (c *cartResolver) Lines() []*lineResolver {
+	lines := make([]*lineResolver, len(c.cart.Contents))
+	for i, line := range c.cart.Contents {
+		lines[i] = &lineResolver{line}
+	}
+	return lines
+}
+
+func (c *cartResolver) Items(ctx context.Context) ([]*inventoryItemResolver, error) {
+	results, err := c.root.itemCounts.GetItemCounts(ctx, c.tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []*inventoryItemResolver{}
+	for _, result := range results {
+		if c.cart.Contents.Quantity(result.ID) > 0 {
+			items = append(items, &inventoryItemResolver{result})
+		}
+	}
+	return items, nil
+}
+
+func (c *cartResolver) Region() *string {
+	if c.cart.Region == "" {
+		return nil
+	}
+	return &c.cart.Region
+}
+
+func (c *cartResolver) Totals() *totalsResolver {
+	if c.cart.Totals == nil {
+		return nil
+	}
+	return &totalsResolver{c.cart.Totals}
+}
+
+func (c *cartResolver) CheckedOut() bool { return c.cart.CheckedOut }
+func (c *cartResolver) Expired() bool    { return c.cart.Expired }
+func (c *cartResolver) Version() int32   { return int32(c.cart.Version()) }
+
+func (c *cartResolver) UpdatedAt() *string {
+	if c.cart.UpdatedAt.IsZero() {
+		return nil
+	}
+	at := c.cart.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	return &at
+}
+
+func (c *cartResolver) Events(ctx context.Context) ([]*eventResolver, error) {
+	events, err := c.root.events.GetAggregateEvents(ctx, c.tenantID, checkout.CartType, c.cart.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvers := make([]*eventResolver, len(events))
+	for i, event := range events {
+		resolvers[i] = &eventResolver{event: event}
+	}
+	return resolvers, nil
+}
+
+type lineResolver struct {
+	line checkout.Line
+}
+
+func (l *lineResolver) ItemID() int32   { return int32(l.line.ItemID) }
+func (l *lineResolver) Quantity() int32 { return int32(l.line.Quantity) }
+
+type totalsResolver struct {
+	totals *checkout.Totals
+}
+
+func (t *totalsResolver) Lines() []*pricedLineResolver {
+	lines := make([]*pricedLineResolver, len(t.totals.Lines))
+	for i, line := range t.totals.Lines {
+		lines[i] = &pricedLineResolver{line}
+	}
+	return lines
+}
+
+func (t *totalsResolver) Taxes() []*taxResolver {
+	taxes := make([]*taxResolver, len(t.totals.Taxes))
+	for i, tax := range t.totals.Taxes {
+		taxes[i] = &taxResolver{tax}
+	}
+	return taxes
+}
+
+// Amounts are Float, whose 53 bit integers hold any amount of cents exactly,
+// since Int is 32 bits.
+func (t *totalsResolver) Subtotal() float64 { return float64(t.totals.Subtotal) }
+func (t *totalsResolver) Tax() float64      { return float64(t.totals.Tax) }
+func (t *totalsResolver) Total() float64    { return float64(t.totals.Total) }
+
+type pricedLineResolver struct {
+	line checkout.PricedLine
+}
+
+func (l *pricedLineResolver) ItemID() int32      { return int32(l.line.ItemID) }
+func (l *pricedLineResolver) Quantity() int32    { return int32(l.line.Quantity) }
+func (l *pricedLineResolver) UnitPrice() float64 { return float64(l.line.UnitPrice) }
+func (l *pricedLineResolver) Total() float64     { return float64(l.line.Total) }
+
+type taxResolver struct {
+	tax checkout.Tax
+}
+
+func (t *taxResolver) Name() string    { return t.tax.Name }
+func (t *taxResolver) Rate() int32     { return int32(t.tax.Rate) }
+func (t *taxResolver) Amount() float64 { return float64(t.tax.Amount) }
+
+type inventoryItemResolver struct {
+	result v2.Result
+}
+
+func (i *inventoryItemResolver) ItemID() int32   { return int32(i.result.ID) }
+func (i *inventoryItemResolver) Sold() int32     { return int32(i.result.Count.SoldCount) }
+func (i *inventoryItemResolver) Reserved() int32 { return int32(i.result.Count.StagedCount) }
+
+// eventResolver resolves an event. A subscription that fails sends one with
+// err set instead of an event, which every field reports.
+type eventResolver struct {
+	event es.Event
+	err   error
+}
+
+func (e *eventResolver) Position() (graphql.ID, error) {
+	return graphql.ID(strconv.FormatInt(e.event.Position, 10)), e.err
+}
+
+func (e *eventResolver) Type() (string, error) {
+	return string(e.event.Type), e.err
+}
+
+func (e *eventResolver) AggregateType() (string, error) {
+	return string(e.event.AggregateType), e.err
+}
+
+func (e *eventResolver) AggregateID() (graphql.ID, error) {
+	return graphql.ID(e.event.AggregateID), e.err
+}
+
+func (e *eventResolver) Version() (int32, error) {
+	return int32(e.event.VersionID), e.err
+}
+
+func (e *eventResolver) At() (string, error) {
+	return e.event.At.UTC().Format(time.RFC3339Nano), e.err
+}
+
+func (e *eventResolver) Hash() (string, error) {
+	return e.event.Hash, e.err
+}
+
+func (e *eventResolver) Data() (string, error) {
+	if e.err != nil {
+		return "", e.err
+	}
+	data, err := json.Marshal(e.event.Data)
+	return string(data), err
+}
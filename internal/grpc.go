@@ -0,0 +1,28 @@
+package internal
+
+import (
+	"fmt"
+
+	"es/internal/authentication"
+	"es/internal/es"
+	"es/internal/grpcapi"
+	v2 "es/internal/inventory/v2"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/grpc"
+)
+
+// NewGRPCServer serves the same use cases as NewApi over gRPC.
+func NewGRPCServer(pool *pgxpool.Pool) *grpc.Server {
+	auth, err := authentication.NewAuthenticator(authentication.LoadConfig())
+	if err != nil {
+		panic(fmt.Sprintf("failed to initialize authenticator: %v", err))
+	}
+
+	return grpcapi.NewServer(
+		auth,
+		newCheckoutUseCase(pool),
+		v2.NewPGItemCountRepository(pool),
+		es.NewEventStream(pool),
+	)
+}
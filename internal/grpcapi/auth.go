@@ -0,0 +1,73 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"es/internal/authentication"
+	"es/internal/tenant"
+
+	"github.com/golang-jwt/jwt/v4"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Authenticator verifies the value of an authorization header, see
+// authentication.Authenticator.
+type Authenticator interface {
+	Authenticate(ctx context.Context, authorization string) (jwt.MapClaims, string, error)
+}
+
+// UnaryAuthInterceptor rejects calls without a valid bearer token in the
+// authorization metadata and scopes the others to the tenant of the token,
+// like authentication.AuthMiddleware does for HTTP requests.
+func UnaryAuthInterceptor(auth Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, auth)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuthInterceptor is UnaryAuthInterceptor for streaming calls.
+func StreamAuthInterceptor(auth Authenticator) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), auth)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func authenticate(ctx context.Context, auth Authenticator) (context.Context, error) {
+	authorization := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			authorization = values[0]
+		}
+	}
+
+	claims, tenantID, err := auth.Authenticate(ctx, authorization)
+	if errors.Is(err, authentication.ErrMissingTenantClaim) {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	ctx = authentication.WithClaims(ctx, claims)
+	return tenant.WithTenant(ctx, tenantID), nil
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,186 @@
+package grpcapi
+
+import (
+	"context"
+	"es/internal/authentication"
+	"es/internal/checkout"
+	"es/internal/grpcapi/cartv1"
+	"es/internal/tenant"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type CartServer struct {
+	cartv1.UnimplementedCartServiceServer
+	usecase *checkout.CheckoutUseCase
+}
+
+func NewCartServer(usecase *checkout.CheckoutUseCase) *CartServer {
+	return &CartServer{
+		usecase: usecase,
+	}
+}
+
+func (s *CartServer) CreateCart(ctx context.Context, req *cartv1.CreateCartRequest) (*cartv1.Cart, error) {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := s.usecase.CreateCart(ctx, tenantID, authentication.SubjectFromContext(ctx), req.GetRegion())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toCart(cart), nil
+}
+
+func (s *CartServer) GetCart(ctx context.Context, req *cartv1.GetCartRequest) (*cartv1.Cart, error) {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := s.usecase.GetCartDetails(ctx, tenantID, req.GetCartId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toCart(cart), nil
+}
+
+func (s *CartServer) AddItem(ctx context.Context, req *cartv1.AddItemRequest) (*cartv1.Cart, error) {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := s.usecase.AddItemsToCart(ctx, tenantID, req.GetCartId(), int(req.GetItemId()), int(req.GetQuantity()), 0)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toCart(cart), nil
+}
+
+func (s *CartServer) RemoveItem(ctx context.Context, req *cartv1.RemoveItemRequest) (*cartv1.Cart, error) {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var cart *checkout.CartAggregate
+	if req.GetAll() {
+		cart, err = s.usecase.RemoveAllOfItem(ctx, tenantID, req.GetCartId(), int(req.GetItemId()), 0)
+	} else {
+		cart, err = s.usecase.RemoveItemsFromCart(ctx, tenantID, req.GetCartId(), int(req.GetItemId()), int(req.GetQuantity()), 0)
+	}
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toCart(cart), nil
+}
+
+func (s *CartServer) SetItemQuantity(ctx context.Context, req *cartv1.SetItemQuantityRequest) (*cartv1.Cart, error) {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := s.usecase.SetItemQuantity(ctx, tenantID, req.GetCartId(), int(req.GetItemId()), int(req.GetQuantity()), 0)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toCart(cart), nil
+}
+
+func (s *CartServer) SetContents(ctx context.Context, req *cartv1.SetContentsRequest) (*cartv1.Cart, error) {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make(checkout.Lines, len(req.GetLines()))
+	for i, line := range req.GetLines() {
+		lines[i] = checkout.Line{ItemID: int(line.GetItemId()), Quantity: int(line.GetQuantity())}
+	}
+	cart, err := s.usecase.SetCartContents(ctx, tenantID, req.GetCartId(), lines, 0)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toCart(cart), nil
+}
+
+func (s *CartServer) Checkout(ctx context.Context, req *cartv1.CheckoutRequest) (*cartv1.Cart, error) {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := s.usecase.Checkout(ctx, tenantID, req.GetCartId(), 0)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toCart(cart), nil
+}
+
+func tenantOf(ctx context.Context) (string, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return "", status.Error(codes.PermissionDenied, err.Error())
+	}
+	return tenantID, nil
+}
+
+func toCart(cart *checkout.CartAggregate) *cartv1.Cart {
+	// Contents predates cart lines and lists one entry per unit.
+	items := cart.Contents.Items()
+	contents := make([]int64, len(items))
+	for i, itemID := range items {
+		contents[i] = int64(itemID)
+	}
+	lines := make([]*cartv1.Line, len(cart.Contents))
+	for i, line := range cart.Contents {
+		lines[i] = &cartv1.Line{ItemId: int64(line.ItemID), Quantity: int64(line.Quantity)}
+	}
+
+	return &cartv1.Cart{
+		CartId:     cart.ID,
+		Owner:      cart.Owner,
+		Contents:   contents,
+		Lines:      lines,
+		Region:     cart.Region,
+		Totals:     toTotals(cart.Totals),
+		CheckedOut: cart.CheckedOut,
+		Expired:    cart.Expired,
+		Version:    int64(cart.Version()),
+		UpdatedAt:  timestamppb.New(cart.UpdatedAt),
+	}
+}
+
+func toTotals(totals *checkout.Totals) *cartv1.Totals {
+	if totals == nil {
+		return nil
+	}
+
+	lines := make([]*cartv1.PricedLine, len(totals.Lines))
+	for i, line := range totals.Lines {
+		lines[i] = &cartv1.PricedLine{
+			ItemId:    int64(line.ItemID),
+			Quantity:  int64(line.Quantity),
+			UnitPrice: line.UnitPrice,
+			Total:     line.Total,
+		}
+	}
+	taxes := make([]*cartv1.Tax, len(totals.Taxes))
+	for i, tax := range totals.Taxes {
+		taxes[i] = &cartv1.Tax{Name: tax.Name, Rate: int64(tax.Rate), Amount: tax.Amount}
+	}
+
+	return &cartv1.Totals{
+		Lines:    lines,
+		Subtotal: totals.Subtotal,
+		Taxes:    taxes,
+		Tax:      totals.Tax,
+		Total:    totals.Total,
+	}
+}
@@ -0,0 +1,122 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"es/internal/es"
+	"es/internal/grpcapi/cartv1"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// TenantEventReader reads the events of a tenant by position, see
+// es.EventStream.GetTenantEvents.
+type TenantEventReader interface {
+	GetTenantEvents(ctx context.Context, tenantID string, afterPos int64, limit int, eventTypes []es.EventType) ([]es.Event, error)
+}
+
+type EventServer struct {
+	cartv1.UnimplementedEventServiceServer
+	events       TenantEventReader
+	batchSize    int
+	pollInterval time.Duration
+}
+
+type EventServerOption func(*EventServer)
+
+// PollEvery sets how often a subscription checks for new events once it has
+// caught up.
+func PollEvery(interval time.Duration) EventServerOption {
+	return func(s *EventServer) {
+		s.pollInterval = interval
+	}
+}
+
+func NewEventServer(events TenantEventReader, options ...EventServerOption) *EventServer {
+	s := &EventServer{
+		events:       events,
+		batchSize:    100,
+		pollInterval: time.Second,
+	}
+
+	for _, opt := range options {
+		opt(s)
+	}
+
+	return s
+}
+
+// Subscribe sends the tenant's events after the requested position and
+// then polls for new ones until the client goes away.
+func (s *EventServer) Subscribe(req *cartv1.SubscribeRequest, stream grpc.ServerStreamingServer[cartv1.Event]) error {
+	ctx := stream.Context()
+
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+
+	eventTypes := make([]es.EventType, len(req.GetEventTypes()))
+	for i, eventType := range req.GetEventTypes() {
+		eventTypes[i] = es.EventType(eventType)
+	}
+
+	position := req.GetAfterPosition()
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			events, err := s.events.GetTenantEvents(ctx, tenantID, position, s.batchSize, eventTypes)
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err != nil {
+				return status.Errorf(codes.Internal, "read events: %v", err)
+			}
+
+			for _, event := range events {
+				msg, err := toEvent(event)
+				if err != nil {
+					return status.Errorf(codes.Internal, "encode event %d: %v", event.Position, err)
+				}
+				if err := stream.Send(msg); err != nil {
+					return err
+				}
+				position = event.Position
+			}
+
+			if len(events) < s.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func toEvent(event es.Event) (*cartv1.Event, error) {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cartv1.Event{
+		Position:      event.Position,
+		Type:          string(event.Type),
+		AggregateType: string(event.AggregateType),
+		AggregateId:   event.AggregateID,
+		Version:       int64(event.VersionID),
+		At:            timestamppb.New(event.At),
+		Data:          data,
+		Hash:          event.Hash,
+	}, nil
+}
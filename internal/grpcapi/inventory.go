@@ -0,0 +1,40 @@
+package grpcapi
+
+import (
+	"context"
+	"es/internal/grpcapi/cartv1"
+	v2 "es/internal/inventory/v2"
+)
+
+type InventoryServer struct {
+	cartv1.UnimplementedInventoryServiceServer
+	repo v2.ItemCountRepository
+}
+
+func NewInventoryServer(repo v2.ItemCountRepository) *InventoryServer {
+	return &InventoryServer{
+		repo: repo,
+	}
+}
+
+func (s *InventoryServer) GetItemCounts(ctx context.Context, _ *cartv1.GetItemCountsRequest) (*cartv1.GetItemCountsResponse, error) {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := s.repo.GetItemCounts(ctx, tenantID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	res := &cartv1.GetItemCountsResponse{Items: make([]*cartv1.ItemCount, len(results))}
+	for i, r := range results {
+		res.Items[i] = &cartv1.ItemCount{
+			ItemId:   int64(r.ID),
+			Sold:     int64(r.Count.SoldCount),
+			Reserved: int64(r.Count.StagedCount),
+		}
+	}
+	return res, nil
+}
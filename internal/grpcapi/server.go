@@ -0,0 +1,56 @@
+// Package grpcapi serves cart commands and queries, inventory counts and an
+// event subscription over gRPC. The services are generated from
+// proto/cart/v1/cart.proto into the cartv1 package.
+package grpcapi
+
+import (
+	"errors"
+	"es/internal/checkout"
+	"es/internal/es"
+	"es/internal/grpcapi/cartv1"
+	v2 "es/internal/inventory/v2"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// NewServer creates a gRPC server with every service registered behind the
+// auth interceptors.
+func NewServer(
+	auth Authenticator,
+	usecase *checkout.CheckoutUseCase,
+	itemCounts v2.ItemCountRepository,
+	events TenantEventReader,
+	opts ...grpc.ServerOption,
+) *grpc.Server {
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(UnaryAuthInterceptor(auth)),
+		grpc.ChainStreamInterceptor(StreamAuthInterceptor(auth)),
+	)
+
+	server := grpc.NewServer(opts...)
+	cartv1.RegisterCartServiceServer(server, NewCartServer(usecase))
+	cartv1.RegisterInventoryServiceServer(server, NewInventoryServer(itemCounts))
+	cartv1.RegisterEventServiceServer(server, NewEventServer(events))
+	return server
+}
+
+// toStatus maps errors from the use cases to gRPC status codes by their
+// kind, like problem.Status does for HTTP.
+func toStatus(err error) error {
+	if errors.Is(err, es.ErrConcurrencyConflict) {
+		return status.Error(codes.Aborted, err.Error())
+	}
+	switch es.KindOf(err) {
+	case es.KindNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case es.KindConflict, es.KindGone, es.KindPrecondition:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case es.KindInvalid:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case es.KindForbidden:
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
@@ -0,0 +1,286 @@
+package grpcapi_test
+
+import (
+	"context"
+	"errors"
+	"es/internal/authentication"
+	"es/internal/checkout"
+	"es/internal/es"
+	"es/internal/grpcapi"
+	"es/internal/grpcapi/cartv1"
+	v2 "es/internal/inventory/v2"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeAuthenticator accepts the tokens it knows, mapping them to tenants.
+type fakeAuthenticator map[string]string
+
+func (a fakeAuthenticator) Authenticate(_ context.Context, authorization string) (jwt.MapClaims, string, error) {
+	if authorization == "" {
+		return nil, "", authentication.ErrMissingToken
+	}
+	tenantID, ok := a[authorization]
+	if !ok {
+		return nil, "", authentication.ErrInvalidToken
+	}
+	if tenantID == "" {
+		return nil, "", authentication.ErrMissingTenantClaim
+	}
+	return jwt.MapClaims{"sub": "alice"}, tenantID, nil
+}
+
+type memoryCartRepository struct {
+	carts map[string]*checkout.CartAggregate
+}
+
+func (r *memoryCartRepository) New(_ context.Context, tenantID string, cartID string, options ...checkout.CartOption) (*checkout.CartAggregate, error) {
+	cart := checkout.NewCartAggregate(cartID, append([]checkout.CartOption{checkout.ForTenant(tenantID)}, options...)...)
+	if err := cart.Init(); err != nil {
+		return nil, err
+	}
+	return cart, r.Save(context.Background(), cart)
+}
+
+func (r *memoryCartRepository) Get(_ context.Context, tenantID string, cartID string) (*checkout.CartAggregate, error) {
+	cart, ok := r.carts[cartID]
+	if !ok || cart.TenantID != tenantID {
+		return nil, nil
+	}
+	return cart, nil
+}
+
+func (r *memoryCartRepository) GetAsOf(_ context.Context, _ string, _ string, _ es.EventRange) (*checkout.CartAggregate, error) {
+	return nil, errors.New("not supported")
+}
+
+func (r *memoryCartRepository) Save(_ context.Context, cart *checkout.CartAggregate) error {
+	cart.Commit()
+	r.carts[cart.ID] = cart
+	return nil
+}
+
+type memoryCatalog map[int]*checkout.Product
+
+func (c memoryCatalog) FindProduct(_ context.Context, _ string, productID int) (*checkout.Product, error) {
+	return c[productID], nil
+}
+
+type fakeItemCounts struct{}
+
+func (fakeItemCounts) GetItemCounts(_ context.Context, _ string) ([]v2.Result, error) {
+	return []v2.Result{{ID: 42, Count: v2.ItemCount{SoldCount: 2, StagedCount: 1}}}, nil
+}
+
+type fakeEventReader struct {
+	events []es.Event
+}
+
+func (r *fakeEventReader) GetTenantEvents(_ context.Context, tenantID string, afterPos int64, limit int, _ []es.EventType) ([]es.Event, error) {
+	events := []es.Event{}
+	for _, e := range r.events {
+		if e.TenantID == tenantID && e.Position > afterPos && len(events) < limit {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func dial(t *testing.T, events grpcapi.TenantEventReader) *grpc.ClientConn {
+	t.Helper()
+
+	repo := &memoryCartRepository{carts: map[string]*checkout.CartAggregate{}}
+	bus := es.NewCommandBus(es.Authorization(es.RequireTenant), es.Validation())
+	pricer := checkout.NewPricer(memoryCatalog{42: {ID: 42, Price: 1250}}, checkout.PricingConfig{
+		DefaultRegion: "DE",
+		Regions: map[string][]checkout.TaxRule{
+			"DE": {{Name: "VAT", Rate: 1900}},
+			"CH": {{Name: "VAT", Rate: 810}},
+		},
+	})
+	checkout.RegisterCommandHandlers(bus, repo, nil, pricer)
+
+	server := grpcapi.NewServer(
+		fakeAuthenticator{"Bearer token-a": "store-a", "Bearer token-b": "store-b", "Bearer no-tenant": ""},
+		checkout.NewCheckoutUseCase(repo, bus, pricer),
+		fakeItemCounts{},
+		events,
+	)
+
+	listener := bufconn.Listen(1 << 20)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	client := cartv1.NewInventoryServiceClient(dial(t, &fakeEventReader{}))
+
+	t.Run("missing token", func(t *testing.T) {
+		_, err := client.GetItemCounts(context.Background(), &cartv1.GetItemCountsRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		_, err := client.GetItemCounts(withToken("forged"), &cartv1.GetItemCountsRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("token without tenant", func(t *testing.T) {
+		_, err := client.GetItemCounts(withToken("no-tenant"), &cartv1.GetItemCountsRequest{})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("valid token", func(t *testing.T) {
+		res, err := client.GetItemCounts(withToken("token-a"), &cartv1.GetItemCountsRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, int64(42), res.GetItems()[0].GetItemId())
+		assert.Equal(t, int64(2), res.GetItems()[0].GetSold())
+		assert.Equal(t, int64(1), res.GetItems()[0].GetReserved())
+	})
+}
+
+func TestCartService(t *testing.T) {
+	client := cartv1.NewCartServiceClient(dial(t, &fakeEventReader{}))
+	ctx := withToken("token-a")
+
+	cart, err := client.CreateCart(ctx, &cartv1.CreateCartRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", cart.GetOwner())
+
+	_, err = client.AddItem(ctx, &cartv1.AddItemRequest{CartId: cart.GetCartId(), ItemId: 42})
+	require.NoError(t, err)
+	_, err = client.AddItem(ctx, &cartv1.AddItemRequest{CartId: cart.GetCartId(), ItemId: 43})
+	require.NoError(t, err)
+	_, err = client.RemoveItem(ctx, &cartv1.RemoveItemRequest{CartId: cart.GetCartId(), ItemId: 42})
+	require.NoError(t, err)
+
+	checkedOut, err := client.Checkout(ctx, &cartv1.CheckoutRequest{CartId: cart.GetCartId()})
+	require.NoError(t, err)
+	assert.True(t, checkedOut.GetCheckedOut())
+	assert.Equal(t, int64(5), checkedOut.GetVersion())
+
+	got, err := client.GetCart(ctx, &cartv1.GetCartRequest{CartId: cart.GetCartId()})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{43}, got.GetContents())
+	require.Len(t, got.GetLines(), 1)
+	assert.Equal(t, int64(43), got.GetLines()[0].GetItemId())
+
+	t.Run("region and totals", func(t *testing.T) {
+		cart, err := client.CreateCart(ctx, &cartv1.CreateCartRequest{Region: "CH"})
+		require.NoError(t, err)
+		assert.Equal(t, "CH", cart.GetRegion())
+
+		cart, err = client.AddItem(ctx, &cartv1.AddItemRequest{CartId: cart.GetCartId(), ItemId: 42, Quantity: 2})
+		require.NoError(t, err)
+		totals := cart.GetTotals()
+		require.NotNil(t, totals)
+		require.Len(t, totals.GetLines(), 1)
+		assert.Equal(t, int64(1250), totals.GetLines()[0].GetUnitPrice())
+		assert.Equal(t, int64(2500), totals.GetSubtotal())
+		require.Len(t, totals.GetTaxes(), 1)
+		assert.Equal(t, int64(810), totals.GetTaxes()[0].GetRate())
+		assert.Equal(t, int64(203), totals.GetTax())
+		assert.Equal(t, int64(2703), totals.GetTotal())
+
+		cart, err = client.AddItem(ctx, &cartv1.AddItemRequest{CartId: cart.GetCartId(), ItemId: 7})
+		require.NoError(t, err)
+		assert.Nil(t, cart.GetTotals(), "Carts that cannot be priced should have no totals")
+
+		_, err = client.CreateCart(ctx, &cartv1.CreateCartRequest{Region: "XX"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("quantities", func(t *testing.T) {
+		cart, err := client.CreateCart(ctx, &cartv1.CreateCartRequest{})
+		require.NoError(t, err)
+		id := cart.GetCartId()
+
+		cart, err = client.AddItem(ctx, &cartv1.AddItemRequest{CartId: id, ItemId: 42, Quantity: 3})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), cart.GetVersion(), "Adding several units should take a single event")
+		assert.Equal(t, []int64{42, 42, 42}, cart.GetContents())
+
+		cart, err = client.RemoveItem(ctx, &cartv1.RemoveItemRequest{CartId: id, ItemId: 42, Quantity: 2})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{42}, cart.GetContents())
+
+		cart, err = client.SetItemQuantity(ctx, &cartv1.SetItemQuantityRequest{CartId: id, ItemId: 7, Quantity: 4})
+		require.NoError(t, err)
+		assert.Equal(t, [][2]int64{{42, 1}, {7, 4}}, lines(cart))
+
+		cart, err = client.SetContents(ctx, &cartv1.SetContentsRequest{CartId: id, Lines: []*cartv1.Line{{ItemId: 9, Quantity: 2}, {ItemId: 7, Quantity: 1}}})
+		require.NoError(t, err)
+		assert.Equal(t, [][2]int64{{7, 1}, {9, 2}}, lines(cart))
+
+		cart, err = client.RemoveItem(ctx, &cartv1.RemoveItemRequest{CartId: id, ItemId: 9, All: true})
+		require.NoError(t, err)
+		assert.Equal(t, [][2]int64{{7, 1}}, lines(cart))
+
+		_, err = client.SetItemQuantity(ctx, &cartv1.SetItemQuantityRequest{CartId: id, ItemId: 7, Quantity: 101})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("carts of other tenants are not found", func(t *testing.T) {
+		_, err := client.GetCart(withToken("token-b"), &cartv1.GetCartRequest{CartId: cart.GetCartId()})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+func TestEventService(t *testing.T) {
+	reader := &fakeEventReader{events: []es.Event{
+		{Position: 1, TenantID: "store-a", Type: checkout.CartCreated, AggregateType: checkout.CartType, AggregateID: "cart-1001", VersionID: 1, Data: map[string]any{}},
+		{Position: 2, TenantID: "store-b", Type: checkout.CartCreated, AggregateType: checkout.CartType, AggregateID: "cart-2001", VersionID: 1, Data: map[string]any{}},
+		{Position: 3, TenantID: "store-a", Type: checkout.ItemAddedToCart, AggregateType: checkout.CartType, AggregateID: "cart-1001", VersionID: 2, Data: map[string]int{"item_id": 42}},
+	}}
+	client := cartv1.NewEventServiceClient(dial(t, reader))
+
+	ctx, cancel := context.WithTimeout(withToken("token-a"), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.Subscribe(ctx, &cartv1.SubscribeRequest{})
+	require.NoError(t, err)
+
+	first, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), first.GetPosition())
+
+	second, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), second.GetPosition())
+	assert.Equal(t, string(checkout.ItemAddedToCart), second.GetType())
+	assert.JSONEq(t, `{"item_id": 42}`, string(second.GetData()))
+}
+
+// lines returns the item IDs and quantities of the lines of the cart.
+func lines(cart *cartv1.Cart) [][2]int64 {
+	var lines [][2]int64
+	for _, line := range cart.GetLines() {
+		lines = append(lines, [2]int64{line.GetItemId(), line.GetQuantity()})
+	}
+	return lines
+}
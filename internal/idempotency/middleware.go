@@ -0,0 +1,183 @@
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"es/internal/authentication"
+	"es/internal/es"
+	"es/internal/tenant"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	HeaderKey      = "Idempotency-Key"
+	HeaderReplayed = "Idempotent-Replayed"
+)
+
+// replayedHeaders are the response headers kept with the response, which
+// clients need to carry on from a replayed change.
+var replayedHeaders = []string{fiber.HeaderETag, fiber.HeaderLocation, es.HeaderEventPosition}
+
+type Config struct {
+	TTL time.Duration
+}
+
+func LoadConfig() Config {
+	// Keys are kept for a day by default, long enough to cover client retries
+	ttl := 24 * time.Hour
+	if ttlStr := os.Getenv("IDEMPOTENCY_TTL_HOURS"); ttlStr != "" {
+		if hours, err := strconv.Atoi(ttlStr); err == nil && hours > 0 {
+			ttl = time.Duration(hours) * time.Hour
+		}
+	}
+
+	return Config{
+		TTL: ttl,
+	}
+}
+
+// Middleware makes mutating routes safe to retry. Requests carrying an
+// Idempotency-Key header are executed once per key:
+//  1. The first request reserves the key and its response is stored
+//  2. Repeats of the same request replay the stored response
+//  3. Reusing the key for a different request is rejected with 422
+//  4. Repeats arriving while the first is still running get 409
+//
+// Keys are scoped to the tenant and user making the request and to its
+// method and path, so nobody replays a response made for someone else.
+// Failed requests (errors and 5xx responses) release the key so the client
+// can retry, and so do concurrency conflicts and failed preconditions, which
+// depend on changes made by others.
+func Middleware(store Store, cfg Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(HeaderKey)
+		if key == "" {
+			return c.Next()
+		}
+
+		key = scopedKey(c, key)
+		fingerprint := requestFingerprint(c)
+
+		record, err := store.Get(c.Context(), key)
+		if err != nil {
+			return err
+		}
+
+		if record == nil {
+			reserved, err := store.Reserve(c.Context(), key, Record{Fingerprint: fingerprint}, cfg.TTL)
+			if err != nil {
+				return err
+			}
+			if reserved {
+				return execute(c, store, cfg, key, fingerprint)
+			}
+
+			// Lost the race against a concurrent request with the same key
+			record, err = store.Get(c.Context(), key)
+			if err != nil {
+				return err
+			}
+			if record == nil {
+				return c.Status(http.StatusConflict).SendString("request with this idempotency key is in progress")
+			}
+		}
+
+		if record.Fingerprint != fingerprint {
+			return c.Status(http.StatusUnprocessableEntity).
+				SendString("idempotency key was already used for a different request")
+		}
+
+		if !record.Completed {
+			return c.Status(http.StatusConflict).SendString("request with this idempotency key is in progress")
+		}
+
+		c.Set(HeaderReplayed, "true")
+		if record.ContentType != "" {
+			c.Set(fiber.HeaderContentType, record.ContentType)
+		}
+		for name, value := range record.Headers {
+			c.Set(name, value)
+		}
+		return c.Status(record.StatusCode).Send(record.Body)
+	}
+}
+
+func execute(c *fiber.Ctx, store Store, cfg Config, key, fingerprint string) error {
+	// Errors are rendered here rather than by the caller, so that client
+	// errors are kept and replayed like any other response.
+	err := c.Next()
+	retry := retryable(err)
+	if err != nil {
+		err = c.App().Config().ErrorHandler(c, err)
+	}
+	if err != nil {
+		if delErr := store.Delete(c.Context(), key); delErr != nil {
+			return fmt.Errorf("%w (release idempotency key: %v)", err, delErr)
+		}
+		return err
+	}
+
+	status := c.Response().StatusCode()
+	if retry || status >= http.StatusInternalServerError {
+		return store.Delete(c.Context(), key)
+	}
+
+	headers := map[string]string{}
+	for _, name := range replayedHeaders {
+		if value := c.GetRespHeader(name); value != "" {
+			// The value points into the response buffer, which is reused.
+			headers[name] = strings.Clone(value)
+		}
+	}
+
+	return store.Save(c.Context(), key, Record{
+		Fingerprint: fingerprint,
+		Completed:   true,
+		StatusCode:  status,
+		ContentType: string(c.Response().Header.ContentType()),
+		Headers:     headers,
+		Body:        append([]byte(nil), c.Response().Body()...),
+	}, cfg.TTL)
+}
+
+// retryable reports whether a request failed on the state of the aggregate
+// at the time, a concurrent change or a version that no longer matches, so
+// a retry may succeed rather than replay the failure.
+func retryable(err error) bool {
+	return errors.Is(err, es.ErrConcurrencyConflict) || es.KindOf(err) == es.KindPrecondition
+}
+
+// scopedKey derives the stored key from the client's key and the tenant,
+// subject, method and path of the request.
+func scopedKey(c *fiber.Ctx, key string) string {
+	tenantID, _ := tenant.FromRequest(c)
+
+	h := sha256.New()
+	for _, part := range []string{tenantID, authentication.Subject(c), c.Method(), c.Path(), key} {
+		h.Write([]byte(part))
+		h.Write([]byte("\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// requestFingerprint identifies the request a key was first used for by its
+// method, URL, precondition and body.
+func requestFingerprint(c *fiber.Ctx) string {
+	h := sha256.New()
+	h.Write([]byte(c.Method()))
+	h.Write([]byte(" "))
+	h.Write([]byte(c.OriginalURL()))
+	h.Write([]byte("\n"))
+	h.Write([]byte(c.Get(fiber.HeaderIfMatch)))
+	h.Write([]byte("\n"))
+	h.Write(c.Body())
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,250 @@
+package idempotency_test
+
+import (
+	"context"
+	"errors"
+	"es/internal/es"
+	"es/internal/idempotency"
+	"es/internal/problem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore is an in-memory Store that ignores TTLs
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[string]idempotency.Record
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{records: make(map[string]idempotency.Record)}
+}
+
+func (s *memoryStore) Get(_ context.Context, key string) (*idempotency.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.records[key]; ok {
+		return &r, nil
+	}
+	return nil, nil
+}
+
+func (s *memoryStore) Reserve(_ context.Context, key string, r idempotency.Record, _ time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.records[key]; ok {
+		return false, nil
+	}
+	s.records[key] = r
+	return true, nil
+}
+
+func (s *memoryStore) Save(_ context.Context, key string, r idempotency.Record, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = r
+	return nil
+}
+
+func (s *memoryStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+func newTestApp(store idempotency.Store, calls *int) *fiber.App {
+	app := fiber.New()
+	// Requests are made as the user named by X-Subject, if any.
+	app.Use(func(c *fiber.Ctx) error {
+		if subject := c.Get("X-Subject"); subject != "" {
+			c.Locals("user", jwt.MapClaims{"sub": subject})
+		}
+		return c.Next()
+	})
+	mw := idempotency.Middleware(store, idempotency.Config{TTL: time.Hour})
+
+	app.Post("/items", mw, func(c *fiber.Ctx) error {
+		*calls++
+		c.Set(fiber.HeaderETag, `"`+strconv.Itoa(*calls)+`"`)
+		return c.Status(http.StatusOK).JSON(fiber.Map{"call": *calls})
+	})
+	app.Post("/fail", mw, func(c *fiber.Ctx) error {
+		*calls++
+		return errors.New("boom")
+	})
+	app.Post("/missing", mw, func(c *fiber.Ctx) error {
+		*calls++
+		return fiber.NewError(http.StatusNotFound, "cart not found")
+	})
+	return app
+}
+
+func doRequest(t *testing.T, app *fiber.App, path, key, body string) (*http.Response, string) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(idempotency.HeaderKey, key)
+	}
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(data)
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Run("replays response for a repeated key", func(t *testing.T) {
+		calls := 0
+		app := newTestApp(newMemoryStore(), &calls)
+
+		first, firstBody := doRequest(t, app, "/items", "abc", `{"item_id":42}`)
+		second, secondBody := doRequest(t, app, "/items", "abc", `{"item_id":42}`)
+
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, http.StatusOK, second.StatusCode)
+		assert.Equal(t, firstBody, secondBody)
+		assert.Equal(t, first.Header.Get(fiber.HeaderContentType), second.Header.Get(fiber.HeaderContentType))
+		assert.Equal(t, `"1"`, second.Header.Get(fiber.HeaderETag))
+		assert.Equal(t, "true", second.Header.Get(idempotency.HeaderReplayed))
+	})
+
+	t.Run("rejects key reuse with a different body", func(t *testing.T) {
+		calls := 0
+		app := newTestApp(newMemoryStore(), &calls)
+
+		doRequest(t, app, "/items", "abc", `{"item_id":42}`)
+		resp, _ := doRequest(t, app, "/items", "abc", `{"item_id":43}`)
+
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	})
+
+	t.Run("requests without a key are not deduplicated", func(t *testing.T) {
+		calls := 0
+		app := newTestApp(newMemoryStore(), &calls)
+
+		doRequest(t, app, "/items", "", `{"item_id":42}`)
+		doRequest(t, app, "/items", "", `{"item_id":42}`)
+
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("in-flight key is rejected", func(t *testing.T) {
+		calls := 0
+		store := newMemoryStore()
+		app := newTestApp(store, &calls)
+
+		doRequest(t, app, "/items", "abc", `{}`)
+		for key, record := range store.records {
+			record.Completed = false
+			store.records[key] = record
+		}
+
+		resp, _ := doRequest(t, app, "/items", "abc", `{}`)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+
+	t.Run("failed requests release the key", func(t *testing.T) {
+		calls := 0
+		app := newTestApp(newMemoryStore(), &calls)
+
+		doRequest(t, app, "/fail", "abc", `{}`)
+		doRequest(t, app, "/fail", "abc", `{}`)
+
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("client errors returned by handlers are replayed", func(t *testing.T) {
+		calls := 0
+		app := newTestApp(newMemoryStore(), &calls)
+
+		doRequest(t, app, "/missing", "abc", `{}`)
+		resp, body := doRequest(t, app, "/missing", "abc", `{}`)
+
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, "cart not found", body)
+		assert.Equal(t, "true", resp.Header.Get(idempotency.HeaderReplayed))
+	})
+	t.Run("keys are scoped to the user and route", func(t *testing.T) {
+		calls := 0
+		app := newTestApp(newMemoryStore(), &calls)
+
+		as := func(subject string, path string) *http.Response {
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{}`))
+			req.Header.Set(idempotency.HeaderKey, "abc")
+			req.Header.Set("X-Subject", subject)
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			return resp
+		}
+
+		as("alice", "/items")
+		resp := as("bob", "/items")
+		assert.Equal(t, 2, calls, "Another user's key must not replay the response made for alice")
+		assert.Empty(t, resp.Header.Get(idempotency.HeaderReplayed))
+
+		resp = as("alice", "/missing")
+		assert.Equal(t, 3, calls)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		resp = as("alice", "/items")
+		assert.Equal(t, 3, calls)
+		assert.Equal(t, "true", resp.Header.Get(idempotency.HeaderReplayed))
+	})
+
+	t.Run("concurrency conflicts release the key", func(t *testing.T) {
+		calls := 0
+		app := newProblemApp(func() error {
+			calls++
+			return fmt.Errorf("%w: expected version 3, got 2", es.ErrConcurrencyConflict)
+		})
+
+		resp, _ := doRequest(t, app, "/change", "abc", `{}`)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+		resp, _ = doRequest(t, app, "/change", "abc", `{}`)
+		assert.Empty(t, resp.Header.Get(idempotency.HeaderReplayed))
+
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("failed preconditions release the key", func(t *testing.T) {
+		calls := 0
+		app := newProblemApp(func() error {
+			calls++
+			return es.NewError(es.KindPrecondition, "cart has changed since the expected version")
+		})
+
+		resp, _ := doRequest(t, app, "/change", "abc", `{}`)
+		assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+		resp, _ = doRequest(t, app, "/change", "abc", `{}`)
+		assert.Empty(t, resp.Header.Get(idempotency.HeaderReplayed))
+
+		assert.Equal(t, 2, calls)
+	})
+}
+
+// newProblemApp serves POST /change with the handler behind the middleware
+// and renders errors as problems, like the API.
+func newProblemApp(handler func() error) *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: problem.ErrorHandler})
+	app.Post("/change", idempotency.Middleware(newMemoryStore(), idempotency.Config{TTL: time.Hour}), func(c *fiber.Ctx) error {
+		return handler()
+	})
+	return app
+}
@@ -0,0 +1,94 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Record is what is remembered about a request made with an idempotency key.
+// A record that is not completed marks a request that is still in flight.
+type Record struct {
+	Fingerprint string            `json:"fingerprint"`
+	Completed   bool              `json:"completed"`
+	StatusCode  int               `json:"status_code,omitempty"`
+	ContentType string            `json:"content_type,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        []byte            `json:"body,omitempty"`
+}
+
+// Store persists idempotency records with a time-to-live.
+type Store interface {
+	// Get returns the record for the key, or nil if there is none.
+	Get(ctx context.Context, key string) (*Record, error)
+	// Reserve stores the record only if the key is unused and reports
+	// whether it did.
+	Reserve(ctx context.Context, key string, record Record, ttl time.Duration) (bool, error)
+	// Save stores the record, replacing any existing one.
+	Save(ctx context.Context, key string, record Record, ttl time.Duration) error
+	// Delete forgets the key so that the request can be retried.
+	Delete(ctx context.Context, key string) error
+}
+
+// RedisClient abstracts the Redis operations used by RedisStore
+type RedisClient interface {
+	Get(ctx context.Context, key string) *redis.StringCmd
+	Set(ctx context.Context, key string, value any, expiration time.Duration) *redis.StatusCmd
+	SetNX(ctx context.Context, key string, value any, expiration time.Duration) *redis.BoolCmd
+	Del(ctx context.Context, keys ...string) *redis.IntCmd
+}
+
+// RedisStore keeps idempotency records in Redis under "idempotency:{key}".
+type RedisStore struct {
+	client RedisClient
+}
+
+func NewRedisStore(client RedisClient) *RedisStore {
+	return &RedisStore{
+		client: client,
+	}
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) (*Record, error) {
+	val, err := s.client.Get(ctx, redisKey(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get idempotency record: %w", err)
+	}
+
+	var record Record
+	if err := json.Unmarshal(val, &record); err != nil {
+		return nil, fmt.Errorf("unmarshal idempotency record: %w", err)
+	}
+	return &record, nil
+}
+
+func (s *RedisStore) Reserve(ctx context.Context, key string, record Record, ttl time.Duration) (bool, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return false, fmt.Errorf("marshal idempotency record: %w", err)
+	}
+	return s.client.SetNX(ctx, redisKey(key), data, ttl).Result()
+}
+
+func (s *RedisStore) Save(ctx context.Context, key string, record Record, ttl time.Duration) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("marshal idempotency record: %w", err)
+	}
+	return s.client.Set(ctx, redisKey(key), data, ttl).Err()
+}
+
+func (s *RedisStore) Delete(ctx context.Context, key string) error {
+	return s.client.Del(ctx, redisKey(key)).Err()
+}
+
+func redisKey(key string) string {
+	return "idempotency:" + key
+}
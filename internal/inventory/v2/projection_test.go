@@ -6,6 +6,7 @@ import (
 	"context"
 	"es/internal/checkout"
 	"es/internal/es"
+	"es/internal/es/estest"
 	v2 "es/internal/inventory/v2"
 	"es/internal/util"
 	"fmt"
@@ -64,18 +65,21 @@ func TestProjectionV2(t *testing.T) {
 	t.Run("when item added to cart", func(t *testing.T) {
 		tc := setupTestContext(t)
 
+		tenantID := "store-a"
 		itemID := 42
-		cartID := 1001
+		cartID := "cart-1001"
 
 		assert.NoError(t, tc.projection.Apply(tc.ctx,
 			es.Event{
 				Type:        checkout.ItemAddedToCart,
+				TenantID:    tenantID,
 				AggregateID: cartID,
 				Data:        map[string]int{"item_id": itemID},
 				Position:    1,
 			},
 			es.Event{
 				Type:        checkout.ItemAddedToCart,
+				TenantID:    tenantID,
 				AggregateID: cartID,
 				Data:        map[string]int{"item_id": itemID},
 				Position:    2,
@@ -85,12 +89,14 @@ func TestProjectionV2(t *testing.T) {
 		assert.NoError(t, tc.projection.Apply(tc.ctx,
 			es.Event{
 				Type:        checkout.ItemRemovedFromCart,
+				TenantID:    tenantID,
 				AggregateID: cartID,
 				Data:        map[string]int{"item_id": itemID},
 				Position:    3,
 			},
 			es.Event{
 				Type:        checkout.ItemAddedToCart,
+				TenantID:    tenantID,
 				AggregateID: cartID,
 				Data:        map[string]int{"item_id": itemID},
 				Position:    4,
@@ -102,8 +108,8 @@ func TestProjectionV2(t *testing.T) {
 		err := tc.pool.QueryRow(tc.ctx, `
 			SELECT quantity
 			FROM inventory_v2.cart_items
-			WHERE cart_id = $1 AND item_id = $2
-		`, cartID, itemID).Scan(&quantity)
+			WHERE tenant_id = $1 AND cart_id = $2 AND item_id = $3
+		`, tenantID, cartID, itemID).Scan(&quantity)
 		assert.NoError(t, err)
 		assert.Equal(t, 2, quantity, "Quantity should be 2 after adding same item twice")
 	})
@@ -111,12 +117,14 @@ func TestProjectionV2(t *testing.T) {
 	t.Run("when item removed from cart", func(t *testing.T) {
 		tc := setupTestContext(t)
 
+		tenantID := "store-a"
 		itemID := 42
-		cartID := 1001
-		cartID2 := 1002
+		cartID := "cart-1001"
+		cartID2 := "cart-1002"
 
 		assert.NoError(t, tc.projection.Apply(tc.ctx, es.Event{
 			Type:        checkout.ItemAddedToCart,
+			TenantID:    tenantID,
 			AggregateID: cartID,
 			Data:        map[string]int{"item_id": itemID},
 			Position:    1,
@@ -125,12 +133,14 @@ func TestProjectionV2(t *testing.T) {
 		assert.NoError(t, tc.projection.Apply(tc.ctx,
 			es.Event{
 				Type:        checkout.ItemAddedToCart,
+				TenantID:    tenantID,
 				AggregateID: cartID2,
 				Data:        map[string]int{"item_id": itemID},
 				Position:    2,
 			},
 			es.Event{
 				Type:        checkout.ItemRemovedFromCart,
+				TenantID:    tenantID,
 				AggregateID: cartID,
 				Data:        map[string]int{"item_id": itemID},
 				Position:    3,
@@ -141,9 +151,149 @@ func TestProjectionV2(t *testing.T) {
 		err := tc.pool.QueryRow(tc.ctx, `
 			SELECT quantity
 			FROM inventory_v2.cart_items
-			WHERE cart_id = $1 AND item_id = $2
-		`, cartID, itemID).Scan(&quantity)
+			WHERE tenant_id = $1 AND cart_id = $2 AND item_id = $3
+		`, tenantID, cartID, itemID).Scan(&quantity)
 		assert.NoError(t, err)
 		assert.Equal(t, 0, quantity, "Quantity should be 0 after removing item twice")
 	})
+	t.Run("when the quantity of an item is set", func(t *testing.T) {
+		tc := setupTestContext(t)
+
+		tenantID := "store-a"
+		itemID := 42
+		cartID := "cart-1001"
+
+		assert.NoError(t, tc.projection.Apply(tc.ctx,
+			es.Event{
+				Type:        checkout.ItemAddedToCart,
+				TenantID:    tenantID,
+				AggregateID: cartID,
+				Data:        map[string]int{"item_id": itemID},
+				Position:    1,
+			},
+			es.Event{
+				Type:        checkout.ItemQuantityChanged,
+				TenantID:    tenantID,
+				AggregateID: cartID,
+				Data:        map[string]int{"item_id": itemID, "quantity": 10, "previous_quantity": 1},
+				Position:    2,
+			},
+			es.Event{
+				Type:        checkout.ItemQuantityChanged,
+				TenantID:    tenantID,
+				AggregateID: cartID,
+				Data:        map[string]int{"item_id": itemID, "quantity": 4, "previous_quantity": 10},
+				Position:    3,
+			},
+		))
+
+		var quantity int
+		err := tc.pool.QueryRow(tc.ctx, `
+			SELECT quantity
+			FROM inventory_v2.cart_items
+			WHERE tenant_id = $1 AND cart_id = $2 AND item_id = $3
+		`, tenantID, cartID, itemID).Scan(&quantity)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, quantity, "Quantity should follow the last quantity set")
+	})
+
+	t.Run("when a cart is checked out", func(t *testing.T) {
+		tc := setupTestContext(t)
+		added := es.Event{
+			Type:        checkout.ItemAddedToCart,
+			TenantID:    "store-a",
+			AggregateID: "cart-1001",
+			Data:        map[string]int{"item_id": 42},
+		}
+
+		estest.NewProjectionFixture(t, tc.projection).
+			Given(added, added).
+			Given(es.Event{Type: checkout.CartCheckedOut, TenantID: "store-a", AggregateID: "cart-1001"}).
+			ThenPosition().
+			Then(func(ctx context.Context, t testing.TB) {
+				results, err := v2.NewPGItemCountRepository(tc.pool).GetItemCounts(ctx, "store-a")
+				assert.NoError(t, err)
+				assert.Equal(t, []v2.Result{
+					{ID: 42, Count: v2.ItemCount{SoldCount: 2, StagedCount: 0}},
+				}, results)
+			})
+	})
+
+	t.Run("position moves past events of other types", func(t *testing.T) {
+		tc := setupTestContext(t)
+
+		assert.NoError(t, tc.projection.SavePosition(tc.ctx, 10))
+		assert.NoError(t, tc.projection.SavePosition(tc.ctx, 5))
+
+		position, err := tc.projection.LatestPosition(tc.ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(10), position, "Position should never move back")
+	})
+
+	t.Run("when carts of different tenants share an ID", func(t *testing.T) {
+		tc := setupTestContext(t)
+
+		itemID := 42
+		cartID := "cart-1001"
+
+		assert.NoError(t, tc.projection.Apply(tc.ctx,
+			es.Event{
+				Type:        checkout.ItemAddedToCart,
+				TenantID:    "store-a",
+				AggregateID: cartID,
+				Data:        map[string]int{"item_id": itemID},
+				Position:    1,
+			},
+			es.Event{
+				Type:        checkout.ItemAddedToCart,
+				TenantID:    "store-b",
+				AggregateID: cartID,
+				Data:        map[string]int{"item_id": itemID},
+				Position:    2,
+			},
+			es.Event{
+				Type:        checkout.CartCheckedOut,
+				TenantID:    "store-b",
+				AggregateID: cartID,
+				Position:    3,
+			},
+		))
+
+		repo := v2.NewPGItemCountRepository(tc.pool)
+
+		resultsA, err := repo.GetItemCounts(tc.ctx, "store-a")
+		assert.NoError(t, err)
+		assert.Equal(t, []v2.Result{
+			{ID: itemID, Count: v2.ItemCount{SoldCount: 0, StagedCount: 1}},
+		}, resultsA)
+
+		resultsB, err := repo.GetItemCounts(tc.ctx, "store-b")
+		assert.NoError(t, err)
+		assert.Equal(t, []v2.Result{
+			{ID: itemID, Count: v2.ItemCount{SoldCount: 1, StagedCount: 0}},
+		}, resultsB)
+	})
+
+	t.Run("when a cart expires", func(t *testing.T) {
+		tc := setupTestContext(t)
+		added := func(cartID string) es.Event {
+			return es.Event{
+				Type:        checkout.ItemAddedToCart,
+				TenantID:    "store-a",
+				AggregateID: cartID,
+				Data:        map[string]int{"item_id": 42},
+			}
+		}
+
+		estest.NewProjectionFixture(t, tc.projection).
+			Given(added("cart-1001"), added("cart-1002"), added("cart-1002")).
+			Given(es.Event{Type: checkout.CartExpired, TenantID: "store-a", AggregateID: "cart-1002"}).
+			Then(func(ctx context.Context, t testing.TB) {
+				results, err := v2.NewPGItemCountRepository(tc.pool).GetItemCounts(ctx, "store-a")
+				assert.NoError(t, err)
+				assert.Equal(t, []v2.Result{
+					{ID: 42, Count: v2.ItemCount{SoldCount: 0, StagedCount: 1}},
+				}, results)
+			})
+	})
 }
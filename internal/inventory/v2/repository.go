@@ -19,7 +19,7 @@ type Result struct {
 
 // ItemCountRepository defines the interface for retrieving item counts
 type ItemCountRepository interface {
-	GetItemCounts(ctx context.Context) ([]Result, error)
+	GetItemCounts(ctx context.Context, tenantID string) ([]Result, error)
 }
 
 // PGItemCountRepository implements ItemCountRepository using PostgreSQL
@@ -34,8 +34,8 @@ func NewPGItemCountRepository(pool *pgxpool.Pool) *PGItemCountRepository {
 	}
 }
 
-// GetItemCounts retrieves sold and reserved counts for the given item IDs
-func (r *PGItemCountRepository) GetItemCounts(ctx context.Context) ([]Result, error) {
+// GetItemCounts retrieves sold and reserved counts of every item of the tenant
+func (r *PGItemCountRepository) GetItemCounts(ctx context.Context, tenantID string) ([]Result, error) {
 	query := `
 		SELECT
 			ci.item_id,
@@ -52,7 +52,9 @@ func (r *PGItemCountRepository) GetItemCounts(ctx context.Context) ([]Result, er
 				END
 			), 0) as reserved_count
 		FROM inventory_v2.cart_items ci
-		JOIN inventory_v2.carts c ON ci.cart_id = c.cart_id
+		JOIN inventory_v2.carts c
+		ON ci.tenant_id = c.tenant_id AND ci.cart_id = c.cart_id
+		WHERE ci.tenant_id = $1
 		GROUP BY ci.item_id
 	`
 
@@ -62,7 +64,7 @@ func (r *PGItemCountRepository) GetItemCounts(ctx context.Context) ([]Result, er
 	}
 	defer conn.Release()
 
-	rows, err := conn.Query(ctx, query)
+	rows, err := conn.Query(ctx, query, tenantID)
 	if err != nil {
 		return nil, fmt.Errorf("query item counts: %w", err)
 	}
@@ -1,6 +1,9 @@
 package v2
 
 import (
+	"es/internal/es"
+	"es/internal/openapi"
+	"es/internal/tenant"
 	"net/http"
 
 	"github.com/gofiber/fiber/v2"
@@ -16,8 +19,26 @@ func NewRouteHandler(repo ItemCountRepository) *RouteHandler {
 	}
 }
 
+// Operations documents the handlers of RouteHandler.
+var Operations = struct {
+	Get openapi.Operation
+}{
+	Get: openapi.Operation{
+		Summary:   "Units of each item sold and reserved in open carts",
+		Tags:      []string{"inventory"},
+		Query:     []openapi.Parameter{es.MinPositionParam},
+		Responses: map[int]any{http.StatusOK: []Result{}},
+	},
+}
+
 func (h *RouteHandler) Get(c *fiber.Ctx) error {
-	res, err := h.repo.GetItemCounts(c.Context())
+	tenantID, err := tenant.FromRequest(c)
+
+	if err != nil {
+		return fiber.NewError(http.StatusForbidden, err.Error())
+	}
+
+	res, err := h.repo.GetItemCounts(c.Context(), tenantID)
 
 	if err != nil {
 		return err
@@ -0,0 +1,346 @@
+// Package migrate applies ordered, versioned SQL migrations and records them
+// in the schema_migrations table. Migrations are grouped in namespaces so the
+// event store and every projection keep their own version history.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrChecksumMismatch = errors.New("applied migration has changed")
+	ErrUnknownMigration = errors.New("applied migration is unknown")
+	ErrNoDownMigration  = errors.New("migration cannot be reverted")
+)
+
+// lockID is the advisory lock held while migrating, so concurrent processes
+// apply each migration exactly once.
+const lockID int64 = 7262_0001
+
+// Migration is one version of a namespace's schema.
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Load reads migrations from files named <version>_<name>.up.sql and
+// <version>_<name>.down.sql at the root of fsys, ordered by version. Down
+// files are optional.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %s", entry.Name())
+		}
+
+		sql, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(sql)
+		} else {
+			m.Down = string(sql)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		m.Checksum = Checksum(m.Up)
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Checksum returns the checksum recorded for a migration's up SQL.
+func Checksum(up string) string {
+	sum := sha256.Sum256([]byte(up))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsOutdated reports whether Up failed because applied migrations were
+// edited or removed, which for rebuildable schemas means starting over.
+func IsOutdated(err error) bool {
+	return errors.Is(err, ErrChecksumMismatch) || errors.Is(err, ErrUnknownMigration)
+}
+
+// MustLoad is Load for embedded migrations, which can only fail on a
+// programming error.
+func MustLoad(fsys fs.FS, dir string) []Migration {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	migrations, err := Load(sub)
+	if err != nil {
+		panic(err)
+	}
+	return migrations
+}
+
+// AppliedMigration is a row of schema_migrations.
+type AppliedMigration struct {
+	Version   int
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+type Migrator struct {
+	pool       *pgxpool.Pool
+	namespace  string
+	migrations []Migration
+}
+
+func NewMigrator(pool *pgxpool.Pool, namespace string, migrations []Migration) *Migrator {
+	return &Migrator{
+		pool:       pool,
+		namespace:  namespace,
+		migrations: migrations,
+	}
+}
+
+// Up applies every migration that has not been applied yet, each in its own
+// transaction, and returns the ones it applied. It fails without applying
+// anything when an applied migration has been edited since.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	applied := []Migration{}
+
+	err := m.locked(ctx, func(conn *pgxpool.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		if err := m.verify(done); err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if err := m.run(ctx, conn, migration, migration.Up, true); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverts the last applied migrations, newest first, and returns the
+// ones it reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	reverted := []Migration{}
+
+	err := m.locked(ctx, func(conn *pgxpool.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		versions := make([]int, 0, len(done))
+		for version := range done {
+			versions = append(versions, version)
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+		for _, version := range versions[:min(steps, len(versions))] {
+			migration, ok := m.find(version)
+			if !ok {
+				return fmt.Errorf("%w: %s %d", ErrUnknownMigration, m.namespace, version)
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("%w: %s %d_%s has no down file", ErrNoDownMigration, m.namespace, version, migration.Name)
+			}
+			if err := m.run(ctx, conn, migration, migration.Down, false); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status returns the applied migrations ordered by version.
+func (m *Migrator) Status(ctx context.Context) ([]AppliedMigration, error) {
+	status := []AppliedMigration{}
+
+	err := m.locked(ctx, func(conn *pgxpool.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, a := range done {
+			status = append(status, a)
+		}
+		return nil
+	})
+
+	sort.Slice(status, func(i, j int) bool {
+		return status[i].Version < status[j].Version
+	})
+	return status, err
+}
+
+func (m *Migrator) locked(ctx context.Context, fn func(*pgxpool.Conn) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		_, _ = conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID)
+	}()
+
+	if _, err := conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			namespace TEXT NOT NULL,
+			version INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			checksum CHAR(64) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'UTC'),
+			PRIMARY KEY (namespace, version)
+		)`,
+	); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *pgxpool.Conn) (map[int]AppliedMigration, error) {
+	rows, err := conn.Query(ctx, `
+		SELECT version, name, checksum, applied_at
+		FROM schema_migrations
+		WHERE namespace = $1`,
+		m.namespace,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	done := make(map[int]AppliedMigration)
+	for rows.Next() {
+		var a AppliedMigration
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &a.AppliedAt); err != nil {
+			return nil, err
+		}
+		done[a.Version] = a
+	}
+	return done, rows.Err()
+}
+
+func (m *Migrator) verify(done map[int]AppliedMigration) error {
+	for version, a := range done {
+		migration, ok := m.find(version)
+		if !ok {
+			return fmt.Errorf("%w: %s %d_%s", ErrUnknownMigration, m.namespace, version, a.Name)
+		}
+		if migration.Checksum != a.Checksum {
+			return fmt.Errorf("%w: %s %d_%s", ErrChecksumMismatch, m.namespace, version, migration.Name)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) run(ctx context.Context, conn *pgxpool.Conn, migration Migration, sql string, up bool) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	defer func(ctx context.Context) {
+		_ = tx.Rollback(ctx)
+	}(ctx)
+
+	if _, err := tx.Exec(ctx, sql); err != nil {
+		return fmt.Errorf("migrate %s %d_%s: %w", m.namespace, migration.Version, migration.Name, err)
+	}
+
+	if up {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO schema_migrations (namespace, version, name, checksum)
+			VALUES ($1, $2, $3, $4)`,
+			m.namespace, migration.Version, migration.Name, migration.Checksum,
+		)
+	} else {
+		_, err = tx.Exec(ctx, `
+			DELETE FROM schema_migrations
+			WHERE namespace = $1 AND version = $2`,
+			m.namespace, migration.Version,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("record migration: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (m *Migrator) find(version int) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
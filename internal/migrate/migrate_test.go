@@ -0,0 +1,53 @@
+package migrate_test
+
+import (
+	"es/internal/migrate"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("orders migrations by version", func(t *testing.T) {
+		migrations, err := migrate.Load(fstest.MapFS{
+			"0002_add_index.up.sql":      {Data: []byte("CREATE INDEX i ON t (a);")},
+			"0001_create_table.up.sql":   {Data: []byte("CREATE TABLE t (a INT);")},
+			"0001_create_table.down.sql": {Data: []byte("DROP TABLE t;")},
+			"README.md":                  {Data: []byte("ignored")},
+		})
+		require.NoError(t, err)
+		require.Len(t, migrations, 2)
+
+		assert.Equal(t, 1, migrations[0].Version)
+		assert.Equal(t, "create_table", migrations[0].Name)
+		assert.Equal(t, "DROP TABLE t;", migrations[0].Down)
+		assert.Len(t, migrations[0].Checksum, 64)
+
+		assert.Equal(t, 2, migrations[1].Version)
+		assert.Empty(t, migrations[1].Down)
+	})
+
+	t.Run("checksum follows the up migration", func(t *testing.T) {
+		first, err := migrate.Load(fstest.MapFS{"0001_a.up.sql": {Data: []byte("SELECT 1;")}})
+		require.NoError(t, err)
+		second, err := migrate.Load(fstest.MapFS{"0001_a.up.sql": {Data: []byte("SELECT 2;")}})
+		require.NoError(t, err)
+
+		assert.NotEqual(t, first[0].Checksum, second[0].Checksum)
+	})
+
+	t.Run("down without up is rejected", func(t *testing.T) {
+		_, err := migrate.Load(fstest.MapFS{"0001_a.down.sql": {Data: []byte("SELECT 1;")}})
+		assert.EqualError(t, err, "migration 1_a has no up file")
+	})
+
+	t.Run("version with two names is rejected", func(t *testing.T) {
+		_, err := migrate.Load(fstest.MapFS{
+			"0001_a.up.sql": {Data: []byte("SELECT 1;")},
+			"0001_b.up.sql": {Data: []byte("SELECT 2;")},
+		})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,278 @@
+// Package openapi builds the OpenAPI 3 document of the HTTP API from the
+// routes as they are registered, so the document cannot drift from the
+// routes it describes.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3gen"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Operation annotates a route handler with what it takes and returns.
+// Request and the values of Responses are example values of the body types,
+// whose schemas are generated from their fields and struct tags.
+//
+// Struct fields may carry an openapi tag with comma separated options:
+// required, minimum=n and maximum=n.
+type Operation struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Deprecated  bool
+	// PathParams overrides the type of path parameters, which are strings
+	// unless given here.
+	PathParams []Parameter
+	Query      []Parameter
+	Headers    []Parameter
+	Request    any
+	// OptionalRequest lets clients leave out the request body.
+	OptionalRequest bool
+	// Responses maps status codes to their body, or nil for no body. Every
+	// operation also has the default error response of the document.
+	Responses map[int]any
+}
+
+// Parameter is a path, query or header parameter. Type is an example value of the
+// parameter's type, a string if nil. Enum lists the allowed values, if any.
+type Parameter struct {
+	Name        string
+	Description string
+	Type        any
+	Enum        []any
+	Required    bool
+}
+
+// Document is an OpenAPI 3 document that routes are added to.
+type Document struct {
+	spec             *openapi3.T
+	errorContentType string
+	errorBody        any
+}
+
+func NewDocument(title string, version string) *Document {
+	return &Document{
+		spec: &openapi3.T{
+			OpenAPI: "3.0.3",
+			Info:    &openapi3.Info{Title: title, Version: version},
+			Paths:   openapi3.NewPaths(),
+			Components: &openapi3.Components{
+				Schemas: openapi3.Schemas{},
+				SecuritySchemes: openapi3.SecuritySchemes{
+					"bearerAuth": &openapi3.SecuritySchemeRef{
+						Value: openapi3.NewJWTSecurityScheme(),
+					},
+				},
+			},
+			Security: openapi3.SecurityRequirements{
+				openapi3.NewSecurityRequirement().Authenticate("bearerAuth"),
+			},
+		},
+	}
+}
+
+// SetErrorResponse documents the body of error responses, which every
+// operation added afterwards has as its default response.
+func (d *Document) SetErrorResponse(contentType string, body any) {
+	d.errorContentType = contentType
+	d.errorBody = body
+}
+
+// Spec returns the document.
+func (d *Document) Spec() *openapi3.T {
+	return d.spec
+}
+
+var pathParam = regexp.MustCompile(`:(\w+)`)
+
+// Add documents the route with the given method and fiber path.
+func (d *Document) Add(method string, path string, op Operation) error {
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+
+	operation := &openapi3.Operation{
+		OperationID: operationID(method, path),
+		Summary:     op.Summary,
+		Description: op.Description,
+		Tags:        op.Tags,
+		Deprecated:  op.Deprecated,
+		Responses:   openapi3.NewResponses(),
+	}
+
+	for _, match := range pathParam.FindAllStringSubmatch(path, -1) {
+		param := Parameter{Name: match[1]}
+		for _, p := range op.PathParams {
+			if p.Name == param.Name {
+				param = p
+			}
+		}
+		schema, err := d.schemaFor(param.Type)
+		if err != nil {
+			return fmt.Errorf("generate schema of path parameter %s: %w", param.Name, err)
+		}
+		operation.AddParameter(openapi3.NewPathParameter(param.Name).
+			WithDescription(param.Description).
+			WithSchema(schema.Value))
+	}
+
+	for _, param := range op.Query {
+		schema, err := d.schemaFor(param.Type)
+		if err != nil {
+			return fmt.Errorf("generate schema of query parameter %s: %w", param.Name, err)
+		}
+		schema.Value.Enum = param.Enum
+		operation.AddParameter(openapi3.NewQueryParameter(param.Name).
+			WithDescription(param.Description).
+			WithRequired(param.Required).
+			WithSchema(schema.Value))
+	}
+
+	for _, param := range op.Headers {
+		schema, err := d.schemaFor(param.Type)
+		if err != nil {
+			return fmt.Errorf("generate schema of header %s: %w", param.Name, err)
+		}
+		operation.AddParameter(openapi3.NewHeaderParameter(param.Name).
+			WithDescription(param.Description).
+			WithRequired(param.Required).
+			WithSchema(schema.Value))
+	}
+
+	if op.Request != nil {
+		schema, err := d.schemaFor(op.Request)
+		if err != nil {
+			return fmt.Errorf("generate schema of request body: %w", err)
+		}
+		operation.RequestBody = &openapi3.RequestBodyRef{
+			Value: openapi3.NewRequestBody().WithRequired(!op.OptionalRequest).WithJSONSchemaRef(schema),
+		}
+	}
+
+	statuses := make([]int, 0, len(op.Responses))
+	for status := range op.Responses {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+
+	for _, status := range statuses {
+		response := openapi3.NewResponse().WithDescription(http.StatusText(status))
+		if body := op.Responses[status]; body != nil {
+			schema, err := d.schemaFor(body)
+			if err != nil {
+				return fmt.Errorf("generate schema of %d response: %w", status, err)
+			}
+			response.WithJSONSchemaRef(schema)
+		}
+		operation.AddResponse(status, response)
+	}
+
+	if d.errorBody != nil {
+		schema, err := d.schemaFor(d.errorBody)
+		if err != nil {
+			return fmt.Errorf("generate schema of error response: %w", err)
+		}
+		operation.Responses.Set("default", &openapi3.ResponseRef{
+			Value: openapi3.NewResponse().
+				WithDescription("Error").
+				WithContent(openapi3.Content{
+					d.errorContentType: openapi3.NewMediaType().WithSchemaRef(schema),
+				}),
+		})
+	}
+
+	d.spec.AddOperation(pathParam.ReplaceAllString(path, "{$1}"), method, operation)
+	return nil
+}
+
+func (d *Document) schemaFor(value any) (*openapi3.SchemaRef, error) {
+	if value == nil {
+		value = ""
+	}
+	return openapi3gen.NewSchemaRefForValue(value, d.spec.Components.Schemas,
+		openapi3gen.UseAllExportedFields(),
+		openapi3gen.SchemaCustomizer(customizeSchema),
+	)
+}
+
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// customizeSchema applies the openapi struct tags and describes raw JSON
+// as any value rather than bytes.
+func customizeSchema(_ string, t reflect.Type, tag reflect.StructTag, schema *openapi3.Schema) error {
+	if t == rawMessageType {
+		*schema = openapi3.Schema{}
+		return nil
+	}
+
+	if t.Kind() == reflect.Struct {
+		for i := range t.NumField() {
+			field := t.Field(i)
+			if _, ok := tagOptions(field.Tag)["required"]; ok {
+				schema.Required = append(schema.Required, jsonName(field))
+			}
+		}
+	}
+
+	for option, value := range tagOptions(tag) {
+		switch option {
+		case "minimum", "maximum":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("parse %s of %s: %w", option, t, err)
+			}
+			if option == "minimum" {
+				schema.Min = &n
+			} else {
+				schema.Max = &n
+			}
+		}
+	}
+	return nil
+}
+
+func tagOptions(tag reflect.StructTag) map[string]string {
+	options := map[string]string{}
+	value, ok := tag.Lookup("openapi")
+	if !ok {
+		return options
+	}
+	for _, option := range strings.Split(value, ",") {
+		name, arg, _ := strings.Cut(option, "=")
+		options[name] = arg
+	}
+	return options
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func operationID(method string, path string) string {
+	id := strings.ToLower(method)
+	for _, segment := range strings.Split(path, "/") {
+		segment = strings.TrimPrefix(segment, ":")
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool { return r == '-' || r == '_' }) {
+			id += strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return id
+}
+
+// Serve answers with the document as JSON.
+func (d *Document) Serve(c *fiber.Ctx) error {
+	return c.Status(http.StatusOK).JSON(d.spec)
+}
@@ -0,0 +1,155 @@
+package openapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"es/internal/openapi"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type addItem struct {
+	ItemID   int  `json:"item_id" openapi:"required,minimum=1"`
+	Quantity *int `json:"quantity,omitempty" openapi:"minimum=1,maximum=100"`
+}
+
+type cart struct {
+	ID        string    `json:"cart_id"`
+	Contents  []int     `json:"contents"`
+	UpdatedAt time.Time `json:"updated_at"`
+	secret    string
+}
+
+type apiError struct {
+	Detail string `json:"detail"`
+}
+
+var addItemOperation = openapi.Operation{
+	Summary:    "Add an item to a cart",
+	PathParams: []openapi.Parameter{{Name: "cartID", Description: "ID of the cart"}},
+	Query:      []openapi.Parameter{{Name: "dry_run", Type: false}},
+	Headers:    []openapi.Parameter{{Name: "If-Match", Required: true}},
+	Request:    addItem{},
+	Responses:  map[int]any{http.StatusCreated: cart{}},
+}
+
+func newApp(t *testing.T, handler fiber.Handler) (*fiber.App, *openapi.Document, *[]error) {
+	t.Helper()
+	var reports []error
+
+	doc := openapi.NewDocument("Carts", "1.0.0")
+	doc.SetErrorResponse("application/problem+json", apiError{})
+
+	app := fiber.New()
+	app.Use(openapi.Validator(doc, func(err error) { reports = append(reports, err) }))
+	doc.Group(app, "/carts").Post("/:cartID/items", addItemOperation, handler)
+	return app, doc, &reports
+}
+
+func post(t *testing.T, app *fiber.App, path string, body string) *http.Response {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"1"`)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	return resp
+}
+
+func TestDocument(t *testing.T) {
+	t.Run("documents registered routes", func(t *testing.T) {
+		_, doc, _ := newApp(t, func(c *fiber.Ctx) error { return nil })
+		require.NoError(t, doc.Spec().Validate(context.Background()))
+
+		op := doc.Spec().Paths.Find("/carts/{cartID}/items").Post
+		require.NotNil(t, op)
+		assert.Equal(t, "postCartsCartIDItems", op.OperationID)
+		assert.Equal(t, "ID of the cart", op.Parameters.GetByInAndName("path", "cartID").Description)
+		assert.True(t, op.Parameters.GetByInAndName("query", "dry_run").Schema.Value.Type.Is("boolean"))
+		assert.True(t, op.Parameters.GetByInAndName("header", "If-Match").Required)
+
+		assert.True(t, op.RequestBody.Value.Required)
+		body := op.RequestBody.Value.Content.Get("application/json").Schema.Value
+		assert.Equal(t, []string{"item_id"}, body.Required)
+		assert.Equal(t, float64(100), *body.Properties["quantity"].Value.Max)
+
+		created := op.Responses.Status(http.StatusCreated).Value.Content.Get("application/json").Schema.Value
+		assert.ElementsMatch(t, []string{"cart_id", "contents", "updated_at"}, keys(created.Properties))
+		assert.NotNil(t, op.Responses.Default().Value.Content.Get("application/problem+json"))
+	})
+
+	t.Run("serves the document", func(t *testing.T) {
+		_, doc, _ := newApp(t, func(c *fiber.Ctx) error { return nil })
+		app := fiber.New()
+		app.Get("/openapi.json", doc.Serve)
+		app.Get("/docs", doc.SwaggerUI("/openapi.json"))
+
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+		require.NoError(t, err)
+		var served map[string]any
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&served))
+		assert.Equal(t, "3.0.3", served["openapi"])
+
+		resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/docs", nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.MIMETextHTMLCharsetUTF8, resp.Header.Get("Content-Type"))
+	})
+}
+
+func TestValidator(t *testing.T) {
+	t.Run("accepts requests and responses that match", func(t *testing.T) {
+		app, _, reports := newApp(t, func(c *fiber.Ctx) error {
+			return c.Status(http.StatusCreated).JSON(cart{ID: "cart-1001", Contents: []int{42}})
+		})
+
+		resp := post(t, app, "/carts/cart-1001/items?dry_run=true", `{"item_id": 42, "quantity": 2}`)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Empty(t, *reports)
+	})
+
+	t.Run("reports requests that do not match", func(t *testing.T) {
+		app, _, reports := newApp(t, func(c *fiber.Ctx) error {
+			return c.Status(http.StatusCreated).JSON(cart{ID: "cart-1001", Contents: []int{}})
+		})
+
+		resp := post(t, app, "/carts/cart-1001/items", `{"item_id": 42, "quantity": 0}`)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode, "validation does not change the response")
+		require.Len(t, *reports, 1)
+		assert.True(t, errors.Is((*reports)[0], openapi.ErrInvalidRequest))
+	})
+
+	t.Run("reports responses that do not match", func(t *testing.T) {
+		app, _, reports := newApp(t, func(c *fiber.Ctx) error {
+			return c.Status(http.StatusCreated).JSON(fiber.Map{"cart_id": 1001})
+		})
+
+		post(t, app, "/carts/cart-1001/items", `{"item_id": 42}`)
+		require.Len(t, *reports, 1)
+		assert.True(t, errors.Is((*reports)[0], openapi.ErrInvalidResponse))
+	})
+
+	t.Run("reports undocumented routes", func(t *testing.T) {
+		app, _, reports := newApp(t, func(c *fiber.Ctx) error { return nil })
+		app.Post("/orders", func(c *fiber.Ctx) error { return c.SendStatus(http.StatusOK) })
+
+		post(t, app, "/orders", `{}`)
+		require.Len(t, *reports, 1)
+		assert.True(t, errors.Is((*reports)[0], openapi.ErrInvalidRequest))
+	})
+}
+
+func keys[V any](m map[string]V) []string {
+	var result []string
+	for k := range m {
+		result = append(result, k)
+	}
+	return result
+}
@@ -0,0 +1,35 @@
+// Package openapitest sets up apps for testing routes against their OpenAPI
+// document.
+package openapitest
+
+import (
+	"es/internal/openapi"
+	"es/internal/problem"
+	"es/internal/tenant"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// NewApp returns an app that renders errors as problems, serves requests as
+// tenant store-a and checks requests and responses against the document,
+// failing the test on the given mismatches, by default every invalid
+// request and response. Register the routes under test on the document:
+//
+//	app, doc := openapitest.NewApp(t, "Carts")
+//	doc.Group(app, "/carts").Get("/", cartsummary.Operations.ListCarts, h.ListCarts)
+func NewApp(t testing.TB, title string, failOn ...error) (*fiber.App, *openapi.Document) {
+	doc := openapi.NewDocument(title, "1.0.0")
+	doc.SetErrorResponse(problem.ContentType, problem.Problem{})
+	if len(failOn) == 0 {
+		failOn = []error{openapi.ErrInvalidRequest, openapi.ErrInvalidResponse}
+	}
+
+	app := fiber.New(fiber.Config{ErrorHandler: problem.ErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		tenant.Set(c, "store-a")
+		return c.Next()
+	})
+	app.Use(openapi.Validator(doc, openapi.FailOn(t, failOn...)))
+	return app, doc
+}
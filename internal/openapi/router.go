@@ -0,0 +1,51 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Router registers routes with fiber and documents them in one call, so
+// every route served is in the document.
+type Router struct {
+	router fiber.Router
+	doc    *Document
+	prefix string
+}
+
+// Group returns a Router for a fiber group with the given prefix and
+// handlers, which documents its routes in the document.
+func (d *Document) Group(router fiber.Router, prefix string, handlers ...fiber.Handler) *Router {
+	return &Router{
+		router: router.Group(prefix, handlers...),
+		doc:    d,
+		prefix: prefix,
+	}
+}
+
+func (r *Router) Get(path string, op Operation, handlers ...fiber.Handler) {
+	r.add(http.MethodGet, path, op, handlers)
+}
+
+func (r *Router) Post(path string, op Operation, handlers ...fiber.Handler) {
+	r.add(http.MethodPost, path, op, handlers)
+}
+
+func (r *Router) Put(path string, op Operation, handlers ...fiber.Handler) {
+	r.add(http.MethodPut, path, op, handlers)
+}
+
+func (r *Router) Delete(path string, op Operation, handlers ...fiber.Handler) {
+	r.add(http.MethodDelete, path, op, handlers)
+}
+
+// add panics if the operation cannot be documented, as fiber does for
+// routes it cannot register.
+func (r *Router) add(method string, path string, op Operation, handlers []fiber.Handler) {
+	if err := r.doc.Add(method, r.prefix+path, op); err != nil {
+		panic(fmt.Sprintf("document %s %s: %v", method, r.prefix+path, err))
+	}
+	r.router.Add(method, path, handlers...)
+}
@@ -0,0 +1,39 @@
+package openapi
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const swaggerUIVersion = "5.17.14"
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>%[1]s</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@%[2]s/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@%[2]s/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "%[3]s", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+// SwaggerUI serves a Swagger UI page for the document at specURL. The UI
+// itself is loaded from unpkg.
+func (d *Document) SwaggerUI(specURL string) fiber.Handler {
+	page := fmt.Sprintf(swaggerUIPage, html.EscapeString(d.spec.Info.Title), swaggerUIVersion, html.EscapeString(specURL))
+
+	return func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		return c.Status(http.StatusOK).SendString(page)
+	}
+}
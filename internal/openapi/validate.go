@@ -0,0 +1,113 @@
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+var (
+	// ErrInvalidRequest wraps requests that do not match the document.
+	ErrInvalidRequest = errors.New("request does not match the OpenAPI document")
+	// ErrInvalidResponse wraps responses that do not match the document.
+	ErrInvalidResponse = errors.New("response does not match the OpenAPI document")
+)
+
+// Validator checks every request and response against the document and
+// reports mismatches, wrapped in ErrInvalidRequest or ErrInvalidResponse,
+// without changing the response. It is meant for tests, which fail on the
+// reports they do not expect.
+func Validator(d *Document, report func(error)) fiber.Handler {
+	// The document is complete once the routes are registered, which is
+	// after the middleware is created.
+	newRouter := sync.OnceValues(func() (routers.Router, error) {
+		return legacy.NewRouter(d.spec)
+	})
+
+	return func(c *fiber.Ctx) error {
+		router, err := newRouter()
+		if err != nil {
+			return fmt.Errorf("build router of the OpenAPI document: %w", err)
+		}
+
+		var req http.Request
+		if err := fasthttpadaptor.ConvertRequest(c.Context(), &req, true); err != nil {
+			return err
+		}
+		req.URL.Scheme, req.URL.Host = "", ""
+
+		route, pathParams, err := router.FindRoute(&req)
+		if err != nil {
+			report(fmt.Errorf("%w: %s %s: %v", ErrInvalidRequest, c.Method(), c.OriginalURL(), err))
+			return c.Next()
+		}
+
+		options := &openapi3filter.Options{
+			AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+			MultiError:         true,
+		}
+		input := &openapi3filter.RequestValidationInput{
+			Request:    &req,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    options,
+		}
+		if err := openapi3filter.ValidateRequest(context.Background(), input); err != nil {
+			report(fmt.Errorf("%w: %s %s: %v", ErrInvalidRequest, c.Method(), c.OriginalURL(), err))
+		}
+
+		// Render errors here to see the response the client gets.
+		if err := c.Next(); err != nil {
+			if err := c.App().Config().ErrorHandler(c, err); err != nil {
+				return err
+			}
+		}
+
+		header := http.Header{}
+		c.Response().Header.VisitAll(func(key, value []byte) {
+			header.Add(string(key), string(value))
+		})
+		err = openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 c.Response().StatusCode(),
+			Header:                 header,
+			Body:                   io.NopCloser(bytes.NewReader(c.Response().Body())),
+			Options:                options,
+		})
+		if err != nil {
+			report(fmt.Errorf("%w: %s %s: %v", ErrInvalidResponse, c.Method(), c.OriginalURL(), err))
+		}
+		return nil
+	}
+}
+
+// TestingT is the part of testing.TB that FailOn needs.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// FailOn returns a report function for Validator that fails the test on
+// mismatches wrapped in any of the given errors, and ignores the others.
+// Tests of invalid requests fail on ErrInvalidResponse only.
+func FailOn(t TestingT, targets ...error) func(error) {
+	return func(err error) {
+		t.Helper()
+		for _, target := range targets {
+			if errors.Is(err, target) {
+				t.Errorf("%v", err)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Package problem answers failed requests with RFC 7807 problem details.
+package problem
+
+import (
+	"errors"
+	"es/internal/es"
+	"log"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ContentType is the media type of problem details documents.
+const ContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details document.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+var kindStatus = map[es.ErrorKind]int{
+	es.KindNotFound:     http.StatusNotFound,
+	es.KindConflict:     http.StatusConflict,
+	es.KindInvalid:      http.StatusUnprocessableEntity,
+	es.KindGone:         http.StatusGone,
+	es.KindForbidden:    http.StatusForbidden,
+	es.KindPrecondition: http.StatusPreconditionFailed,
+}
+
+// Status returns the HTTP status for err: the status of a *fiber.Error, the
+// status matching the kind of a domain error, or 500.
+func Status(err error) int {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return fiberErr.Code
+	}
+	if status, ok := kindStatus[es.KindOf(err)]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// ErrorHandler is a fiber.ErrorHandler that writes errors returned by
+// handlers as problem details. The details of internal errors are logged
+// rather than sent to the client.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	status := Status(err)
+
+	p := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Instance: c.OriginalURL(),
+	}
+	if status >= http.StatusInternalServerError {
+		log.Printf("%s %s: %v", c.Method(), c.OriginalURL(), err)
+	} else {
+		p.Detail = err.Error()
+	}
+
+	if err := c.Status(status).JSON(p); err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentType, ContentType)
+	return nil
+}
@@ -0,0 +1,70 @@
+package problem_test
+
+import (
+	"encoding/json"
+	"errors"
+	"es/internal/es"
+	"es/internal/problem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorHandler(t *testing.T) {
+	respond := func(t *testing.T, err error) (*http.Response, problem.Problem) {
+		t.Helper()
+		app := fiber.New(fiber.Config{ErrorHandler: problem.ErrorHandler})
+		app.Get("/carts/:cartID", func(c *fiber.Ctx) error { return err })
+
+		resp, reqErr := app.Test(httptest.NewRequest(http.MethodGet, "/carts/cart-1001?x=1", nil))
+		require.NoError(t, reqErr)
+		assert.Equal(t, problem.ContentType, resp.Header.Get(fiber.HeaderContentType))
+
+		var p problem.Problem
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&p))
+		return resp, p
+	}
+
+	t.Run("domain errors map to their kind", func(t *testing.T) {
+		for kind, status := range map[es.ErrorKind]int{
+			es.KindNotFound:     http.StatusNotFound,
+			es.KindConflict:     http.StatusConflict,
+			es.KindInvalid:      http.StatusUnprocessableEntity,
+			es.KindGone:         http.StatusGone,
+			es.KindForbidden:    http.StatusForbidden,
+			es.KindPrecondition: http.StatusPreconditionFailed,
+		} {
+			sentinel := es.NewError(kind, "cart not found")
+			resp, p := respond(t, fmt.Errorf("get cart: %w", sentinel))
+
+			assert.Equal(t, status, resp.StatusCode)
+			assert.Equal(t, problem.Problem{
+				Type:     "about:blank",
+				Title:    http.StatusText(status),
+				Status:   status,
+				Detail:   "get cart: cart not found",
+				Instance: "/carts/cart-1001?x=1",
+			}, p)
+		}
+	})
+
+	t.Run("fiber errors keep their status", func(t *testing.T) {
+		resp, p := respond(t, fiber.NewError(http.StatusBadRequest, "limit must be between 1 and 100"))
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "limit must be between 1 and 100", p.Detail)
+	})
+
+	t.Run("internal errors hide their details", func(t *testing.T) {
+		resp, p := respond(t, errors.New("connection refused"))
+
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assert.Equal(t, "Internal Server Error", p.Title)
+		assert.Empty(t, p.Detail)
+	})
+}
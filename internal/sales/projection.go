@@ -0,0 +1,90 @@
+// Package sales reports on checked out carts: the best selling items, units
+// sold per hour or day and how many carts are checked out.
+package sales
+
+import (
+	"es/internal/checkout"
+	"es/internal/es"
+	"es/internal/es/sqlprojection"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Projection tracks the contents of carts in tables of the sales_analytics
+// schema. At checkout the items of the cart are stamped with the time of
+// the checkout and the hour and day it falls in, in UTC.
+type Projection struct {
+	*sqlprojection.Projection
+}
+
+func NewProjection(pool *pgxpool.Pool) *Projection {
+	return &Projection{
+		Projection: sqlprojection.New(pool, "sales_analytics").
+			Table(sqlprojection.Table{
+				Name: "carts",
+				Columns: []sqlprojection.Column{
+					{Name: "tenant_id", Type: "VARCHAR(64) NOT NULL"},
+					{Name: "cart_id", Type: "VARCHAR(64) NOT NULL"},
+					{Name: "created_at", Type: "TIMESTAMPTZ"},
+					{Name: "checked_out_at", Type: "TIMESTAMPTZ"},
+				},
+				Key:     []string{"tenant_id", "cart_id"},
+				Indexes: []string{"tenant_id, created_at"},
+			}).
+			Table(sqlprojection.Table{
+				Name: "cart_items",
+				Columns: []sqlprojection.Column{
+					{Name: "tenant_id", Type: "VARCHAR(64) NOT NULL"},
+					{Name: "cart_id", Type: "VARCHAR(64) NOT NULL"},
+					{Name: "item_id", Type: "INTEGER NOT NULL"},
+					{Name: "quantity", Type: "INTEGER NOT NULL"},
+					{Name: "sold_at", Type: "TIMESTAMPTZ"},
+					{Name: "sold_hour", Type: "TIMESTAMPTZ"},
+					{Name: "sold_day", Type: "TIMESTAMPTZ"},
+				},
+				Key:     []string{"tenant_id", "cart_id", "item_id"},
+				Indexes: []string{"tenant_id, sold_at"},
+			}).
+			On(checkout.CartCreated, cartCreated).
+			On(checkout.ItemAddedToCart, itemQuantityChanged).
+			On(checkout.ItemRemovedFromCart, itemQuantityChanged).
+			On(checkout.ItemQuantityChanged, itemQuantityChanged).
+			On(checkout.CartCheckedOut, cartCheckedOut),
+	}
+}
+
+func cartCreated(event es.Event) ([]sqlprojection.Op, error) {
+	return []sqlprojection.Op{
+		sqlprojection.Upsert("carts", sqlprojection.Row{
+			"tenant_id":  event.TenantID,
+			"cart_id":    event.AggregateID,
+			"created_at": event.At.UTC(),
+		}),
+	}, nil
+}
+
+// itemQuantityChanged makes sure the cart has a row before counting units.
+var itemQuantityChanged = checkout.QuantityDeltaHandler(func(event es.Event, _ int) []sqlprojection.Op {
+	return []sqlprojection.Op{
+		sqlprojection.Upsert("carts", sqlprojection.Row{"tenant_id": event.TenantID, "cart_id": event.AggregateID}),
+	}
+})
+
+func cartCheckedOut(event es.Event) ([]sqlprojection.Op, error) {
+	at := event.At.UTC()
+	cart := sqlprojection.Row{"tenant_id": event.TenantID, "cart_id": event.AggregateID}
+
+	return []sqlprojection.Op{
+		sqlprojection.Upsert("carts", sqlprojection.Row{
+			"tenant_id":      event.TenantID,
+			"cart_id":        event.AggregateID,
+			"checked_out_at": at,
+		}),
+		sqlprojection.Update("cart_items", cart, sqlprojection.Row{
+			"sold_at":   at,
+			"sold_hour": at.Truncate(time.Hour),
+			"sold_day":  time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC),
+		}),
+	}, nil
+}
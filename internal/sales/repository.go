@@ -0,0 +1,149 @@
+package sales
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Bucket is the length of the periods units sold are counted in.
+type Bucket string
+
+const (
+	Hour Bucket = "hour"
+	Day  Bucket = "day"
+)
+
+// bucketColumns maps buckets to the cart_items column holding the start of
+// the bucket an item was sold in.
+var bucketColumns = map[Bucket]string{
+	Hour: "sold_hour",
+	Day:  "sold_day",
+}
+
+// Window is the time range [From, To) a report covers.
+type Window struct {
+	From time.Time
+	To   time.Time
+}
+
+type TopItem struct {
+	ItemID    int `json:"item_id"`
+	UnitsSold int `json:"units_sold"`
+}
+
+type BucketSales struct {
+	Start     time.Time `json:"start"`
+	UnitsSold int       `json:"units_sold"`
+}
+
+type Conversion struct {
+	CartsCreated    int     `json:"carts_created"`
+	CartsCheckedOut int     `json:"carts_checked_out"`
+	Rate            float64 `json:"conversion_rate"`
+}
+
+// SalesRepository reports on the sales_analytics read model.
+type SalesRepository interface {
+	TopItems(ctx context.Context, tenantID string, window Window, limit int) ([]TopItem, error)
+	UnitsSold(ctx context.Context, tenantID string, window Window, bucket Bucket) ([]BucketSales, error)
+	Conversion(ctx context.Context, tenantID string, window Window) (Conversion, error)
+}
+
+type PGSalesRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPGSalesRepository(pool *pgxpool.Pool) *PGSalesRepository {
+	return &PGSalesRepository{
+		pool: pool,
+	}
+}
+
+// TopItems returns the items with the most units sold in the window.
+func (r *PGSalesRepository) TopItems(ctx context.Context, tenantID string, window Window, limit int) ([]TopItem, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT item_id, SUM(quantity) AS units_sold
+		FROM sales_analytics.cart_items
+		WHERE tenant_id = $1 AND sold_at >= $2 AND sold_at < $3 AND quantity > 0
+		GROUP BY item_id
+		ORDER BY units_sold DESC, item_id
+		LIMIT $4`,
+		tenantID, window.From.UTC(), window.To.UTC(), limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query top items: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]TopItem, 0)
+	for rows.Next() {
+		var item TopItem
+		if err := rows.Scan(&item.ItemID, &item.UnitsSold); err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return items, nil
+}
+
+// UnitsSold returns the units sold in each bucket of the window that had
+// sales, oldest first.
+func (r *PGSalesRepository) UnitsSold(ctx context.Context, tenantID string, window Window, bucket Bucket) ([]BucketSales, error) {
+	column, ok := bucketColumns[bucket]
+	if !ok {
+		return nil, fmt.Errorf("unknown bucket %q", bucket)
+	}
+
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(`
+		SELECT %[1]s, SUM(quantity)
+		FROM sales_analytics.cart_items
+		WHERE tenant_id = $1 AND sold_at >= $2 AND sold_at < $3 AND quantity > 0
+		GROUP BY %[1]s
+		ORDER BY %[1]s`, column),
+		tenantID, window.From.UTC(), window.To.UTC(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query units sold: %w", err)
+	}
+	defer rows.Close()
+
+	buckets := make([]BucketSales, 0)
+	for rows.Next() {
+		var b BucketSales
+		if err := rows.Scan(&b.Start, &b.UnitsSold); err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+		b.Start = b.Start.UTC()
+		buckets = append(buckets, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return buckets, nil
+}
+
+// Conversion counts the carts created in the window and how many of them
+// have been checked out.
+func (r *PGSalesRepository) Conversion(ctx context.Context, tenantID string, window Window) (Conversion, error) {
+	var c Conversion
+	err := r.pool.QueryRow(ctx, `
+		SELECT COUNT(*), COUNT(checked_out_at)
+		FROM sales_analytics.carts
+		WHERE tenant_id = $1 AND created_at >= $2 AND created_at < $3`,
+		tenantID, window.From.UTC(), window.To.UTC(),
+	).Scan(&c.CartsCreated, &c.CartsCheckedOut)
+	if err != nil {
+		return Conversion{}, fmt.Errorf("query conversion: %w", err)
+	}
+
+	if c.CartsCreated > 0 {
+		c.Rate = float64(c.CartsCheckedOut) / float64(c.CartsCreated)
+	}
+	return c, nil
+}
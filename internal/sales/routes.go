@@ -0,0 +1,171 @@
+package sales
+
+import (
+	"errors"
+	"es/internal/es"
+	"es/internal/openapi"
+	"es/internal/tenant"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// DefaultWindow is how far back reports go without a from parameter.
+	DefaultWindow = 7 * 24 * time.Hour
+	DefaultLimit  = 10
+	MaxLimit      = 100
+)
+
+type RouteHandler struct {
+	repo SalesRepository
+	now  func() time.Time
+}
+
+func NewRouteHandler(repo SalesRepository) *RouteHandler {
+	return &RouteHandler{
+		repo: repo,
+		now:  time.Now,
+	}
+}
+
+// reportParams are the query parameters every report takes.
+var reportParams = []openapi.Parameter{
+	{Name: "from", Description: "RFC 3339 timestamp, 7 days before to by default", Type: time.Time{}},
+	{Name: "to", Description: "RFC 3339 timestamp, now by default", Type: time.Time{}},
+	es.MinPositionParam,
+}
+
+// Operations documents the handlers of RouteHandler.
+var Operations = struct {
+	TopItems, UnitsSold, Conversion openapi.Operation
+}{
+	TopItems: openapi.Operation{
+		Summary: "Best selling items",
+		Tags:    []string{"analytics"},
+		Query: append([]openapi.Parameter{
+			{Name: "limit", Description: "At most 100, 10 by default", Type: 0},
+		}, reportParams...),
+		Responses: map[int]any{http.StatusOK: []TopItem{}},
+	},
+	UnitsSold: openapi.Operation{
+		Summary: "Units sold per hour or day",
+		Tags:    []string{"analytics"},
+		Query: append([]openapi.Parameter{
+			{Name: "bucket", Description: "day by default", Enum: []any{string(Hour), string(Day)}},
+		}, reportParams...),
+		Responses: map[int]any{http.StatusOK: []BucketSales{}},
+	},
+	Conversion: openapi.Operation{
+		Summary:   "Carts created and checked out",
+		Tags:      []string{"analytics"},
+		Query:     reportParams,
+		Responses: map[int]any{http.StatusOK: Conversion{}},
+	},
+}
+
+// TopItems returns the best selling items of the window, at most limit.
+func (h *RouteHandler) TopItems(c *fiber.Ctx) error {
+	tenantID, err := tenant.FromRequest(c)
+
+	if err != nil {
+		return fiber.NewError(http.StatusForbidden, err.Error())
+	}
+
+	window, err := h.window(c)
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	limit := c.QueryInt("limit", DefaultLimit)
+	if limit <= 0 || limit > MaxLimit {
+		return fiber.NewError(http.StatusBadRequest, "limit must be between 1 and 100")
+	}
+
+	items, err := h.repo.TopItems(c.Context(), tenantID, window, limit)
+
+	if err != nil {
+		return err
+	}
+
+	return c.Status(http.StatusOK).JSON(items)
+}
+
+// UnitsSold returns the units sold per hour or day of the window.
+func (h *RouteHandler) UnitsSold(c *fiber.Ctx) error {
+	tenantID, err := tenant.FromRequest(c)
+
+	if err != nil {
+		return fiber.NewError(http.StatusForbidden, err.Error())
+	}
+
+	window, err := h.window(c)
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	bucket := Bucket(c.Query("bucket", string(Day)))
+	if _, ok := bucketColumns[bucket]; !ok {
+		return fiber.NewError(http.StatusBadRequest, "bucket must be hour or day")
+	}
+
+	buckets, err := h.repo.UnitsSold(c.Context(), tenantID, window, bucket)
+
+	if err != nil {
+		return err
+	}
+
+	return c.Status(http.StatusOK).JSON(buckets)
+}
+
+// Conversion returns the share of carts created in the window that have
+// been checked out.
+func (h *RouteHandler) Conversion(c *fiber.Ctx) error {
+	tenantID, err := tenant.FromRequest(c)
+
+	if err != nil {
+		return fiber.NewError(http.StatusForbidden, err.Error())
+	}
+
+	window, err := h.window(c)
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	conversion, err := h.repo.Conversion(c.Context(), tenantID, window)
+
+	if err != nil {
+		return err
+	}
+
+	return c.Status(http.StatusOK).JSON(conversion)
+}
+
+// window reads the from and to query parameters. To defaults to now and
+// from to DefaultWindow before to.
+func (h *RouteHandler) window(c *fiber.Ctx) (Window, error) {
+	window := Window{To: h.now()}
+
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return Window{}, errors.New("to must be an RFC 3339 timestamp")
+		}
+		window.To = t
+	}
+
+	window.From = window.To.Add(-DefaultWindow)
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return Window{}, errors.New("from must be an RFC 3339 timestamp")
+		}
+		window.From = t
+	}
+
+	if !window.From.Before(window.To) {
+		return Window{}, errors.New("from must be before to")
+	}
+	return window, nil
+}
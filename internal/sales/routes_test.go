@@ -0,0 +1,96 @@
+package sales_test
+
+import (
+	"context"
+	"es/internal/openapi"
+	"es/internal/openapi/openapitest"
+	"es/internal/sales"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRepository struct {
+	window sales.Window
+	limit  int
+	bucket sales.Bucket
+}
+
+func (r *fakeRepository) TopItems(_ context.Context, _ string, window sales.Window, limit int) ([]sales.TopItem, error) {
+	r.window, r.limit = window, limit
+	return []sales.TopItem{}, nil
+}
+
+func (r *fakeRepository) UnitsSold(_ context.Context, _ string, window sales.Window, bucket sales.Bucket) ([]sales.BucketSales, error) {
+	r.window, r.bucket = window, bucket
+	return []sales.BucketSales{}, nil
+}
+
+func (r *fakeRepository) Conversion(_ context.Context, _ string, window sales.Window) (sales.Conversion, error) {
+	r.window = window
+	return sales.Conversion{}, nil
+}
+
+// newTestApp serves the sales routes, checking requests and responses
+// against their OpenAPI document and failing the test on the given
+// mismatches.
+func newTestApp(t *testing.T, repo sales.SalesRepository, failOn ...error) *fiber.App {
+	h := sales.NewRouteHandler(repo)
+	app, doc := openapitest.NewApp(t, "Sales", failOn...)
+	api := doc.Group(app, "/analytics/sales")
+	api.Get("/top-items", sales.Operations.TopItems, h.TopItems)
+	api.Get("/units", sales.Operations.UnitsSold, h.UnitsSold)
+	api.Get("/conversion", sales.Operations.Conversion, h.Conversion)
+	return app
+}
+
+func get(t *testing.T, app *fiber.App, target string) int {
+	t.Helper()
+	res, err := app.Test(httptest.NewRequest(http.MethodGet, target, nil))
+	require.NoError(t, err)
+	return res.StatusCode
+}
+
+func TestSalesRoutes(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	window := "from=2026-01-01T00:00:00Z&to=2026-02-01T00:00:00Z"
+
+	t.Run("top items over a window", func(t *testing.T) {
+		repo := &fakeRepository{}
+		assert.Equal(t, http.StatusOK, get(t, newTestApp(t, repo), "/analytics/sales/top-items?limit=5&"+window))
+		assert.Equal(t, sales.Window{From: from, To: to}, repo.window)
+		assert.Equal(t, 5, repo.limit)
+	})
+
+	t.Run("window defaults to the last week", func(t *testing.T) {
+		repo := &fakeRepository{}
+		assert.Equal(t, http.StatusOK, get(t, newTestApp(t, repo), "/analytics/sales/conversion"))
+		assert.Equal(t, sales.DefaultWindow, repo.window.To.Sub(repo.window.From))
+	})
+
+	t.Run("units sold per day by default", func(t *testing.T) {
+		repo := &fakeRepository{}
+		assert.Equal(t, http.StatusOK, get(t, newTestApp(t, repo), "/analytics/sales/units?"+window))
+		assert.Equal(t, sales.Day, repo.bucket)
+
+		assert.Equal(t, http.StatusOK, get(t, newTestApp(t, repo), "/analytics/sales/units?bucket=hour&"+window))
+		assert.Equal(t, sales.Hour, repo.bucket)
+	})
+
+	t.Run("rejects invalid parameters", func(t *testing.T) {
+		for _, target := range []string{
+			"/analytics/sales/units?bucket=week",
+			"/analytics/sales/top-items?limit=0",
+			"/analytics/sales/conversion?from=yesterday",
+			"/analytics/sales/conversion?from=2026-02-01T00:00:00Z&to=2026-01-01T00:00:00Z",
+		} {
+			assert.Equal(t, http.StatusBadRequest, get(t, newTestApp(t, &fakeRepository{}, openapi.ErrInvalidResponse), target), target)
+		}
+	})
+}
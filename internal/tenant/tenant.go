@@ -0,0 +1,45 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const localsKey = "tenant_id"
+
+// ErrMissingTenant is returned when a request has not been scoped to a tenant.
+var ErrMissingTenant = errors.New("request is not scoped to a tenant")
+
+// Set scopes the request to the given tenant. It is called by the
+// authentication middleware once the token has been verified.
+func Set(c *fiber.Ctx, tenantID string) {
+	c.Locals(localsKey, tenantID)
+}
+
+// FromRequest returns the tenant the request has been scoped to.
+func FromRequest(c *fiber.Ctx) (string, error) {
+	tenantID, ok := c.Locals(localsKey).(string)
+	if !ok || tenantID == "" {
+		return "", ErrMissingTenant
+	}
+	return tenantID, nil
+}
+
+type contextKey struct{}
+
+// WithTenant scopes a context to the given tenant, for callers outside of
+// fiber handlers such as the gRPC interceptors.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, tenantID)
+}
+
+// FromContext returns the tenant the context has been scoped to.
+func FromContext(ctx context.Context) (string, error) {
+	tenantID, ok := ctx.Value(contextKey{}).(string)
+	if !ok || tenantID == "" {
+		return "", ErrMissingTenant
+	}
+	return tenantID, nil
+}